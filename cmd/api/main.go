@@ -1,14 +1,24 @@
 package main
 
 import (
+	"errors"
+	"io"
 	"log"
 	"net"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
 
+	"github.com/amir-aharon/goliath/internal/aof"
 	"github.com/amir-aharon/goliath/internal/command"
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/pubsub"
+	"github.com/amir-aharon/goliath/internal/rdb"
 	"github.com/amir-aharon/goliath/internal/server"
 	"github.com/amir-aharon/goliath/internal/session"
+	"github.com/amir-aharon/goliath/internal/snapshot"
 	"github.com/amir-aharon/goliath/internal/store"
 )
 
@@ -21,9 +31,45 @@ func main() {
 	d := command.NewDispatcher()
 	command.RegisterBuiltins(d)
 
-	kv := store.NewMemory()
-	command.RegisterKV(d, kv)
-	command.RegisterTTL(d, kv)
+	hub := pubsub.NewHub()
+	n := notify.NewNotifier(hub, notify.LoadConfig().Flags)
+	d.SetNotifier(n)
+
+	dbs := store.NewDatabases()
+	dbs.OnExpire(func(db int, k string) { n.Notify(notify.Expired, "expired", k, db) })
+	dbs.OnFieldExpire(func(db int, k string, _ []string) { n.Notify(notify.Hash, "hexpired", k, db) })
+	command.RegisterKV(d, dbs)
+	command.RegisterTTL(d, dbs)
+	command.RegisterPubSub(d, hub)
+
+	// like redis, the append-only file wins over the snapshot when enabled
+	// since it's the more complete of the two
+	var rw command.Rewriter
+	var journal *aof.AOF
+	scfg := snapshot.LoadConfig()
+	if cfg := aof.LoadConfig(); cfg.Enabled {
+		replay := command.NewClient(io.Discard)
+		loaded, err := aof.Load(cfg.Path, cfg.LoadTruncated, func(args []string) error {
+			return d.Dispatch(replay, args[0], args[1:])
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("aof: replayed %d commands from %s", loaded, cfg.Path)
+
+		a, err := aof.Open(cfg, dbs)
+		if err != nil {
+			log.Fatal(err)
+		}
+		d.AddJournal(a)
+		rw, journal = a, a
+	} else if err := loadDump(scfg.Path, dbs); err != nil {
+		log.Fatal(err)
+	}
+
+	sv := snapshot.NewSaver(scfg, d.Settled(dbs), d)
+	command.RegisterPersistence(d, rw, sv)
+	command.RegisterDebug(d, dbs)
 
 	srv := server.Server{
 		Addr: "0.0.0.0",
@@ -32,5 +78,67 @@ func main() {
 			return session.New(c, d)
 		},
 	}
-	log.Fatal(srv.Serve())
+
+	// flush and sync the append-only file on the way out, whether the
+	// server fails or is told to stop, once no command is writing to it
+	shutdown := sync.OnceFunc(func() {
+		if journal == nil {
+			return
+		}
+		d.Exclusive(func() {
+			if err := journal.Close(); err != nil {
+				log.Printf("aof: close: %v", err)
+			}
+		})
+	})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		shutdown()
+		os.Exit(0)
+	}()
+
+	err := srv.Serve()
+	shutdown()
+	log.Fatal(err)
+}
+
+// loadDump restores the dump file at path if there is one. Both goliath
+// snapshots and Redis RDB files are accepted, told apart by their magic, so
+// pointing DBFILENAME at a dump.rdb migrates it on boot.
+func loadDump(path string, dbs *store.Databases) error {
+	if rdb.IsRDB(path) {
+		dump, err := rdb.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		restore("rdb", path, dbs, dump.DBs)
+		for _, sk := range dump.Skipped {
+			log.Printf("rdb: skipped %s key %q in db %d", sk.Type, sk.Key, sk.DB)
+		}
+		return nil
+	}
+
+	dump, err := snapshot.LoadFile(path)
+	switch {
+	case err == nil:
+		restore("snapshot", path, dbs, dump)
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	return nil
+}
+
+func restore(kind, path string, dbs *store.Databases, dump map[int][]store.Entry) {
+	var n int
+	for db, entries := range dump {
+		if db < dbs.Len() {
+			n += len(entries)
+		}
+	}
+	for _, db := range dbs.Restore(dump) {
+		log.Printf("%s: skipping %d keys in db %d, only %d databases are configured", kind, len(dump[db]), db, dbs.Len())
+	}
+	log.Printf("%s: loaded %d keys from %s", kind, n, path)
 }
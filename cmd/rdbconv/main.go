@@ -0,0 +1,94 @@
+// Command rdbconv converts a Redis RDB dump into goliath's persistence
+// formats, for migrating an existing Redis dataset.
+//
+//	rdbconv -in dump.rdb -out dump.gsnap
+//	rdbconv -in dump.rdb -out appendonly.aof -format aof
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/aof"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/rdb"
+	"github.com/amir-aharon/goliath/internal/snapshot"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func main() {
+	in := flag.String("in", "dump.rdb", "RDB file to read")
+	out := flag.String("out", "dump.gsnap", "file to write")
+	format := flag.String("format", "snapshot", "output format: snapshot or aof")
+	db := flag.Int("db", -1, "database to convert, or -1 for all of them")
+	flag.Parse()
+
+	dump, err := rdb.LoadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, sk := range dump.Skipped {
+		log.Printf("skipped %s key %q in db %d", sk.Type, sk.Key, sk.DB)
+	}
+
+	// drop keys that already expired, like a server loading the file would
+	now := time.Now()
+	dbs := make(map[int][]store.Entry)
+	var n int
+	for i, entries := range dump.DBs {
+		if *db >= 0 && i != *db {
+			continue
+		}
+		for _, e := range entries {
+			if e.ExpiresAt.IsZero() || e.ExpiresAt.After(now) {
+				dbs[i] = append(dbs[i], e)
+				n++
+			}
+		}
+	}
+
+	switch *format {
+	case "snapshot":
+		err = snapshot.SaveFile(*out, dbs)
+	case "aof":
+		err = writeAOF(*out, dbs)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d keys in %d databases of %s (RDB v%d) to %s", n, len(dbs), *in, dump.Version, *out)
+}
+
+// writeAOF writes dbs as the commands an AOF rewrite would produce.
+func writeAOF(path string, dbs map[int][]store.Entry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for i, db := range slices.Sorted(maps.Keys(dbs)) {
+		if i > 0 || db != 0 {
+			_ = proto.BulkArray(w, []string{"SELECT", strconv.Itoa(db)})
+		}
+		for _, e := range dbs[db] {
+			for _, cmd := range aof.Commands(e) {
+				_ = proto.BulkArray(w, cmd)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
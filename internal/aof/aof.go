@@ -0,0 +1,202 @@
+package aof
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// Fsync controls when appended commands are forced to disk.
+type Fsync int
+
+const (
+	FsyncEverySec Fsync = iota // fsync once per second in the background
+	FsyncAlways                // fsync after every command
+	FsyncNo                    // leave it to the OS
+)
+
+func ParseFsync(s string) (Fsync, error) {
+	switch strings.ToLower(s) {
+	case "always":
+		return FsyncAlways, nil
+	case "everysec":
+		return FsyncEverySec, nil
+	case "no":
+		return FsyncNo, nil
+	}
+	return 0, fmt.Errorf("aof: unknown fsync policy %q", s)
+}
+
+func (f Fsync) String() string {
+	switch f {
+	case FsyncAlways:
+		return "always"
+	case FsyncNo:
+		return "no"
+	}
+	return "everysec"
+}
+
+// Source is the dataset a rewrite rebuilds the log from, by database.
+type Source interface {
+	Snapshot() map[int][]store.Entry
+}
+
+// AOF appends mutating commands to a file as RESP arrays, the same encoding
+// clients use to send them, so the file can be replayed with proto.Reader.
+type AOF struct {
+	mu    sync.Mutex
+	cfg   Config
+	src   Source
+	f     *os.File
+	w     *bufio.Writer
+	dirty bool // written since the last fsync
+
+	// the database the log's last SELECT switched to, -1 when a replay
+	// can't be assumed to be in any particular one
+	selected int
+
+	size     int64 // current file size
+	baseSize int64 // size after the last rewrite, for the growth trigger
+
+	rewriting  bool
+	rewriteBuf bytes.Buffer // commands appended while a rewrite runs
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func Open(cfg Config, src Source) (*AOF, error) {
+	f, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// a replay starts out in database 0, but a file with commands in it may
+	// have switched away from it
+	selected := 0
+	if st.Size() > 0 {
+		selected = -1
+	}
+
+	a := &AOF{
+		cfg:      cfg,
+		src:      src,
+		f:        f,
+		w:        bufio.NewWriter(f),
+		size:     st.Size(),
+		baseSize: st.Size(),
+		selected: selected,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go a.syncLoop()
+	return a, nil
+}
+
+// Append writes one command that ran against database db, preceded by a
+// SELECT if the log was in another one. Every call reaches the OS before
+// returning; whether it also reaches the disk depends on the fsync policy.
+//
+// Callers must not run Append concurrently with the mutations it records
+// (the dispatcher serializes them), since a rewrite triggered from here
+// snapshots the dataset.
+func (a *AOF) Append(db int, args []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var rec bytes.Buffer
+	if db != a.selected {
+		_ = proto.BulkArray(&rec, []string{"SELECT", strconv.Itoa(db)})
+	}
+	_ = proto.BulkArray(&rec, args)
+
+	if _, err := a.w.Write(rec.Bytes()); err != nil {
+		return err
+	}
+	if err := a.w.Flush(); err != nil {
+		return err
+	}
+	a.selected = db
+	a.dirty = true
+	a.size += int64(rec.Len())
+
+	if a.rewriting {
+		a.rewriteBuf.Write(rec.Bytes())
+	} else if a.shouldRewriteLocked() {
+		if err := a.beginRewriteLocked(); err != nil {
+			log.Printf("aof: automatic rewrite: %v", err)
+		}
+	}
+
+	if a.cfg.Fsync == FsyncAlways {
+		return a.syncLocked()
+	}
+	return nil
+}
+
+func (a *AOF) syncLocked() error {
+	if !a.dirty {
+		return nil
+	}
+	a.dirty = false
+	return a.f.Sync()
+}
+
+func (a *AOF) syncLoop() {
+	defer close(a.done)
+	if a.cfg.Fsync != FsyncEverySec {
+		<-a.stop
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.mu.Lock()
+			_ = a.syncLocked()
+			a.mu.Unlock()
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// Close flushes, fsyncs and closes the file regardless of the policy. A
+// rewrite still in progress is abandoned.
+func (a *AOF) Close() error {
+	close(a.stop)
+	<-a.done
+
+	a.mu.Lock()
+	a.rewriting = false
+	defer a.mu.Unlock()
+
+	if err := a.w.Flush(); err != nil {
+		a.f.Close()
+		return err
+	}
+	a.dirty = true
+	if err := a.syncLocked(); err != nil {
+		a.f.Close()
+		return err
+	}
+	return a.f.Close()
+}
@@ -0,0 +1,102 @@
+package aof_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/aof"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestParseFsync(t *testing.T) {
+	for _, s := range []string{"always", "everysec", "no", "ALWAYS"} {
+		f, err := aof.ParseFsync(s)
+		if err != nil {
+			t.Fatalf("ParseFsync(%q): unexpected error: %v", s, err)
+		}
+		if f.String() == "" {
+			t.Fatalf("ParseFsync(%q): empty String()", s)
+		}
+	}
+	if _, err := aof.ParseFsync("sometimes"); err == nil {
+		t.Fatalf("ParseFsync(sometimes): expected error")
+	}
+}
+
+func TestAppendThenLoad(t *testing.T) {
+	for _, policy := range []aof.Fsync{aof.FsyncAlways, aof.FsyncEverySec, aof.FsyncNo} {
+		t.Run(policy.String(), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "appendonly.aof")
+			a, err := aof.Open(aof.Config{Path: path, Fsync: policy}, store.NewDatabases())
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+
+			want := [][]string{
+				{"SET", "k", "hello world"},
+				{"SET", "bin", "\x00\r\n\xff"},
+				{"DEL", "k"},
+			}
+			for _, args := range want {
+				if err := a.Append(0, args); err != nil {
+					t.Fatalf("append: %v", err)
+				}
+			}
+			if err := a.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			var got [][]string
+			n, err := aof.Load(path, false, func(args []string) error {
+				got = append(got, args)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if n != len(want) || !reflect.DeepEqual(got, want) {
+				t.Fatalf("load: got %d %q, want %q", n, got, want)
+			}
+		})
+	}
+}
+
+func TestOpen_AppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	if err := os.WriteFile(path, []byte("*2\r\n$3\r\nDEL\r\n$1\r\na\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := aof.Open(aof.Config{Path: path, Fsync: aof.FsyncNo}, store.NewDatabases())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := a.Append(0, []string{"DEL", "b"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	// the file may have switched databases, so the first append says which
+	// one it's for
+	if want := "*2\r\n$3\r\nDEL\r\n$1\r\na\r\n*2\r\n$6\r\nSELECT\r\n$1\r\n0\r\n*2\r\n$3\r\nDEL\r\n$1\r\nb\r\n"; string(data) != want {
+		t.Fatalf("file: got %q, want %q", data, want)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func itoa(n int64) string { return strconv.FormatInt(n, 10) }
+
+func join(args []string) string { return strings.Join(args, " ") }
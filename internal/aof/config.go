@@ -0,0 +1,62 @@
+package aof
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Config struct {
+	Enabled       bool
+	Path          string
+	Fsync         Fsync
+	LoadTruncated bool
+
+	// rewrite once the file has grown by RewritePercentage since the last
+	// rewrite and is at least RewriteMinSize bytes; 0 disables it
+	RewritePercentage int
+	RewriteMinSize    int64
+}
+
+func LoadConfig() Config {
+	cfg := Config{
+		Path:          "appendonly.aof",
+		Fsync:         FsyncEverySec,
+		LoadTruncated: true,
+
+		RewritePercentage: 100,
+		RewriteMinSize:    64 << 20,
+	}
+
+	if v := os.Getenv("APPENDONLY"); v != "" {
+		cfg.Enabled = strings.EqualFold(v, "yes")
+	}
+
+	if v := os.Getenv("APPENDFILENAME"); v != "" {
+		cfg.Path = v
+	}
+
+	if v := os.Getenv("APPENDFSYNC"); v != "" {
+		if parsed, err := ParseFsync(v); err == nil {
+			cfg.Fsync = parsed
+		}
+	}
+
+	if v := os.Getenv("AOF_LOAD_TRUNCATED"); v != "" {
+		cfg.LoadTruncated = strings.EqualFold(v, "yes")
+	}
+
+	if v := os.Getenv("AUTO_AOF_REWRITE_PERCENTAGE"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed >= 0 {
+			cfg.RewritePercentage = parsed
+		}
+	}
+
+	if v := os.Getenv("AUTO_AOF_REWRITE_MIN_SIZE"); v != "" {
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil && parsed >= 0 {
+			cfg.RewriteMinSize = parsed
+		}
+	}
+
+	return cfg
+}
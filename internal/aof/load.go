@@ -0,0 +1,74 @@
+package aof
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/amir-aharon/goliath/internal/proto"
+)
+
+// ErrTruncated is returned by Load when the file ends in the middle of a
+// command and repairing it wasn't allowed.
+var ErrTruncated = errors.New("aof: truncated trailing command")
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// Load replays every command in the file at path through apply and returns
+// how many were applied. A missing file is not an error. If the last command
+// was cut short (e.g. by a crash mid-write) and repair is set, the file is
+// truncated back to the last complete command; otherwise ErrTruncated is
+// returned.
+func Load(path string, repair bool, apply func(args []string) error) (int, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	cr := &countingReader{r: f}
+	r := proto.NewReader(cr)
+
+	var (
+		n    int
+		good int64 // offset just past the last complete command
+	)
+	for {
+		args, err := r.ReadCommand()
+		switch {
+		case err == io.EOF:
+			return n, nil
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			if !repair {
+				return n, fmt.Errorf("%w at offset %d of %s", ErrTruncated, good, path)
+			}
+			log.Printf("aof: truncating %s from %d to %d bytes", path, cr.n, good)
+			return n, f.Truncate(good)
+		case err != nil:
+			return n, fmt.Errorf("aof: bad command at offset %d of %s: %w", good, path, err)
+		}
+
+		good = cr.n - int64(r.Buffered())
+		if len(args) == 0 {
+			continue
+		}
+		if err := apply(args); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
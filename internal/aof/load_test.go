@@ -0,0 +1,84 @@
+package aof_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/aof"
+)
+
+const (
+	recSET = "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"
+	recDEL = "*2\r\n$3\r\nDEL\r\n$1\r\nk\r\n"
+)
+
+func writeAOF(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func count(n *int) func([]string) error {
+	return func([]string) error { *n++; return nil }
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	n, err := aof.Load(filepath.Join(t.TempDir(), "nope.aof"), false, count(new(int)))
+	if n != 0 || err != nil {
+		t.Fatalf("got (%d, %v), want (0, nil)", n, err)
+	}
+}
+
+func TestLoad_TruncatedWithoutRepair(t *testing.T) {
+	path := writeAOF(t, recSET+recDEL[:10])
+
+	var applied int
+	_, err := aof.Load(path, false, count(&applied))
+	if !errors.Is(err, aof.ErrTruncated) {
+		t.Fatalf("expected ErrTruncated, got %v", err)
+	}
+	if applied != 1 {
+		t.Fatalf("applied %d commands before the truncated one, want 1", applied)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != recSET+recDEL[:10] {
+		t.Fatalf("file was modified without repair: %q", data)
+	}
+}
+
+func TestLoad_TruncatedWithRepair(t *testing.T) {
+	path := writeAOF(t, recSET+recDEL+recSET[:7])
+
+	n, err := aof.Load(path, true, count(new(int)))
+	if err != nil || n != 2 {
+		t.Fatalf("got (%d, %v), want (2, nil)", n, err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != recSET+recDEL {
+		t.Fatalf("after repair: got %q, want %q", data, recSET+recDEL)
+	}
+}
+
+func TestLoad_CorruptCommand(t *testing.T) {
+	path := writeAOF(t, recSET+"*1\r\n:5\r\n"+recDEL)
+
+	_, err := aof.Load(path, true, count(new(int)))
+	if err == nil || errors.Is(err, aof.ErrTruncated) {
+		t.Fatalf("expected a corruption error, got %v", err)
+	}
+}
+
+func TestLoad_ApplyErrorStopsReplay(t *testing.T) {
+	path := writeAOF(t, recSET+recDEL)
+	boom := errors.New("boom")
+
+	n, err := aof.Load(path, false, func([]string) error { return boom })
+	if !errors.Is(err, boom) || n != 0 {
+		t.Fatalf("got (%d, %v), want (0, boom)", n, err)
+	}
+}
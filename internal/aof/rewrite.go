@@ -0,0 +1,215 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+var ErrRewriteInProgress = errors.New("background append only file rewriting already in progress")
+
+// Rewrite starts compacting the log in the background: the current dataset
+// is written to a temporary file as the Commands that recreate each live
+// key, with a SELECT before each database but the first,
+// commands appended meanwhile are buffered and added at the end, and the
+// result atomically replaces the log.
+//
+// Like Append, it must not run concurrently with mutations, so the snapshot
+// and the start of buffering happen at the same point in the command stream.
+func (a *AOF) Rewrite() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.beginRewriteLocked()
+}
+
+// Rewriting reports whether a rewrite is in progress.
+func (a *AOF) Rewriting() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rewriting
+}
+
+func (a *AOF) shouldRewriteLocked() bool {
+	if a.cfg.RewritePercentage == 0 || a.size < a.cfg.RewriteMinSize {
+		return false
+	}
+	base := max(a.baseSize, 1)
+	return (a.size-base)*100/base >= int64(a.cfg.RewritePercentage)
+}
+
+func (a *AOF) beginRewriteLocked() error {
+	if a.rewriting {
+		return ErrRewriteInProgress
+	}
+	dbs := a.src.Snapshot()
+	a.rewriting = true
+	a.rewriteBuf.Reset()
+	// the buffered commands have to start with a SELECT, since they'll
+	// follow whichever database the rewritten file ends in
+	a.selected = -1
+	go a.rewrite(dbs)
+	return nil
+}
+
+func (a *AOF) rewrite(dbs map[int][]store.Entry) {
+	tmp := a.cfg.Path + ".rewrite"
+	if err := a.rewriteTo(tmp, dbs); err != nil {
+		log.Printf("aof: rewrite: %v", err)
+		os.Remove(tmp)
+		a.mu.Lock()
+		a.rewriting = false
+		a.rewriteBuf.Reset()
+		a.mu.Unlock()
+	}
+}
+
+func (a *AOF) rewriteTo(tmp string, dbs map[int][]store.Entry) error {
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for i, db := range slices.Sorted(maps.Keys(dbs)) {
+		// a replay starts in database 0
+		if i > 0 || db != 0 {
+			_ = proto.BulkArray(w, []string{"SELECT", strconv.Itoa(db)})
+		}
+		for _, e := range dbs[db] {
+			for _, cmd := range Commands(e) {
+				_ = proto.BulkArray(w, cmd)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	// fsync the bulk of the file before taking the lock, so the critical
+	// section only covers the (usually small) tail.
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.rewriting { // closed meanwhile
+		f.Close()
+		return errors.New("aof closed during rewrite")
+	}
+
+	if _, err := f.Write(a.rewriteBuf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := os.Rename(tmp, a.cfg.Path); err != nil {
+		f.Close()
+		return err
+	}
+	syncDir(filepath.Dir(a.cfg.Path))
+
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	_ = a.w.Flush()
+	a.f.Close()
+	a.f, a.w = f, bufio.NewWriter(f)
+	a.size, a.baseSize = st.Size(), st.Size()
+	a.dirty = false
+	a.rewriting = false
+	a.rewriteBuf.Reset()
+	return nil
+}
+
+// itemsPerCmd caps the elements a rewrite puts in one command, like redis,
+// so a big collection doesn't become one huge command.
+const itemsPerCmd = 64
+
+// Commands returns the commands that recreate e: a SET, or for a list
+// RPUSHes of up to itemsPerCmd elements each and for a hash HSETs of up to
+// itemsPerCmd fields each, then an HPEXPIREAT per field expiry, followed
+// by a PEXPIREAT if it has a TTL.
+func Commands(e store.Entry) [][]string {
+	var cmds [][]string
+	switch e.Kind() {
+	case store.KindList:
+		for rest := e.List; len(rest) > 0; {
+			n := min(len(rest), itemsPerCmd)
+			cmds = append(cmds, append([]string{"RPUSH", e.Key}, rest[:n]...))
+			rest = rest[n:]
+		}
+	case store.KindHash:
+		for rest := e.Hash; len(rest) > 0; {
+			n := min(len(rest), itemsPerCmd)
+			cmd := make([]string, 0, 2+2*n)
+			cmd = append(cmd, "HSET", e.Key)
+			for _, hf := range rest[:n] {
+				cmd = append(cmd, hf.Field, hf.Val)
+			}
+			cmds = append(cmds, cmd)
+			rest = rest[n:]
+		}
+		cmds = append(cmds, fieldExpiries(e)...)
+	default:
+		cmds = append(cmds, []string{"SET", e.Key, e.Val})
+	}
+	if !e.ExpiresAt.IsZero() {
+		cmds = append(cmds, []string{"PEXPIREAT", e.Key, strconv.FormatInt(e.ExpiresAt.UnixMilli(), 10)})
+	}
+	return cmds
+}
+
+// fieldExpiries returns the HPEXPIREATs that restore the field TTLs of the
+// hash in e, one per distinct expiry, in the order the fields were added.
+func fieldExpiries(e store.Entry) [][]string {
+	var order []int64
+	fields := make(map[int64][]string)
+	for _, hf := range e.Hash {
+		if hf.ExpiresAt.IsZero() {
+			continue
+		}
+		ms := hf.ExpiresAt.UnixMilli()
+		if _, ok := fields[ms]; !ok {
+			order = append(order, ms)
+		}
+		fields[ms] = append(fields[ms], hf.Field)
+	}
+
+	var cmds [][]string
+	for _, ms := range order {
+		for rest := fields[ms]; len(rest) > 0; {
+			n := min(len(rest), itemsPerCmd)
+			cmd := make([]string, 0, 5+n)
+			cmd = append(cmd, "HPEXPIREAT", e.Key, strconv.FormatInt(ms, 10), "FIELDS", strconv.Itoa(n))
+			cmds = append(cmds, append(cmd, rest[:n]...))
+			rest = rest[n:]
+		}
+	}
+	return cmds
+}
+
+// syncDir makes a rename durable; failures are ignored since not every
+// platform supports fsync on directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
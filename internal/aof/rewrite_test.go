@@ -0,0 +1,204 @@
+package aof_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/aof"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func waitRewrite(t *testing.T, a *aof.AOF) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for a.Rewriting() {
+		if time.Now().After(deadline) {
+			t.Fatalf("rewrite did not finish in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func loadAll(t *testing.T, path string) [][]string {
+	t.Helper()
+	var got [][]string
+	if _, err := aof.Load(path, false, func(args []string) error {
+		got = append(got, args)
+		return nil
+	}); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return got
+}
+
+func TestRewrite_CompactsToLiveKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	dbs := store.NewDatabases()
+	mem := dbs.DB(0)
+	a, err := aof.Open(aof.Config{Path: path, Fsync: aof.FsyncNo}, dbs)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer a.Close()
+
+	for _, v := range []string{"1", "2", "3"} {
+		mem.Set("hot", v)
+		must(t, a.Append(0, []string{"SET", "hot", v}))
+	}
+	expiry := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+	mem.SetEx("ttl", "v", time.Until(expiry))
+	mem.ExpireAt("ttl", expiry)
+	must(t, a.Append(0, []string{"SET", "ttl", "v"}))
+
+	before, _ := os.Stat(path)
+	must(t, a.Rewrite())
+	must(t, a.Append(0, []string{"DEL", "gone"})) // lands during or after the rewrite
+	waitRewrite(t, a)
+
+	got := loadAll(t, path)
+	want := map[string]bool{
+		"SET hot 3": true,
+		"SET ttl v": true,
+		"PEXPIREAT ttl " + itoa(expiry.UnixMilli()): true,
+		"SELECT 0": true,
+		"DEL gone": true,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("rewritten log: got %q, want %d records", got, len(want))
+	}
+	for _, args := range got {
+		if !want[join(args)] {
+			t.Fatalf("unexpected record %q in %q", args, got)
+		}
+	}
+	if last := got[len(got)-1]; !reflect.DeepEqual(last, []string{"DEL", "gone"}) {
+		t.Fatalf("command appended during rewrite should come last, got %q", last)
+	}
+
+	after, _ := os.Stat(path)
+	tail := "*2\r\n$6\r\nSELECT\r\n$1\r\n0\r\n*2\r\n$3\r\nDEL\r\n$4\r\ngone\r\n"
+	if after.Size() >= before.Size()+int64(len(tail)) {
+		t.Fatalf("rewrite didn't shrink the log: %d -> %d bytes", before.Size(), after.Size())
+	}
+
+	// appends after the rewrite go to the new file
+	must(t, a.Append(0, []string{"DEL", "hot"}))
+	got = loadAll(t, path)
+	if last := got[len(got)-1]; !reflect.DeepEqual(last, []string{"DEL", "hot"}) {
+		t.Fatalf("append after rewrite: last record %q", last)
+	}
+}
+
+func TestRewrite_TriggeredByGrowth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	dbs := store.NewDatabases()
+	dbs.DB(0).Set("k", "v")
+	a, err := aof.Open(aof.Config{
+		Path:              path,
+		Fsync:             aof.FsyncNo,
+		RewritePercentage: 100,
+		RewriteMinSize:    100,
+	}, dbs)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer a.Close()
+
+	// each record is 29 bytes; the 4th pushes the file past the minimum
+	for range 4 {
+		must(t, a.Append(0, []string{"SET", "k", "v"}))
+	}
+	waitRewrite(t, a)
+
+	if got := loadAll(t, path); !reflect.DeepEqual(got, [][]string{{"SET", "k", "v"}}) {
+		t.Fatalf("after automatic rewrite: got %q", got)
+	}
+}
+
+func TestRewrite_SelectsDatabases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	dbs := store.NewDatabases()
+	a, err := aof.Open(aof.Config{Path: path, Fsync: aof.FsyncNo}, dbs)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer a.Close()
+
+	dbs.DB(3).Set("b", "2")
+	must(t, a.Append(3, []string{"SET", "b", "2"}))
+	dbs.DB(0).Set("a", "1")
+	must(t, a.Append(0, []string{"SET", "a", "1"}))
+	must(t, a.Append(0, []string{"DEL", "x"}))
+
+	want := [][]string{{"SELECT", "3"}, {"SET", "b", "2"}, {"SELECT", "0"}, {"SET", "a", "1"}, {"DEL", "x"}}
+	if got := loadAll(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("log: got %q, want %q", got, want)
+	}
+
+	must(t, a.Rewrite())
+	waitRewrite(t, a)
+	must(t, a.Append(3, []string{"DEL", "b"}))
+
+	want = [][]string{{"SET", "a", "1"}, {"SELECT", "3"}, {"SET", "b", "2"}, {"SELECT", "3"}, {"DEL", "b"}}
+	if got := loadAll(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rewritten log: got %q, want %q", got, want)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	at := time.UnixMilli(1_700_000_000_000)
+	if got, want := aof.Commands(store.Entry{Key: "s", Val: "v", ExpiresAt: at}), [][]string{
+		{"SET", "s", "v"},
+		{"PEXPIREAT", "s", "1700000000000"},
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("string: got %q, want %q", got, want)
+	}
+
+	list := make([]string, 130)
+	for i := range list {
+		list[i] = strconv.Itoa(i)
+	}
+	got := aof.Commands(store.Entry{Key: "l", List: list})
+	if len(got) != 3 || len(got[0]) != 66 || len(got[2]) != 4 {
+		t.Fatalf("list: got %d commands", len(got))
+	}
+	var replayed []string
+	for _, cmd := range got {
+		if cmd[0] != "RPUSH" || cmd[1] != "l" {
+			t.Fatalf("list: got %q", cmd[:2])
+		}
+		replayed = append(replayed, cmd[2:]...)
+	}
+	if !reflect.DeepEqual(replayed, list) {
+		t.Fatalf("list elements out of order")
+	}
+
+	hash := make([]store.HashField, 70)
+	for i := range hash {
+		hash[i] = store.HashField{Field: "f" + strconv.Itoa(i), Val: strconv.Itoa(i)}
+	}
+	got = aof.Commands(store.Entry{Key: "h", Hash: hash, ExpiresAt: at})
+	if len(got) != 3 || len(got[0]) != 130 || len(got[1]) != 14 || got[2][0] != "PEXPIREAT" {
+		t.Fatalf("hash: got %d commands", len(got))
+	}
+	if got[1][0] != "HSET" || got[1][1] != "h" || got[1][2] != "f64" || got[1][3] != "64" {
+		t.Fatalf("hash: second command starts %q", got[1][:4])
+	}
+	fieldAt := time.UnixMilli(1_700_000_000_500)
+	got = aof.Commands(store.Entry{Key: "h", Hash: []store.HashField{
+		{Field: "a", Val: "1", ExpiresAt: fieldAt},
+		{Field: "b", Val: "2"},
+		{Field: "c", Val: "3", ExpiresAt: fieldAt},
+	}})
+	want := [][]string{
+		{"HSET", "h", "a", "1", "b", "2", "c", "3"},
+		{"HPEXPIREAT", "h", "1700000000500", "FIELDS", "2", "a", "c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("field expiries: got %q, want %q", got, want)
+	}
+}
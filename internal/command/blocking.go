@@ -0,0 +1,277 @@
+package command
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// waiter is a client parked by a blocking command such as BLPOP until a
+// command that leaves a list on one of its keys serves it.
+type waiter struct {
+	db       int
+	proto    int
+	keys     []string
+	deadline time.Time // zero waits forever
+
+	// the command to run again for it, and the reply to give up with
+	name string
+	args []string
+	null func(io.Writer) error
+
+	// guarded by blocking.mu: claimed while a command is serving the
+	// waiter, cancelled if it gave up meanwhile, and done once it's been
+	// handed its reply
+	claimed, cancelled, done bool
+
+	// receives the reply once the waiter is served, or nil if it was
+	// cancelled during a claim that didn't serve it
+	reply chan []byte
+}
+
+type blockedKey struct {
+	db  int
+	key string
+}
+
+// blocking tracks the clients waiting on each key, in the order they
+// blocked. They're served by the command that pushes onto the key, first
+// come first served, before any other mutating command gets to run; see
+// serveBlocked.
+type blocking struct {
+	mu     sync.Mutex
+	queues map[blockedKey][]*waiter
+}
+
+func (b *blocking) add(w *waiter) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.queues == nil {
+		b.queues = make(map[blockedKey][]*waiter)
+	}
+	for _, k := range w.keys {
+		bk := blockedKey{w.db, k}
+		b.queues[bk] = append(b.queues[bk], w)
+	}
+}
+
+// claim returns the first waiter on bk that no other command is serving,
+// marking it as claimed, or nil if there's none.
+func (b *blocking) claim(bk blockedKey) *waiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, w := range b.queues[bk] {
+		if !w.claimed {
+			w.claimed = true
+			return w
+		}
+	}
+	return nil
+}
+
+// release ends a claim on w. If it was served, with reply, or cancelled
+// meanwhile, it leaves every queue and is handed reply; otherwise it keeps
+// its place.
+func (b *blocking) release(w *waiter, reply []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w.claimed = false
+	if reply == nil && !w.cancelled {
+		return
+	}
+	b.remove(w)
+	w.done = true
+	w.reply <- reply
+}
+
+// cancel takes w off every key it waits on, e.g. once it times out. It
+// reports false if a command is serving w right then or already has, in
+// which case w gets a reply, nil if it wasn't served after all.
+func (b *blocking) cancel(w *waiter) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if w.claimed || w.done {
+		w.cancelled = true
+		return false
+	}
+	b.remove(w)
+	return true
+}
+
+// must be called with mu held
+func (b *blocking) remove(w *waiter) {
+	for _, k := range w.keys {
+		bk := blockedKey{w.db, k}
+		q := b.queues[bk]
+		for i, o := range q {
+			if o == w {
+				q = append(q[:i:i], q[i+1:]...)
+				break
+			}
+		}
+		if len(q) == 0 {
+			delete(b.queues, bk)
+		} else {
+			b.queues[bk] = q
+		}
+	}
+}
+
+// waiting returns the keys of database db that clients wait on.
+func (b *blocking) waiting(db int) []blockedKey {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var keys []blockedKey
+	for bk := range b.queues {
+		if bk.db == db {
+			keys = append(keys, bk)
+		}
+	}
+	return keys
+}
+
+// block parks c on keys of kv after a blocking command found nothing to
+// serve it, to run name with args again once one of them gets a list. A
+// timeout of 0 waits forever; null is the reply if it runs out. When the
+// command is being run again c is already parked, and stays so.
+func (d *Dispatcher) block(c *Client, kv store.Keyspace, keys []string, timeout time.Duration, name string, args []string, null func(io.Writer) error) error {
+	c.SkipPropagate()
+	c.blocked = true
+	if c.wait != nil {
+		return nil
+	}
+
+	w := &waiter{
+		db:    c.DB,
+		proto: c.Proto,
+		keys:  keys,
+		name:  name,
+		args:  args,
+		null:  null,
+		reply: make(chan []byte, 1),
+	}
+	if timeout > 0 {
+		w.deadline = time.Now().Add(timeout)
+	}
+	d.blocked.add(w)
+	c.wait = w
+
+	// a push since the command looked served whoever waited before w was
+	// there, so check again on its behalf
+	for _, k := range keys {
+		if kv.Type(k) == store.KindList {
+			d.signal(c, c.DB, k)
+			break
+		}
+	}
+	return nil
+}
+
+// signal marks k in database db as possibly holding a list for the clients
+// waiting on it, who are served once the command c is running is done.
+func (d *Dispatcher) signal(c *Client, db int, k string) {
+	c.ready = append(c.ready, blockedKey{db, k})
+}
+
+// signalDB signals every key clients wait on in database db, for when its
+// whole contents change.
+func (d *Dispatcher) signalDB(c *Client, db int) {
+	c.ready = append(c.ready, d.blocked.waiting(db)...)
+}
+
+// keyReady signals k in database db if a command other than a push, e.g.
+// RENAME, just left a list there.
+func (d *Dispatcher) keyReady(c *Client, dbs *store.Databases, db int, k string) {
+	if dbs.DB(db).Type(k) == store.KindList {
+		d.signal(c, db, k)
+	}
+}
+
+// serveBlocked serves the clients waiting on the keys c's command
+// signaled, oldest first, for as long as each key has something for them,
+// like redis's handleClientsBlockedOnKeys. Their commands run right here,
+// journaled after c's and still under the d.mu Dispatch holds for it, so no
+// other command can take what was pushed ahead of them, journal or not; the
+// replies are handed to Wait to write. Keys signaled by a served
+// command, like BLMOVE's destination, are served in turn.
+func (d *Dispatcher) serveBlocked(c *Client) {
+	ready := c.ready
+	for len(ready) > 0 {
+		bk := ready[0]
+		ready = ready[1:]
+		for {
+			w := d.blocked.claim(bk)
+			if w == nil {
+				break
+			}
+			var buf bytes.Buffer
+			wc := &Client{Writer: &buf, Proto: w.proto, DB: w.db, wait: w}
+			_ = d.call(wc, d.Table[strings.ToUpper(w.name)], w.name, w.args)
+			ready = append(ready, wc.ready...)
+			if wc.blocked {
+				// nothing left for it, nor for anyone behind it
+				d.blocked.release(w, nil)
+				break
+			}
+			d.blocked.release(w, buf.Bytes())
+		}
+	}
+}
+
+// Blocked reports whether the last command c ran parked it, so the caller
+// must Wait before running anything else for it.
+func (c *Client) Blocked() bool {
+	return c.wait != nil
+}
+
+// Wait keeps c parked after a blocking command until a command that leaves
+// a list on one of its keys serves it, writing the reply, or until its
+// timeout runs out and it's answered with a null. Closing gone, e.g.
+// because the connection dropped, gives up without a reply. mu is held
+// around every reply written to c, as the caller holds it around Dispatch.
+func (d *Dispatcher) Wait(c *Client, mu sync.Locker, gone <-chan struct{}) error {
+	w := c.wait
+	if w == nil {
+		return nil
+	}
+	defer func() {
+		c.wait, c.blocked = nil, false
+	}()
+
+	var timeout <-chan time.Time
+	if !w.deadline.IsZero() {
+		t := time.NewTimer(time.Until(w.deadline))
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	write := func(reply []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		_, err := c.Write(reply)
+		return err
+	}
+	select {
+	case reply := <-w.reply:
+		return write(reply)
+	case <-timeout:
+		// unless a command served c meanwhile
+		if !d.blocked.cancel(w) {
+			if reply := <-w.reply; reply != nil {
+				return write(reply)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return w.null(c)
+	case <-gone:
+		if !d.blocked.cancel(w) {
+			<-w.reply
+		}
+		return nil
+	}
+}
@@ -0,0 +1,262 @@
+package command_test
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/command"
+	"github.com/amir-aharon/goliath/internal/notify"
+)
+
+// blockedClient is a client whose replies may be written by Wait from
+// another goroutine, under mu.
+type blockedClient struct {
+	*command.Client
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func newBlockedClient() *blockedClient {
+	bc := &blockedClient{}
+	bc.Client = command.NewClient(&bc.buf)
+	return bc
+}
+
+func (bc *blockedClient) reply() string {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.buf.String()
+}
+
+// block runs a blocking command for bc, which must park it, and waits it
+// out in the background; the returned channel is closed once it's done.
+func (bc *blockedClient) block(t *testing.T, d *command.Dispatcher, gone <-chan struct{}, args ...string) <-chan struct{} {
+	t.Helper()
+	must(t, d.Dispatch(bc.Client, args[0], args[1:]))
+	if !bc.Blocked() {
+		t.Fatalf("%q didn't block, replied %q", args, bc.reply())
+	}
+	if got := bc.reply(); got != "" {
+		t.Fatalf("%q replied %q while blocked", args, got)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = d.Wait(bc.Client, &bc.mu, gone)
+	}()
+	return done
+}
+
+func TestBlockingPops_ServedRightAway(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	mustRun(t, d, "RPUSH", "a", "1", "2", "3", "4")
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"BLPOP", "missing", "a", "0"}, "*2\r\n$1\r\na\r\n$1\r\n1\r\n"},
+		{[]string{"BRPOP", "a", "0.5"}, "*2\r\n$1\r\na\r\n$1\r\n4\r\n"},
+		{[]string{"BLMPOP", "0", "2", "missing", "a", "LEFT", "COUNT", "5"}, "*2\r\n$1\r\na\r\n*2\r\n$1\r\n2\r\n$1\r\n3\r\n"},
+		{[]string{"RPUSH", "a", "x"}, ":1\r\n"},
+		{[]string{"BLMOVE", "a", "b", "LEFT", "RIGHT", "0"}, "$1\r\nx\r\n"},
+		{[]string{"BLPOP", "a", "-1"}, "-ERR timeout is negative\r\n"},
+		{[]string{"BLPOP", "a", "soon"}, "-ERR timeout is not a float or out of range\r\n"},
+		{[]string{"BLPOP", "a", "inf"}, "-ERR timeout is not a float or out of range\r\n"},
+		{[]string{"BLMPOP", "0", "0", "a", "LEFT"}, "-ERR numkeys should be greater than 0\r\n"},
+		{[]string{"BLMOVE", "a", "b", "UP", "LEFT", "0"}, "-ERR syntax error\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+
+	wantRecords := [][]string{
+		{"RPUSH", "a", "1", "2", "3", "4"},
+		{"LPOP", "a", "1"},
+		{"RPOP", "a", "1"},
+		{"LPOP", "a", "2"},
+		{"RPUSH", "a", "x"},
+		{"LMOVE", "a", "b", "LEFT", "RIGHT"},
+	}
+	if !reflect.DeepEqual(j.records, wantRecords) {
+		t.Fatalf("journal:\n got %q\nwant %q", j.records, wantRecords)
+	}
+}
+
+func TestBlockingPops_Timeout(t *testing.T) {
+	d := newDispatcher()
+
+	cases := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"BLPOP", "a", "b", "0.01"}, "*-1\r\n"},
+		{[]string{"BLMPOP", "0.01", "1", "a", "RIGHT"}, "*-1\r\n"},
+		{[]string{"BLMOVE", "a", "b", "LEFT", "LEFT", "0.01"}, "$-1\r\n"},
+		{[]string{"BRPOPLPUSH", "a", "b", "0.01"}, "$-1\r\n"},
+	}
+	for _, c := range cases {
+		bc := newBlockedClient()
+		<-bc.block(t, d, nil, c.cmd...)
+		if got := bc.reply(); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.cmd, got, c.want)
+		}
+		if bc.Blocked() {
+			t.Errorf("%q: still blocked after timing out", c.cmd)
+		}
+	}
+}
+
+func TestBlockingPops_FIFO(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	first, second := newBlockedClient(), newBlockedClient()
+	done1 := first.block(t, d, nil, "BLPOP", "q", "0")
+	done2 := second.block(t, d, nil, "BLPOP", "other", "q", "0")
+
+	mustRun(t, d, "RPUSH", "q", "a", "b", "c")
+	<-done1
+	<-done2
+
+	if got := first.reply(); got != "*2\r\n$1\r\nq\r\n$1\r\na\r\n" {
+		t.Fatalf("first waiter: got %q", got)
+	}
+	if got := second.reply(); got != "*2\r\n$1\r\nq\r\n$1\r\nb\r\n" {
+		t.Fatalf("second waiter: got %q", got)
+	}
+	if got := mustRun(t, d, "LRANGE", "q", "0", "-1"); got != "*1\r\n$1\r\nc\r\n" {
+		t.Fatalf("left over: got %q", got)
+	}
+
+	wantRecords := [][]string{
+		{"RPUSH", "q", "a", "b", "c"},
+		{"LPOP", "q", "1"},
+		{"LPOP", "q", "1"},
+	}
+	if !reflect.DeepEqual(j.records, wantRecords) {
+		t.Fatalf("journal:\n got %q\nwant %q", j.records, wantRecords)
+	}
+}
+
+func TestBlockingPops_ServedBeforeTheNextCommand(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	bc := newBlockedClient()
+	done := bc.block(t, d, nil, "BLPOP", "q", "0")
+
+	// the push hands its element to the waiter before it returns, so a
+	// command right after it can't take it first
+	mustRun(t, d, "RPUSH", "q", "a")
+	if got := mustRun(t, d, "LPOP", "q"); got != "$-1\r\n" {
+		t.Fatalf("LPOP after the push: got %q", got)
+	}
+	<-done
+
+	if got := bc.reply(); got != "*2\r\n$1\r\nq\r\n$1\r\na\r\n" {
+		t.Fatalf("waiter: got %q", got)
+	}
+	wantRecords := [][]string{
+		{"RPUSH", "q", "a"},
+		{"LPOP", "q", "1"},
+	}
+	if !reflect.DeepEqual(j.records, wantRecords) {
+		t.Fatalf("journal:\n got %q\nwant %q", j.records, wantRecords)
+	}
+}
+
+// popDuringPush is a Notifier that has another client LPOP the key being
+// pushed onto, while the push is still running, and gives the pop a moment
+// to get in before the push returns.
+type popDuringPush struct {
+	d   *command.Dispatcher
+	got chan string
+}
+
+func (p *popDuringPush) Notify(_ notify.Class, event, key string, _ int) {
+	if event != "rpush" {
+		return
+	}
+	go func() {
+		got, _ := run(p.d, "LPOP", key)
+		p.got <- got
+	}()
+	select {
+	case got := <-p.got:
+		p.got <- got
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestBlockingPops_ServedBeforeConcurrentPopsWithoutJournal(t *testing.T) {
+	d := newDispatcher()
+	pop := &popDuringPush{d: d, got: make(chan string, 1)}
+	d.SetNotifier(pop)
+
+	bc := newBlockedClient()
+	done := bc.block(t, d, nil, "BLPOP", "q", "0")
+
+	// with no journal, another client's pop still waits until the waiter
+	// was served
+	mustRun(t, d, "RPUSH", "q", "a")
+	if got := <-pop.got; got != "$-1\r\n" {
+		t.Fatalf("LPOP during the push: got %q", got)
+	}
+	<-done
+	if got := bc.reply(); got != "*2\r\n$1\r\nq\r\n$1\r\na\r\n" {
+		t.Fatalf("waiter: got %q", got)
+	}
+}
+
+func TestBlockingPops_WokenByMoveAndRename(t *testing.T) {
+	d := newDispatcher()
+
+	mover := newBlockedClient()
+	done1 := mover.block(t, d, nil, "BLMOVE", "src", "dst", "RIGHT", "LEFT", "0")
+	popper := newBlockedClient()
+	done2 := popper.block(t, d, nil, "BRPOP", "dst", "0")
+
+	// the element goes src -> dst for the mover, then to the popper
+	mustRun(t, d, "RPUSH", "tmp", "x")
+	mustRun(t, d, "RENAME", "tmp", "src")
+	<-done1
+	<-done2
+
+	if got := mover.reply(); got != "$1\r\nx\r\n" {
+		t.Fatalf("BLMOVE: got %q", got)
+	}
+	if got := popper.reply(); got != "*2\r\n$3\r\ndst\r\n$1\r\nx\r\n" {
+		t.Fatalf("BRPOP: got %q", got)
+	}
+	if got := mustRun(t, d, "EXISTS", "src", "dst"); got != ":0\r\n" {
+		t.Fatalf("EXISTS: got %q", got)
+	}
+}
+
+func TestBlockingPops_GoneClientLosesNothing(t *testing.T) {
+	d := newDispatcher()
+
+	gone := make(chan struct{})
+	bc := newBlockedClient()
+	done := bc.block(t, d, gone, "BLPOP", "q", "0")
+	close(gone)
+	<-done
+
+	mustRun(t, d, "RPUSH", "q", "a")
+	if got := bc.reply(); got != "" {
+		t.Fatalf("departed client was answered %q", got)
+	}
+	if got := mustRun(t, d, "LLEN", "q"); got != ":1\r\n" {
+		t.Fatalf("LLEN: got %q", got)
+	}
+}
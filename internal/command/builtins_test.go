@@ -1,6 +1,7 @@
 package command_test
 
 import (
+	"bytes"
 	"errors"
 	"strings"
 	"testing"
@@ -25,8 +26,8 @@ func TestECHO_EchoesArgument(t *testing.T) {
 	if err != nil {
 		t.Fatalf("dispatch error: %v", err)
 	}
-	if got != "hello\r\n" {
-		t.Fatalf("got %q, want %q", got, "hello\r\n")
+	if got != "$5\r\nhello\r\n" {
+		t.Fatalf("got %q, want %q", got, "$5\r\nhello\r\n")
 	}
 }
 
@@ -65,3 +66,62 @@ func TestBuiltins_WrongArity(t *testing.T) {
 		}
 	}
 }
+
+func TestHELLO_SwitchesProtocol(t *testing.T) {
+	d := newDispatcher()
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+
+	must(t, d.Dispatch(c, "HELLO", []string{"3", "SETNAME", "worker"}))
+	if c.Proto != 3 || c.Name != "worker" {
+		t.Fatalf("after HELLO 3: proto=%d name=%q, want 3 worker", c.Proto, c.Name)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "%7\r\n$6\r\nserver\r\n$7\r\ngoliath\r\n") {
+		t.Fatalf("HELLO 3 reply: got %q, want a 7 entry map", got)
+	}
+
+	buf.Reset()
+	must(t, d.Dispatch(c, "GET", []string{"missing"}))
+	if got := buf.String(); got != "_\r\n" {
+		t.Fatalf("GET missing under RESP3: got %q, want %q", got, "_\r\n")
+	}
+
+	buf.Reset()
+	must(t, d.Dispatch(c, "HELLO", []string{"2"}))
+	if c.Proto != 2 || !strings.HasPrefix(buf.String(), "*14\r\n") {
+		t.Fatalf("HELLO 2: proto=%d reply=%q", c.Proto, buf.String())
+	}
+}
+
+func TestHELLO_NoArgsKeepsProtocol(t *testing.T) {
+	d := newDispatcher()
+	got := mustRun(t, d, "HELLO")
+	if !strings.HasPrefix(got, "*14\r\n") || !strings.Contains(got, "$5\r\nproto\r\n:2\r\n") {
+		t.Fatalf("HELLO: got %q", got)
+	}
+}
+
+func TestHELLO_Errors(t *testing.T) {
+	d := newDispatcher()
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"4"}, "-NOPROTO unsupported protocol version\r\n"},
+		{[]string{"x"}, "-ERR Protocol version is not an integer or out of range\r\n"},
+		{[]string{"3", "AUTH", "bob", "pw"}, "-WRONGPASS invalid username-password pair or user is disabled.\r\n"},
+		{[]string{"3", "SETNAME"}, "-ERR Syntax error in HELLO option 'SETNAME'\r\n"},
+		{[]string{"3", "BOGUS"}, "-ERR Syntax error in HELLO option 'BOGUS'\r\n"},
+	}
+	for _, tc := range cases {
+		var buf bytes.Buffer
+		c := command.NewClient(&buf)
+		must(t, d.Dispatch(c, "HELLO", tc.args))
+		if got := buf.String(); got != tc.want {
+			t.Fatalf("HELLO %q: got %q, want %q", tc.args, got, tc.want)
+		}
+		if c.Proto != 2 {
+			t.Fatalf("HELLO %q: failed HELLO changed protocol to %d", tc.args, c.Proto)
+		}
+	}
+}
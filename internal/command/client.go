@@ -0,0 +1,96 @@
+package command
+
+import (
+	"io"
+	"sync/atomic"
+
+	"github.com/amir-aharon/goliath/internal/pubsub"
+)
+
+var nextClientID atomic.Int64
+
+// Client is the per-connection state a handler runs against. Replies are
+// written to it directly; Proto selects the RESP version the proto helpers
+// encode with.
+type Client struct {
+	io.Writer
+	ID    int64
+	Name  string
+	Proto int
+
+	// DB is the index of the database the client has selected.
+	DB int
+
+	// Sub is set once the client first subscribes; the session delivers
+	// its messages from then on.
+	Sub *pubsub.Subscriber
+
+	// wait is set while a blocking command has the client parked, and
+	// blocked by each run of that command that found nothing for it
+	wait    *waiter
+	blocked bool
+
+	// per-call state, reset by Dispatch
+	replied    bool
+	failed     bool
+	propagated [][]string
+	ready      []blockedKey // keys signaled for blocked clients
+}
+
+func NewClient(w io.Writer) *Client {
+	return &Client{
+		Writer: w,
+		ID:     nextClientID.Add(1),
+		Proto:  2,
+	}
+}
+
+func (c *Client) Protocol() int {
+	return c.Proto
+}
+
+// subscribed reports whether the client is in RESP2 subscriber mode, where
+// only the pubsub commands, PING and QUIT may run. RESP3 clients can mix
+// pushes with regular replies, so they never enter it.
+func (c *Client) subscribed() bool {
+	return c.Proto < 3 && c.Sub != nil && c.Sub.Count() > 0
+}
+
+// Write passes replies through, noting whether the current command answered
+// with an error; failed commands aren't journaled.
+func (c *Client) Write(p []byte) (int, error) {
+	if !c.replied && len(p) > 0 {
+		c.replied = true
+		c.failed = p[0] == '-'
+	}
+	return c.Writer.Write(p)
+}
+
+// Propagate replaces what the journal records for the current command. It
+// may be called several times to record more than one command, e.g. to turn
+// a relative expiry into an absolute one that replays correctly later.
+func (c *Client) Propagate(args ...string) {
+	c.propagated = append(c.propagated, args)
+}
+
+// SkipPropagate keeps the current command out of the journal, for a write
+// that turned out to change nothing, like SET NX on an existing key.
+func (c *Client) SkipPropagate() {
+	c.propagated = [][]string{}
+}
+
+// changes returns how many journal records the command that just ran
+// leaves: none if it failed, else what it propagated, or itself.
+func (c *Client) changes() int {
+	switch {
+	case c.failed:
+		return 0
+	case c.propagated == nil:
+		return 1
+	}
+	return len(c.propagated)
+}
+
+func (c *Client) reset() {
+	c.replied, c.failed, c.propagated, c.ready = false, false, nil, nil
+}
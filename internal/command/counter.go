@@ -0,0 +1,64 @@
+package command
+
+import (
+	"math"
+
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func registerCounters(d *Dispatcher, dbs *store.Databases) {
+	d.Register("INCR", 1, 1, true, func(c *Client, args []string) error {
+		return incrBy(d, dbs.DB(c.DB), c, args[0], 1)
+	})
+	d.Register("DECR", 1, 1, true, func(c *Client, args []string) error {
+		return incrBy(d, dbs.DB(c.DB), c, args[0], -1)
+	})
+	d.Register("INCRBY", 2, 2, true, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		n, ok := store.ParseInt(args[1])
+		if !ok {
+			return proto.Err(c, store.ErrNotInteger.Error())
+		}
+		return incrBy(d, kv, c, args[0], n)
+	})
+	d.Register("DECRBY", 2, 2, true, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		n, ok := store.ParseInt(args[1])
+		if !ok {
+			return proto.Err(c, store.ErrNotInteger.Error())
+		}
+		if n == math.MinInt64 {
+			return proto.Err(c, "decrement would overflow")
+		}
+		return incrBy(d, kv, c, args[0], -n)
+	})
+
+	d.Register("INCRBYFLOAT", 2, 2, true, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		delta, ok := store.ParseFloat(args[1])
+		if !ok {
+			return proto.Err(c, store.ErrNotFloat.Error())
+		}
+		f, err := kv.IncrByFloat(args[0], delta)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		// replicas and replays shouldn't redo the float math, which could
+		// round differently; record the result instead
+		v := store.FormatFloat(f)
+		c.Propagate("SET", args[0], v, "KEEPTTL")
+		d.notify(c.DB, notify.String, "incrbyfloat", args[0])
+		return proto.Bulk(c, v)
+	})
+}
+
+func incrBy(d *Dispatcher, kv store.Strings, c *Client, key string, delta int64) error {
+	n, err := kv.IncrBy(key, delta)
+	if err != nil {
+		return storeErr(c, err)
+	}
+	d.notify(c.DB, notify.String, "incrby", key)
+	return proto.Int(c, n)
+}
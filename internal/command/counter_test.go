@@ -0,0 +1,91 @@
+package command_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCounters(t *testing.T) {
+	d := newDispatcher()
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"INCR", "n"}, ":1\r\n"},
+		{[]string{"INCRBY", "n", "10"}, ":11\r\n"},
+		{[]string{"DECR", "n"}, ":10\r\n"},
+		{[]string{"DECRBY", "n", "-5"}, ":15\r\n"},
+		{[]string{"GET", "n"}, "$2\r\n15\r\n"},
+		{[]string{"INCRBYFLOAT", "n", "0.5"}, "$4\r\n15.5\r\n"},
+		{[]string{"INCRBYFLOAT", "n", "-15.5"}, "$1\r\n0\r\n"},
+		{[]string{"INCR", "n"}, ":1\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestCounters_Errors(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "SET", "s", "abc")
+	mustRun(t, d, "SET", "max", "9223372036854775807")
+	mustRun(t, d, "SET", "f", "1.5")
+
+	cases := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"INCR", "s"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"INCR", "f"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"INCRBY", "n", "1.5"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"INCRBY", "n", "+5"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"INCR", "max"}, "-ERR increment or decrement would overflow\r\n"},
+		{[]string{"DECRBY", "n", "-9223372036854775808"}, "-ERR decrement would overflow\r\n"},
+		{[]string{"INCRBYFLOAT", "s", "1"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"INCRBYFLOAT", "n", "nan"}, "-ERR value is not a valid float\r\n"},
+		{[]string{"INCRBYFLOAT", "n", "x"}, "-ERR value is not a valid float\r\n"},
+	}
+	for _, c := range cases {
+		if got := mustRun(t, d, c.cmd[0], c.cmd[1:]...); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.cmd, got, c.want)
+		}
+	}
+	if got := mustRun(t, d, "GET", "max"); got != "$19\r\n9223372036854775807\r\n" {
+		t.Fatalf("failed INCR changed the value: %q", got)
+	}
+}
+
+func TestCounters_KeepTTL(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	d := newDispatcherWithClock(fc)
+	mustRun(t, d, "SET", "n", "1", "EX", "100")
+	mustRun(t, d, "INCR", "n")
+	mustRun(t, d, "INCRBYFLOAT", "n", "1.5")
+	if got := mustRun(t, d, "TTL", "n"); got != ":100\r\n" {
+		t.Fatalf("TTL after increments: got %q", got)
+	}
+}
+
+func TestCounters_Journal(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	mustRun(t, d, "INCRBY", "n", "2")
+	mustRun(t, d, "INCRBYFLOAT", "n", "0.25")
+	mustRun(t, d, "SET", "s", "x")
+	mustRun(t, d, "INCR", "s") // error reply, not journaled
+
+	want := [][]string{
+		{"INCRBY", "n", "2"},
+		{"SET", "n", "2.25", "KEEPTTL"},
+		{"SET", "s", "x"},
+	}
+	if !reflect.DeepEqual(j.records, want) {
+		t.Fatalf("journal: got %q, want %q", j.records, want)
+	}
+}
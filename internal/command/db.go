@@ -0,0 +1,95 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func registerDatabases(d *Dispatcher, dbs *store.Databases) {
+	d.Register("SELECT", 1, 1, false, func(c *Client, args []string) error {
+		db, msg := dbIndex(dbs, args[0])
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+		c.DB = db
+		return proto.OK(c)
+	})
+
+	// MOVE key db
+	d.Register("MOVE", 2, 2, true, func(c *Client, args []string) error {
+		db, msg := dbIndex(dbs, args[1])
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+		if db == c.DB {
+			return proto.Err(c, "source and destination objects are the same")
+		}
+		if !dbs.Move(args[0], c.DB, db) {
+			c.SkipPropagate()
+			return proto.Int(c, 0)
+		}
+		d.notify(c.DB, notify.Generic, "move_from", args[0])
+		d.notify(db, notify.Generic, "move_to", args[0])
+		d.keyReady(c, dbs, db, args[0])
+		return proto.Int(c, 1)
+	})
+
+	// SWAPDB index1 index2
+	d.Register("SWAPDB", 2, 2, true, func(c *Client, args []string) error {
+		i, err := strconv.Atoi(args[0])
+		if err != nil {
+			return proto.Err(c, "invalid first DB index")
+		}
+		j, err := strconv.Atoi(args[1])
+		if err != nil {
+			return proto.Err(c, "invalid second DB index")
+		}
+		if i < 0 || i >= dbs.Len() || j < 0 || j >= dbs.Len() {
+			return proto.Err(c, "DB index is out of range")
+		}
+		dbs.Swap(i, j)
+		d.signalDB(c, i)
+		d.signalDB(c, j)
+		return proto.OK(c)
+	})
+
+	// ASYNC is accepted for compatibility: dropping the old keyspace is
+	// already constant time, the garbage collector frees it afterwards
+	d.Register("FLUSHDB", 0, 1, true, func(c *Client, args []string) error {
+		if !flushMode(args) {
+			return proto.Err(c, "syntax error")
+		}
+		dbs.DB(c.DB).Flush()
+		return proto.OK(c)
+	})
+
+	d.Register("FLUSHALL", 0, 1, true, func(c *Client, args []string) error {
+		if !flushMode(args) {
+			return proto.Err(c, "syntax error")
+		}
+		dbs.FlushAll()
+		return proto.OK(c)
+	})
+}
+
+// flushMode reports whether args are a valid FLUSHDB/FLUSHALL mode.
+func flushMode(args []string) bool {
+	return len(args) == 0 || strings.EqualFold(args[0], "ASYNC") || strings.EqualFold(args[0], "SYNC")
+}
+
+// dbIndex parses a database index, returning the error to reply with if
+// it isn't a valid one.
+func dbIndex(dbs *store.Databases, s string) (int, string) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, "value is not an integer or out of range"
+	}
+	if i < 0 || i >= dbs.Len() {
+		return 0, "DB index is out of range"
+	}
+	return i, ""
+}
@@ -0,0 +1,131 @@
+package command_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/command"
+)
+
+func TestSELECT_IsolatesDatabases(t *testing.T) {
+	d := newDispatcher()
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"SET", "k", "zero"}, "+OK\r\n"},
+		{[]string{"SELECT", "1"}, "+OK\r\n"},
+		{[]string{"GET", "k"}, "$-1\r\n"},
+		{[]string{"SET", "k", "one"}, "+OK\r\n"},
+		{[]string{"DBSIZE"}, ":1\r\n"},
+		{[]string{"SELECT", "0"}, "+OK\r\n"},
+		{[]string{"GET", "k"}, "$4\r\nzero\r\n"},
+		{[]string{"SELECT", "16"}, "-ERR DB index is out of range\r\n"},
+		{[]string{"SELECT", "-1"}, "-ERR DB index is out of range\r\n"},
+		{[]string{"SELECT", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"GET", "k"}, "$4\r\nzero\r\n"},
+	}
+	for _, s := range steps {
+		if got := dispatch(t, d, c, &buf, s.cmd...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestMOVE_SWAPDB_COPY(t *testing.T) {
+	d := newDispatcher()
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"SET", "k", "v", "EX", "100"}, "+OK\r\n"},
+		{[]string{"MOVE", "k", "0"}, "-ERR source and destination objects are the same\r\n"},
+		{[]string{"MOVE", "k", "16"}, "-ERR DB index is out of range\r\n"},
+		{[]string{"MOVE", "missing", "2"}, ":0\r\n"},
+		{[]string{"MOVE", "k", "2"}, ":1\r\n"},
+		{[]string{"EXISTS", "k"}, ":0\r\n"},
+		{[]string{"COPY", "k", "k", "DB", "2"}, ":0\r\n"}, // gone from here
+		{[]string{"SELECT", "2"}, "+OK\r\n"},
+		{[]string{"TTL", "k"}, ":100\r\n"},
+		{[]string{"COPY", "k", "k", "DB", "3"}, ":1\r\n"},
+		{[]string{"SET", "k", "other"}, "+OK\r\n"},
+		{[]string{"MOVE", "k", "3"}, ":0\r\n"}, // already exists there
+
+		{[]string{"SWAPDB", "2", "3"}, "+OK\r\n"},
+		{[]string{"GET", "k"}, "$1\r\nv\r\n"},
+		{[]string{"SWAPDB", "x", "3"}, "-ERR invalid first DB index\r\n"},
+		{[]string{"SWAPDB", "2", "x"}, "-ERR invalid second DB index\r\n"},
+		{[]string{"SWAPDB", "2", "16"}, "-ERR DB index is out of range\r\n"},
+	}
+	for _, s := range steps {
+		if got := dispatch(t, d, c, &buf, s.cmd...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestFLUSHDB_FLUSHALL(t *testing.T) {
+	d := newDispatcher()
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"MSET", "a", "1", "b", "2"}, "+OK\r\n"},
+		{[]string{"SELECT", "1"}, "+OK\r\n"},
+		{[]string{"SET", "c", "3"}, "+OK\r\n"},
+		{[]string{"FLUSHDB"}, "+OK\r\n"},
+		{[]string{"DBSIZE"}, ":0\r\n"},
+		{[]string{"SET", "c", "3"}, "+OK\r\n"},
+		{[]string{"SELECT", "0"}, "+OK\r\n"},
+		{[]string{"DBSIZE"}, ":2\r\n"},
+		{[]string{"FLUSHDB", "NOW"}, "-ERR syntax error\r\n"},
+		{[]string{"FLUSHALL", "ASYNC"}, "+OK\r\n"},
+		{[]string{"DBSIZE"}, ":0\r\n"},
+		{[]string{"SELECT", "1"}, "+OK\r\n"},
+		{[]string{"DBSIZE"}, ":0\r\n"},
+		{[]string{"SET", "c", "3"}, "+OK\r\n"},
+		{[]string{"FLUSHDB", "sync"}, "+OK\r\n"},
+		{[]string{"KEYS", "*"}, "*0\r\n"},
+	}
+	for _, s := range steps {
+		if got := dispatch(t, d, c, &buf, s.cmd...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestDatabases_JournalAndEvents(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+	n := &fakeNotifier{}
+	d.SetNotifier(n)
+
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+	dispatch(t, d, c, &buf, "SET", "k", "v")
+	dispatch(t, d, c, &buf, "SELECT", "4")
+	dispatch(t, d, c, &buf, "SET", "k", "v")
+	dispatch(t, d, c, &buf, "MOVE", "k", "5")
+	dispatch(t, d, c, &buf, "MOVE", "k", "5") // nothing to move, not journaled
+
+	wantRecords := [][]string{{"SET", "k", "v"}, {"SET", "k", "v"}, {"MOVE", "k", "5"}}
+	if !reflect.DeepEqual(j.records, wantRecords) || !reflect.DeepEqual(j.dbs, []int{0, 4, 4}) {
+		t.Fatalf("journal: got %q in dbs %v", j.records, j.dbs)
+	}
+
+	wantEvents := []string{"$ set k", "$ set k @4", "g move_from k @4", "g move_to k @5"}
+	if !reflect.DeepEqual(n.events, wantEvents) {
+		t.Fatalf("events:\n got %q\nwant %q", n.events, wantEvents)
+	}
+}
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/rdb"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// RegisterDebug wires DEBUG, which hosts admin-only maintenance subcommands.
+func RegisterDebug(d *Dispatcher, dbs *store.Databases) {
+	d.Register("DEBUG", 1, -1, false, func(c *Client, args []string) error {
+		switch sub := strings.ToUpper(args[0]); sub {
+		case "EXPORT-RDB":
+			// DEBUG EXPORT-RDB <path>: dump the dataset as a Redis RDB file
+			if len(args) != 2 {
+				return proto.Err(c, "wrong number of arguments for 'debug|export-rdb' command")
+			}
+			if err := rdb.SaveFile(args[1], d.Settled(dbs).Snapshot()); err != nil {
+				return proto.Err(c, err.Error())
+			}
+			return proto.OK(c)
+		default:
+			return proto.Err(c, fmt.Sprintf("unknown subcommand '%s'. Try DEBUG HELP.", args[0]))
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package command_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/command"
+	"github.com/amir-aharon/goliath/internal/rdb"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestDEBUG_ExportRDB(t *testing.T) {
+	d := command.NewDispatcher()
+	dbs := store.NewDatabases()
+	command.RegisterKV(d, dbs)
+	command.RegisterDebug(d, dbs)
+
+	mustRun(t, d, "SET", "k", "v")
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	if got := mustRun(t, d, "DEBUG", "export-rdb", path); got != "+OK\r\n" {
+		t.Fatalf("DEBUG EXPORT-RDB: got %q", got)
+	}
+
+	dump, err := rdb.LoadFile(path)
+	if err != nil {
+		t.Fatalf("load exported file: %v", err)
+	}
+	if want := []store.Entry{{Key: "k", Val: "v"}}; !reflect.DeepEqual(dump.DBs[0], want) {
+		t.Fatalf("exported: got %+v, want %+v", dump.DBs[0], want)
+	}
+}
+
+func TestDEBUG_Errors(t *testing.T) {
+	d := command.NewDispatcher()
+	command.RegisterDebug(d, store.NewDatabases())
+
+	if got, want := mustRun(t, d, "DEBUG", "nope"), "-ERR unknown subcommand 'nope'. Try DEBUG HELP.\r\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got, want := mustRun(t, d, "DEBUG", "EXPORT-RDB"), "-ERR wrong number of arguments for 'debug|export-rdb' command\r\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -3,15 +3,18 @@ package command
 import (
 	"errors"
 	"fmt"
-	"io"
+	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/amir-aharon/goliath/internal/notify"
 	"github.com/amir-aharon/goliath/internal/proto"
 )
 
 var ErrQuit = errors.New("quit")
 
-type Handler func(w io.Writer, args []string) error
+type Handler func(c *Client, args []string) error
 
 type Spec struct {
 	MinArgs  int
@@ -23,15 +26,38 @@ type Spec struct {
 func (s Spec) Validate(name string, args []string) error {
 	n := len(args)
 	if n < s.MinArgs || (s.MaxArgs >= 0 && n > s.MaxArgs) {
-		return fmt.Errorf("wrong number of arguments for '%s'", strings.ToUpper(name))
+		return fmt.Errorf("wrong number of arguments for '%s' command", strings.ToLower(name))
 	}
 	return nil
 }
 
 type CommandTable map[string]Spec
 
+// Journal records mutating commands after they succeed, e.g. to an
+// append-only file, along with the database they ran against.
+type Journal interface {
+	Append(db int, args []string) error
+}
+
+// Notifier is told about every change a command makes to a key, e.g. to
+// publish keyspace notifications.
+type Notifier interface {
+	Notify(class notify.Class, event, key string, db int)
+}
+
 type Dispatcher struct {
-	Table CommandTable
+	Table    CommandTable
+	journals []Journal
+	notifier Notifier
+	blocked  blocking
+
+	// counts what successful mutating commands change, journaled or not,
+	// without taking mu
+	changes atomic.Int64
+
+	// serializes mutating commands, so journals log them in the order they
+	// were applied in and Exclusive sees the dataset between two of them
+	mu sync.Mutex
 }
 
 func NewDispatcher() *Dispatcher {
@@ -47,15 +73,103 @@ func (d *Dispatcher) Register(name string, minArgs, maxArgs int, mutating bool,
 	}
 }
 
-func (d *Dispatcher) Dispatch(w io.Writer, name string, args []string) error {
+// AddJournal registers j to receive every successful mutating command.
+// Journals must be added before the dispatcher starts serving.
+func (d *Dispatcher) AddJournal(j Journal) {
+	d.journals = append(d.journals, j)
+}
+
+// SetNotifier registers n to hear about key changes. Like journals, it must
+// be set before the dispatcher starts serving.
+func (d *Dispatcher) SetNotifier(n Notifier) {
+	d.notifier = n
+}
+
+func (d *Dispatcher) notify(db int, class notify.Class, event, key string) {
+	if d.notifier != nil {
+		d.notifier.Notify(class, event, key, db)
+	}
+}
+
+// Changes returns how many changes mutating commands have made so far,
+// counted like journal records: a command that changed nothing adds none.
+// It's safe to call at any time, e.g. to drive save rules.
+func (d *Dispatcher) Changes() int64 {
+	return d.changes.Load()
+}
+
+// Exclusive runs fn while no mutating command is in flight, so fn sees the
+// dataset at a well defined point in the journal.
+func (d *Dispatcher) Exclusive(fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn()
+}
+
+func (d *Dispatcher) Dispatch(c *Client, name string, args []string) error {
 	spec, ok := d.Table[strings.ToUpper(name)]
 	if !ok {
-		return proto.Err(w, "unknown command")
+		return proto.Err(c, unknownCommand(name, args))
 	}
 
 	if err := spec.Validate(name, args); err != nil {
-		return proto.Err(w, err.Error())
+		return proto.Err(c, err.Error())
+	}
+
+	if c.subscribed() && !subscriberCommands[strings.ToUpper(name)] {
+		return proto.Err(c, fmt.Sprintf("Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", strings.ToLower(name)))
+	}
+
+	c.reset()
+	if !spec.Mutating {
+		return spec.Handler(c, args)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err := d.call(c, spec, name, args)
+	d.serveBlocked(c)
+	return err
+}
+
+// call runs a mutating command for c, counting its changes and journaling
+// it if it succeeded. d.mu must be held.
+func (d *Dispatcher) call(c *Client, spec Spec, name string, args []string) error {
+	err := spec.Handler(c, args)
+	d.changes.Add(int64(c.changes()))
+	if c.failed || len(d.journals) == 0 {
+		return err
+	}
+
+	records := c.propagated
+	if records == nil {
+		records = [][]string{append([]string{strings.ToUpper(name)}, args...)}
+	}
+	for _, j := range d.journals {
+		for _, r := range records {
+			if jerr := j.Append(c.DB, r); jerr != nil {
+				log.Printf("journal: %v", jerr)
+			}
+		}
 	}
+	return err
+}
+
+// commands a RESP2 client may still run while subscribed
+var subscriberCommands = map[string]bool{
+	"SUBSCRIBE":    true,
+	"UNSUBSCRIBE":  true,
+	"PSUBSCRIBE":   true,
+	"PUNSUBSCRIBE": true,
+	"PING":         true,
+	"QUIT":         true,
+}
 
-	return spec.Handler(w, args)
+func unknownCommand(name string, args []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "unknown command '%.128s', with args beginning with: ", name)
+	for _, a := range args {
+		fmt.Fprintf(&b, "'%.128s' ", a)
+	}
+	return b.String()
 }
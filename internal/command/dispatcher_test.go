@@ -3,7 +3,6 @@ package command_test
 import (
 	"bytes"
 	"fmt"
-	"io"
 	"testing"
 
 	"github.com/amir-aharon/goliath/internal/command"
@@ -12,12 +11,12 @@ import (
 func TestUnknownCommand(t *testing.T) {
 	d := command.NewDispatcher()
 	var buf bytes.Buffer
-	if err := d.Dispatch(&buf, "NODER", nil); err != nil {
+	if err := d.Dispatch(command.NewClient(&buf), "NODER", []string{"a", "b"}); err != nil {
 		t.Fatalf("unexpected error from Dispatch: %v", err)
 	}
 
 	got := buf.String()
-	want := "-ERR unknown command\r\n"
+	want := "-ERR unknown command 'NODER', with args beginning with: 'a' 'b' \r\n"
 	if got != want {
 		t.Errorf("Dispatch wrote %q, want %q", got, want)
 	}
@@ -26,17 +25,17 @@ func TestUnknownCommand(t *testing.T) {
 func TestDispatcherArityErrors(t *testing.T) {
 	d := command.NewDispatcher()
 
-	d.Register("ECHO", 1, 1, false, func(w io.Writer, args []string) error {
-		_, _ = fmt.Fprint(w, "ok\r\n")
+	d.Register("ECHO", 1, 1, false, func(c *command.Client, args []string) error {
+		_, _ = fmt.Fprint(c, "ok\r\n")
 		return nil
 	})
 
 	t.Run("too few", func(t *testing.T) {
 		var buf bytes.Buffer
-		if err := d.Dispatch(&buf, "ECHO", []string{}); err != nil {
+		if err := d.Dispatch(command.NewClient(&buf), "ECHO", []string{}); err != nil {
 			t.Fatalf("unexpected error from Dispatch: %v", err)
 		}
-		want := "-ERR wrong number of arguments for 'ECHO'\r\n"
+		want := "-ERR wrong number of arguments for 'echo' command\r\n"
 		if got := buf.String(); got != want {
 			t.Errorf("got %q, want %q", got, want)
 		}
@@ -44,10 +43,10 @@ func TestDispatcherArityErrors(t *testing.T) {
 
 	t.Run("too many", func(t *testing.T) {
 		var buf bytes.Buffer
-		if err := d.Dispatch(&buf, "ECHO", []string{"a", "b"}); err != nil {
+		if err := d.Dispatch(command.NewClient(&buf), "ECHO", []string{"a", "b"}); err != nil {
 			t.Fatalf("unexpected error from Dispatch: %v", err)
 		}
-		want := "-ERR wrong number of arguments for 'ECHO'\r\n"
+		want := "-ERR wrong number of arguments for 'echo' command\r\n"
 		if got := buf.String(); got != want {
 			t.Errorf("got %q, want %q", got, want)
 		}
@@ -56,13 +55,13 @@ func TestDispatcherArityErrors(t *testing.T) {
 
 func TestDispatcherCaseInsensitive(t *testing.T) {
 	d := command.NewDispatcher()
-	d.Register("PING", 0, 0, false, func(w io.Writer, _ []string) error {
-		_, _ = fmt.Fprint(w, "ran\r\n")
+	d.Register("PING", 0, 0, false, func(c *command.Client, _ []string) error {
+		_, _ = fmt.Fprint(c, "ran\r\n")
 		return nil
 	})
 
 	var buf bytes.Buffer
-	if err := d.Dispatch(&buf, "ping", nil); err != nil {
+	if err := d.Dispatch(command.NewClient(&buf), "ping", nil); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if got, want := buf.String(), "ran\r\n"; got != want {
@@ -72,13 +71,13 @@ func TestDispatcherCaseInsensitive(t *testing.T) {
 
 func TestDispatcherHappyPath(t *testing.T) {
 	d := command.NewDispatcher()
-	d.Register("HI", 0, 0, false, func(w io.Writer, _ []string) error {
-		_, _ = fmt.Fprint(w, "hi\r\n")
+	d.Register("HI", 0, 0, false, func(c *command.Client, _ []string) error {
+		_, _ = fmt.Fprint(c, "hi\r\n")
 		return nil
 	})
 
 	var buf bytes.Buffer
-	if err := d.Dispatch(&buf, "HI", nil); err != nil {
+	if err := d.Dispatch(command.NewClient(&buf), "HI", nil); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if got, want := buf.String(), "hi\r\n"; got != want {
@@ -1,77 +1,261 @@
 package command
 
 import (
-	"io"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/amir-aharon/goliath/internal/notify"
 	"github.com/amir-aharon/goliath/internal/proto"
 	"github.com/amir-aharon/goliath/internal/store"
 )
 
 func RegisterBuiltins(d *Dispatcher) {
-	d.Register("PING", 0, 0, false, func(w io.Writer, _ []string) error {
-		return proto.PONG(w)
+	d.Register("PING", 0, 0, false, func(c *Client, _ []string) error {
+		// subscribed RESP2 clients can only receive push-shaped replies
+		if c.subscribed() {
+			return proto.BulkArray(c, []string{"pong", ""})
+		}
+		return proto.PONG(c)
 	})
-	d.Register("ECHO", 1, 1, false, func(w io.Writer, args []string) error {
-		return proto.Line(w, args[0])
+	d.Register("ECHO", 1, 1, false, func(c *Client, args []string) error {
+		return proto.Bulk(c, args[0])
 	})
-	d.Register("QUIT", 0, 0, false, func(w io.Writer, _ []string) error {
-		if err := proto.OK(w); err != nil {
+	d.Register("QUIT", 0, 0, false, func(c *Client, _ []string) error {
+		if err := proto.OK(c); err != nil {
 			return err
 		}
 		return ErrQuit
 	})
+	d.Register("HELLO", 0, -1, false, hello)
 }
 
-func RegisterKV(d *Dispatcher, kv store.KV) {
-	d.Register("GET", 1, 1, false, func(w io.Writer, args []string) error {
-		if v, ok := kv.Get(args[0]); ok {
-			return proto.Line(w, v)
+// ServerVersion is reported by HELLO.
+const ServerVersion = "0.1.0"
+
+// HELLO [protover [AUTH username password] [SETNAME clientname]]
+func hello(c *Client, args []string) error {
+	ver, name := c.Proto, c.Name
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil {
+			return proto.Err(c, "Protocol version is not an integer or out of range")
 		}
-		return proto.Err(w, "key not found")
-	})
+		if v < 2 || v > 3 {
+			return proto.Error(c, "NOPROTO unsupported protocol version")
+		}
+		ver = v
+	}
 
-	d.Register("SET", 2, 2, true, func(w io.Writer, args []string) error {
-		kv.Set(args[0], args[1])
-		return proto.OK(w)
-	})
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "AUTH":
+			if i+2 >= len(args) {
+				return proto.Err(c, "Syntax error in HELLO option 'AUTH'")
+			}
+			// there is no ACL support; like redis with no requirepass, the
+			// default user accepts any password.
+			if args[i+1] != "default" {
+				return proto.Error(c, "WRONGPASS invalid username-password pair or user is disabled.")
+			}
+			i += 2
+		case "SETNAME":
+			if i+1 >= len(args) {
+				return proto.Err(c, "Syntax error in HELLO option 'SETNAME'")
+			}
+			if strings.ContainsFunc(args[i+1], func(r rune) bool { return r <= ' ' || r > '~' }) {
+				return proto.Err(c, "Client names cannot contain spaces, newlines or special characters.")
+			}
+			name = args[i+1]
+			i++
+		default:
+			return proto.Err(c, fmt.Sprintf("Syntax error in HELLO option '%s'", args[i]))
+		}
+	}
+
+	c.Proto, c.Name = ver, name
+
+	if err := proto.Map(c, 7); err != nil {
+		return err
+	}
+	_ = proto.Bulk(c, "server")
+	_ = proto.Bulk(c, "goliath")
+	_ = proto.Bulk(c, "version")
+	_ = proto.Bulk(c, ServerVersion)
+	_ = proto.Bulk(c, "proto")
+	_ = proto.Int(c, int64(c.Proto))
+	_ = proto.Bulk(c, "id")
+	_ = proto.Int(c, c.ID)
+	_ = proto.Bulk(c, "mode")
+	_ = proto.Bulk(c, "standalone")
+	_ = proto.Bulk(c, "role")
+	_ = proto.Bulk(c, "master")
+	_ = proto.Bulk(c, "modules")
+	return proto.Array(c, 0)
+}
 
-	d.Register("SETEX", 3, 3, true, func(w io.Writer, args []string) error {
-		secs, err := strconv.Atoi(args[1])
-		if err != nil || secs <= 0 {
-			return proto.Err(w, "seconds must be a positive integer")
+func RegisterKV(d *Dispatcher, dbs *store.Databases) {
+	d.Register("GET", 1, 1, false, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		v, ok, err := kv.Get(args[0])
+		switch {
+		case err != nil:
+			return storeErr(c, err)
+		case ok:
+			return proto.Bulk(c, v)
 		}
-		kv.SetEx(args[0], args[2], time.Duration(secs)*time.Second)
-		return proto.OK(w)
+		return proto.NullBulk(c)
 	})
 
-	d.Register("DEL", 1, 1, true, func(w io.Writer, args []string) error {
-		if kv.Del(args[0]) {
-			return proto.OK(w)
-		}
-		return proto.Err(w, "key not found")
+	d.Register("SET", 2, -1, true, func(c *Client, args []string) error {
+		return set(d, dbs.DB(c.DB), c, args)
 	})
+
+	d.Register("SETEX", 3, 3, true, setex(d, dbs, "setex", "EX"))
+	d.Register("PSETEX", 3, 3, true, setex(d, dbs, "psetex", "PX"))
+
+	// UNLINK frees memory in the background in redis; here the garbage
+	// collector already does, so both are the same command
+	d.Register("DEL", 1, -1, true, del(d, dbs))
+	d.Register("UNLINK", 1, -1, true, del(d, dbs))
+
+	d.Register("EXISTS", 1, -1, false, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		return proto.Int(c, int64(kv.Exists(args...)))
+	})
+
+	registerCounters(d, dbs)
+	registerStrings(d, dbs)
+	registerLists(d, dbs)
+	registerHashes(d, dbs)
+	registerHashTTL(d, dbs)
+	registerKeyspace(d, dbs)
+	registerDatabases(d, dbs)
 }
 
-func RegisterTTL(d *Dispatcher, kv store.KV) {
-	d.Register("TTL", 1, 1, false, func(w io.Writer, args []string) error {
-		secs, exists, hasExp := kv.TTL(args[0])
-		switch {
-		case !exists:
-			return proto.Int(w, -2)
-		case !hasExp:
-			return proto.Int(w, -1)
+// SET key value [NX | XX] [GET] [EX seconds | PX milliseconds |
+// EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
+func set(d *Dispatcher, kv store.Strings, c *Client, args []string) error {
+	key, val := args[0], args[1]
+
+	var opt store.SetOptions
+	var unit, expire string // the expiry option given, if any, and its argument
+	for i := 2; i < len(args); i++ {
+		switch a := strings.ToUpper(args[i]); {
+		case a == "NX" && opt.Cond != store.SetXX:
+			opt.Cond = store.SetNX
+		case a == "XX" && opt.Cond != store.SetNX:
+			opt.Cond = store.SetXX
+		case a == "GET":
+			opt.Get = true
+		case a == "KEEPTTL" && (unit == "" || unit == a):
+			unit = a
+		case (a == "EX" || a == "PX" || a == "EXAT" || a == "PXAT") && (unit == "" || unit == a) && i+1 < len(args):
+			unit, expire = a, args[i+1]
+			i++
 		default:
-			return proto.Int(w, int64(secs))
+			return proto.Err(c, "syntax error")
 		}
-	})
+	}
 
-	d.Register("PERSIST", 1, 1, true, func(w io.Writer, args []string) error {
-		hasExp := kv.Persist(args[0])
-		if hasExp {
-			return proto.Int(w, 1)
+	// journaled with an absolute expiry so a replay doesn't extend it
+	record := []string{"SET", key, val}
+	switch unit {
+	case "KEEPTTL":
+		opt.KeepTTL = true
+		record = append(record, "KEEPTTL")
+	case "EX", "PX", "EXAT", "PXAT":
+		n, err := strconv.ParseInt(expire, 10, 64)
+		if err != nil {
+			return proto.Err(c, "value is not an integer or out of range")
 		}
-		return proto.Int(w, 0)
-	})
+		now := time.UnixMilli(time.Now().UnixMilli())
+		at, ok := expiryTime(unit, n, now)
+		if n <= 0 || !ok {
+			return proto.Err(c, "invalid expire time in 'set' command")
+		}
+		if unit == "EX" || unit == "PX" {
+			// relative expiries follow the store's clock
+			opt.TTL = at.Sub(now)
+		} else {
+			opt.ExpiresAt = at
+		}
+	}
+
+	old, existed, written, at, err := kv.SetWith(key, val, opt)
+	if err != nil {
+		return storeErr(c, err)
+	}
+	if written {
+		expires := unit != "" && unit != "KEEPTTL"
+		if expires {
+			record = append(record, "PXAT", strconv.FormatInt(at.UnixMilli(), 10))
+		}
+		c.Propagate(record...)
+		d.notify(c.DB, notify.String, "set", key)
+		if expires {
+			d.notify(c.DB, notify.Generic, "expire", key)
+		}
+	} else {
+		c.SkipPropagate()
+	}
+
+	switch {
+	case opt.Get && existed:
+		return proto.Bulk(c, old)
+	case opt.Get, !written:
+		return proto.NullBulk(c)
+	default:
+		return proto.OK(c)
+	}
+}
+
+// SETEX key seconds value, and PSETEX with milliseconds
+func setex(d *Dispatcher, dbs *store.Databases, name, unit string) Handler {
+	return func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		now := time.UnixMilli(time.Now().UnixMilli())
+		at, ok := expiryTime(unit, n, now)
+		if n <= 0 || !ok {
+			return proto.Err(c, fmt.Sprintf("invalid expire time in '%s' command", name))
+		}
+
+		// relative to the store's clock, which also decides what's journaled
+		_, _, _, at, _ = kv.SetWith(args[0], args[2], store.SetOptions{TTL: at.Sub(now)})
+		d.notify(c.DB, notify.String, "set", args[0])
+		d.notify(c.DB, notify.Generic, "expire", args[0])
+		c.Propagate("SET", args[0], args[2])
+		c.Propagate("PEXPIREAT", args[0], strconv.FormatInt(at.UnixMilli(), 10))
+		return proto.OK(c)
+	}
+}
+
+// DEL key [key ...]: replies with how many keys existed
+func del(d *Dispatcher, dbs *store.Databases) Handler {
+	return func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		deleted := kv.DelKeys(args...)
+		if len(deleted) == 0 {
+			c.SkipPropagate()
+		}
+		for _, k := range deleted {
+			d.notify(c.DB, notify.Generic, "del", k)
+		}
+		return proto.Int(c, int64(len(deleted)))
+	}
+}
+
+// storeErr replies with an error from the store. WRONGTYPE has its own
+// prefix; everything else is an ERR.
+func storeErr(c *Client, err error) error {
+	if errors.Is(err, store.ErrWrongType) {
+		return proto.Error(c, err.Error())
+	}
+	return proto.Err(c, err.Error())
 }
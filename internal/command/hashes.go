@@ -0,0 +1,364 @@
+package command
+
+import (
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/amir-aharon/goliath/internal/glob"
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func registerHashes(d *Dispatcher, dbs *store.Databases) {
+	// HSET key field value [field value ...]
+	d.Register("HSET", 3, -1, true, func(c *Client, args []string) error {
+		if len(args)%2 == 0 {
+			return proto.Err(c, "wrong number of arguments for 'hset' command")
+		}
+		n, err := dbs.DB(c.DB).HSet(args[0], args[1:]...)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		d.notify(c.DB, notify.Hash, "hset", args[0])
+		return proto.Int(c, int64(n))
+	})
+
+	// HMSET is HSET replying OK, from before HSET took several fields
+	d.Register("HMSET", 3, -1, true, func(c *Client, args []string) error {
+		if len(args)%2 == 0 {
+			return proto.Err(c, "wrong number of arguments for 'hmset' command")
+		}
+		if _, err := dbs.DB(c.DB).HSet(args[0], args[1:]...); err != nil {
+			return storeErr(c, err)
+		}
+		d.notify(c.DB, notify.Hash, "hset", args[0])
+		return proto.OK(c)
+	})
+
+	d.Register("HSETNX", 3, 3, true, func(c *Client, args []string) error {
+		ok, err := dbs.DB(c.DB).HSetNX(args[0], args[1], args[2])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if !ok {
+			c.SkipPropagate()
+			return proto.Int(c, 0)
+		}
+		d.notify(c.DB, notify.Hash, "hset", args[0])
+		return proto.Int(c, 1)
+	})
+
+	d.Register("HGET", 2, 2, false, func(c *Client, args []string) error {
+		v, ok, err := dbs.DB(c.DB).HGet(args[0], args[1])
+		switch {
+		case err != nil:
+			return storeErr(c, err)
+		case !ok:
+			return proto.NullBulk(c)
+		}
+		return proto.Bulk(c, v)
+	})
+
+	d.Register("HMGET", 2, -1, false, func(c *Client, args []string) error {
+		vals, ok, err := dbs.DB(c.DB).HMGet(args[0], args[1:]...)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if err := proto.Array(c, len(vals)); err != nil {
+			return err
+		}
+		for i := range vals {
+			if ok[i] {
+				err = proto.Bulk(c, vals[i])
+			} else {
+				err = proto.NullBulk(c)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	d.Register("HDEL", 2, -1, true, func(c *Client, args []string) error {
+		n, emptied, err := dbs.DB(c.DB).HDel(args[0], args[1:]...)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if n == 0 {
+			c.SkipPropagate()
+		} else {
+			d.notify(c.DB, notify.Hash, "hdel", args[0])
+			if emptied {
+				d.notify(c.DB, notify.Generic, "del", args[0])
+			}
+		}
+		return proto.Int(c, int64(n))
+	})
+
+	d.Register("HLEN", 1, 1, false, func(c *Client, args []string) error {
+		n, err := dbs.DB(c.DB).HLen(args[0])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		return proto.Int(c, int64(n))
+	})
+
+	d.Register("HSTRLEN", 2, 2, false, func(c *Client, args []string) error {
+		n, err := dbs.DB(c.DB).HStrLen(args[0], args[1])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		return proto.Int(c, int64(n))
+	})
+
+	d.Register("HEXISTS", 2, 2, false, func(c *Client, args []string) error {
+		ok, err := dbs.DB(c.DB).HExists(args[0], args[1])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if ok {
+			return proto.Int(c, 1)
+		}
+		return proto.Int(c, 0)
+	})
+
+	d.Register("HGETALL", 1, 1, false, func(c *Client, args []string) error {
+		pairs, err := dbs.DB(c.DB).HGetAll(args[0])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if err := proto.Map(c, len(pairs)/2); err != nil {
+			return err
+		}
+		for _, s := range pairs {
+			if err := proto.Bulk(c, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	d.Register("HKEYS", 1, 1, false, func(c *Client, args []string) error {
+		fields, err := dbs.DB(c.DB).HKeys(args[0])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		return proto.BulkArray(c, fields)
+	})
+
+	d.Register("HVALS", 1, 1, false, func(c *Client, args []string) error {
+		vals, err := dbs.DB(c.DB).HVals(args[0])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		return proto.BulkArray(c, vals)
+	})
+
+	d.Register("HINCRBY", 3, 3, true, func(c *Client, args []string) error {
+		delta, ok := store.ParseInt(args[2])
+		if !ok {
+			return proto.Err(c, store.ErrNotInteger.Error())
+		}
+		n, err := dbs.DB(c.DB).HIncrBy(args[0], args[1], delta)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		d.notify(c.DB, notify.Hash, "hincrby", args[0])
+		return proto.Int(c, n)
+	})
+
+	d.Register("HINCRBYFLOAT", 3, 3, true, func(c *Client, args []string) error {
+		delta, ok := store.ParseFloat(args[2])
+		if !ok {
+			return proto.Err(c, store.ErrNotFloat.Error())
+		}
+		f, at, err := dbs.DB(c.DB).HIncrByFloat(args[0], args[1], delta)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		// recorded as the result, like INCRBYFLOAT, followed by the
+		// field's expiry, which the HSET drops
+		v := store.FormatFloat(f)
+		c.Propagate("HSET", args[0], args[1], v)
+		if !at.IsZero() {
+			c.Propagate(fieldsCmd("HPEXPIREAT", args[0], []string{args[1]}, strconv.FormatInt(at.UnixMilli(), 10))...)
+		}
+		d.notify(c.DB, notify.Hash, "hincrbyfloat", args[0])
+		return proto.Bulk(c, v)
+	})
+
+	d.Register("HSCAN", 2, -1, false, func(c *Client, args []string) error {
+		return hscan(dbs.DB(c.DB), c, args)
+	})
+
+	d.Register("HRANDFIELD", 1, 3, false, func(c *Client, args []string) error {
+		return hrandfield(dbs.DB(c.DB), c, args)
+	})
+}
+
+// HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+func hscan(kv store.Hashes, c *Client, args []string) error {
+	cursor, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return proto.Err(c, "invalid cursor")
+	}
+
+	pattern, count, novalues := "*", 10, false
+	for i := 2; i < len(args); i++ {
+		if strings.EqualFold(args[i], "NOVALUES") {
+			novalues = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return proto.Err(c, "syntax error")
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return proto.Err(c, "value is not an integer or out of range")
+			}
+			if n < 1 {
+				return proto.Err(c, "syntax error")
+			}
+			count = int(min(n, math.MaxInt32))
+		default:
+			return proto.Err(c, "syntax error")
+		}
+		i++
+	}
+
+	pairs, next, err := kv.HScan(args[0], cursor, count)
+	if err != nil {
+		return storeErr(c, err)
+	}
+	var out []string
+	for i := 0; i < len(pairs); i += 2 {
+		if pattern != "*" && !glob.Match(pattern, pairs[i]) {
+			continue
+		}
+		out = append(out, pairs[i])
+		if !novalues {
+			out = append(out, pairs[i+1])
+		}
+	}
+
+	if err := proto.Array(c, 2); err != nil {
+		return err
+	}
+	if err := proto.Bulk(c, strconv.FormatUint(next, 10)); err != nil {
+		return err
+	}
+	return proto.BulkArray(c, out)
+}
+
+// HRANDFIELD key [count [WITHVALUES]]
+func hrandfield(kv store.Hashes, c *Client, args []string) error {
+	if len(args) == 1 {
+		pairs, err := kv.HRandField(args[0], 1)
+		switch {
+		case err != nil:
+			return storeErr(c, err)
+		case len(pairs) == 0:
+			return proto.NullBulk(c)
+		}
+		return proto.Bulk(c, pairs[0])
+	}
+
+	count, ok := parseIndex(args[1])
+	if !ok {
+		return proto.Err(c, "value is not an integer or out of range")
+	}
+	withValues := false
+	if len(args) == 3 {
+		if !strings.EqualFold(args[2], "WITHVALUES") {
+			return proto.Err(c, "syntax error")
+		}
+		withValues = true
+		// as in Redis, so the reply's length, twice the count, can't
+		// overflow
+		if n, _ := strconv.ParseInt(args[1], 10, 64); n < -math.MaxInt64/2 || n > math.MaxInt64/2 {
+			return proto.Err(c, "value is out of range")
+		}
+	}
+
+	if count < -randFieldsBatch {
+		return randFieldsRepeated(kv, c, args[0], -count, withValues)
+	}
+	pairs, err := kv.HRandField(args[0], count)
+	if err != nil {
+		return storeErr(c, err)
+	}
+	n := len(pairs) / 2
+	if !withValues {
+		fields := make([]string, n)
+		for i := range fields {
+			fields[i] = pairs[2*i]
+		}
+		return proto.BulkArray(c, fields)
+	}
+
+	// RESP3 pairs each field with its value, RESP2 flattens them
+	if c.Protocol() < 3 {
+		return proto.BulkArray(c, pairs)
+	}
+	if err := proto.Array(c, n); err != nil {
+		return err
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		if err := proto.BulkArray(c, pairs[i:i+2]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// randFieldsBatch is the largest negative HRANDFIELD count the store picks
+// fields for itself, building the whole reply under the hash's lock.
+const randFieldsBatch = 1024
+
+// randFieldsRepeated replies to HRANDFIELD with a larger negative count, n
+// fields that may repeat. The hash is copied once and the fields are picked
+// from the copy as the reply is written, so a count far past the hash's
+// size costs neither memory nor time under the lock.
+func randFieldsRepeated(kv store.Hashes, c *Client, k string, n int, withValues bool) error {
+	pairs, err := kv.HGetAll(k)
+	if err != nil {
+		return storeErr(c, err)
+	}
+	if len(pairs) == 0 {
+		return proto.Array(c, 0)
+	}
+
+	resp2Pairs := withValues && c.Protocol() < 3
+	size := n
+	if resp2Pairs {
+		size = 2 * n
+	}
+	if err := proto.Array(c, size); err != nil {
+		return err
+	}
+	for range n {
+		i := 2 * rand.IntN(len(pairs)/2)
+		switch {
+		case !withValues:
+			err = proto.Bulk(c, pairs[i])
+		case resp2Pairs:
+			if err = proto.Bulk(c, pairs[i]); err == nil {
+				err = proto.Bulk(c, pairs[i+1])
+			}
+		default:
+			err = proto.BulkArray(c, pairs[i:i+2])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
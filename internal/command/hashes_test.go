@@ -0,0 +1,205 @@
+package command_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/command"
+)
+
+func TestHashCommands(t *testing.T) {
+	d := newDispatcher()
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"HSET", "h", "a", "1", "b", "2"}, ":2\r\n"},
+		{[]string{"HSET", "h", "a", "3"}, ":0\r\n"},
+		{[]string{"HSET", "h", "a"}, "-ERR wrong number of arguments for 'hset' command\r\n"},
+		{[]string{"HMSET", "h", "c", "x"}, "+OK\r\n"},
+		{[]string{"HSETNX", "h", "c", "y"}, ":0\r\n"},
+		{[]string{"HGET", "h", "a"}, "$1\r\n3\r\n"},
+		{[]string{"HGET", "h", "nope"}, "$-1\r\n"},
+		{[]string{"HGET", "missing", "a"}, "$-1\r\n"},
+		{[]string{"HMGET", "h", "b", "nope"}, "*2\r\n$1\r\n2\r\n$-1\r\n"},
+		{[]string{"HMGET", "missing", "b"}, "*1\r\n$-1\r\n"},
+		{[]string{"HGETALL", "h"}, "*6\r\n$1\r\na\r\n$1\r\n3\r\n$1\r\nb\r\n$1\r\n2\r\n$1\r\nc\r\n$1\r\nx\r\n"},
+		{[]string{"HGETALL", "missing"}, "*0\r\n"},
+		{[]string{"HKEYS", "h"}, "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n"},
+		{[]string{"HVALS", "h"}, "*3\r\n$1\r\n3\r\n$1\r\n2\r\n$1\r\nx\r\n"},
+		{[]string{"HLEN", "h"}, ":3\r\n"},
+		{[]string{"HSTRLEN", "h", "a"}, ":1\r\n"},
+		{[]string{"HEXISTS", "h", "a"}, ":1\r\n"},
+		{[]string{"HEXISTS", "h", "nope"}, ":0\r\n"},
+		{[]string{"HINCRBY", "h", "a", "10"}, ":13\r\n"},
+		{[]string{"HINCRBY", "h", "c", "1"}, "-ERR hash value is not an integer\r\n"},
+		{[]string{"HINCRBY", "h", "a", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"HINCRBY", "h", "a", "+5"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"HINCRBYFLOAT", "h", "a", "0.5"}, "$4\r\n13.5\r\n"},
+		{[]string{"HINCRBYFLOAT", "h", "c", "1"}, "-ERR hash value is not a float\r\n"},
+		{[]string{"HDEL", "h", "a", "nope"}, ":1\r\n"},
+		{[]string{"HDEL", "h", "b", "c"}, ":2\r\n"},
+		{[]string{"EXISTS", "h"}, ":0\r\n"},
+		{[]string{"HRANDFIELD", "missing"}, "$-1\r\n"},
+		{[]string{"HRANDFIELD", "missing", "3"}, "*0\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestHSCAN_HRANDFIELD(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "HSET", "h", "name", "ann", "nick", "a", "age", "30")
+
+	cases := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"HSCAN", "h", "0"}, "*2\r\n$1\r\n0\r\n*6\r\n$4\r\nname\r\n$3\r\nann\r\n$4\r\nnick\r\n$1\r\na\r\n$3\r\nage\r\n$2\r\n30\r\n"},
+		{[]string{"HSCAN", "h", "0", "MATCH", "n*", "COUNT", "1"}, "*2\r\n$1\r\n0\r\n*4\r\n$4\r\nname\r\n$3\r\nann\r\n$4\r\nnick\r\n$1\r\na\r\n"},
+		{[]string{"HSCAN", "h", "0", "MATCH", "a*", "NOVALUES"}, "*2\r\n$1\r\n0\r\n*1\r\n$3\r\nage\r\n"},
+		{[]string{"HSCAN", "missing", "0"}, "*2\r\n$1\r\n0\r\n*0\r\n"},
+		{[]string{"HSCAN", "h", "x"}, "-ERR invalid cursor\r\n"},
+		{[]string{"HSCAN", "h", "0", "COUNT", "0"}, "-ERR syntax error\r\n"},
+		{[]string{"HSCAN", "h", "0", "MATCH"}, "-ERR syntax error\r\n"},
+		{[]string{"HRANDFIELD", "h", "1", "WITHSCORES"}, "-ERR syntax error\r\n"},
+		{[]string{"HRANDFIELD", "h", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"HRANDFIELD", "h", "0"}, "*0\r\n"},
+		{[]string{"HRANDFIELD", "h", "-4611686018427387904", "WITHVALUES"}, "-ERR value is out of range\r\n"},
+	}
+	for _, c := range cases {
+		if got := mustRun(t, d, c.cmd[0], c.cmd[1:]...); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.cmd, got, c.want)
+		}
+	}
+
+	got := mustRun(t, d, "HRANDFIELD", "h")
+	if got != "$4\r\nname\r\n" && got != "$4\r\nnick\r\n" && got != "$3\r\nage\r\n" {
+		t.Fatalf("HRANDFIELD: got %q", got)
+	}
+	if got := mustRun(t, d, "HRANDFIELD", "h", "5", "WITHVALUES"); !strings.HasPrefix(got, "*6\r\n") {
+		t.Fatalf("HRANDFIELD 5 WITHVALUES: got %q", got)
+	}
+	if got := mustRun(t, d, "HRANDFIELD", "h", "-5"); !strings.HasPrefix(got, "*5\r\n") {
+		t.Fatalf("HRANDFIELD -5: got %q", got)
+	}
+}
+
+// cappedWriter fails writes once more than max bytes were written.
+type cappedWriter struct {
+	n, max int
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if w.n += len(p); w.n > w.max {
+		return 0, errors.New("reply too long")
+	}
+	return len(p), nil
+}
+
+func TestHRANDFIELD_LargeNegativeCount(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "HSET", "h", "f", "v")
+
+	if got, want := mustRun(t, d, "HRANDFIELD", "h", "-5000"), "*5000\r\n"+strings.Repeat("$1\r\nf\r\n", 5000); got != want {
+		t.Fatalf("HRANDFIELD -5000: got %d bytes, want %d", len(got), len(want))
+	}
+	if got, want := mustRun(t, d, "HRANDFIELD", "h", "-5000", "WITHVALUES"), "*10000\r\n"+strings.Repeat("$1\r\nf\r\n$1\r\nv\r\n", 5000); got != want {
+		t.Fatalf("HRANDFIELD -5000 WITHVALUES: got %d bytes, want %d", len(got), len(want))
+	}
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+	c.Proto = 3
+	must(t, d.Dispatch(c, "HRANDFIELD", []string{"h", "-5000", "WITHVALUES"}))
+	if got, want := buf.String(), "*5000\r\n"+strings.Repeat("*2\r\n$1\r\nf\r\n$1\r\nv\r\n", 5000); got != want {
+		t.Fatalf("RESP3 HRANDFIELD -5000 WITHVALUES: got %d bytes, want %d", len(got), len(want))
+	}
+	if got := mustRun(t, d, "HRANDFIELD", "missing", "-5000"); got != "*0\r\n" {
+		t.Fatalf("HRANDFIELD missing -5000: got %q", got)
+	}
+
+	// the reply is written as the fields are picked, so a client that
+	// stops reading stops it before billions of them are built
+	w := &cappedWriter{max: 1 << 20}
+	if err := d.Dispatch(command.NewClient(w), "HRANDFIELD", []string{"h", "-2147483648"}); err == nil {
+		t.Fatalf("HRANDFIELD -2147483648: expected the capped writer to fail")
+	}
+}
+
+func TestHashes_JournalAndNotify(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+	n := &fakeNotifier{}
+	d.SetNotifier(n)
+
+	mustRun(t, d, "HSET", "h", "f", "1")
+	mustRun(t, d, "HSETNX", "h", "f", "2")
+	mustRun(t, d, "HINCRBYFLOAT", "h", "f", "1.5")
+	mustRun(t, d, "HINCRBY", "h", "g", "2")
+	mustRun(t, d, "HDEL", "h", "nope")
+	mustRun(t, d, "HDEL", "h", "f", "g")
+
+	wantRecords := [][]string{
+		{"HSET", "h", "f", "1"},
+		{"HSET", "h", "f", "2.5"},
+		{"HINCRBY", "h", "g", "2"},
+		{"HDEL", "h", "f", "g"},
+	}
+	if !reflect.DeepEqual(j.records, wantRecords) {
+		t.Fatalf("journal:\n got %q\nwant %q", j.records, wantRecords)
+	}
+	wantEvents := []string{
+		"h hset h",
+		"h hincrbyfloat h",
+		"h hincrby h",
+		"h hdel h",
+		"g del h",
+	}
+	if !reflect.DeepEqual(n.events, wantEvents) {
+		t.Fatalf("events:\n got %q\nwant %q", n.events, wantEvents)
+	}
+}
+
+func TestHashes_WrongType(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "SET", "s", "v")
+	mustRun(t, d, "HSET", "h", "f", "v")
+
+	const wrongType = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	for _, cmd := range [][]string{
+		{"HSET", "s", "f", "v"},
+		{"HGET", "s", "f"},
+		{"HGETALL", "s"},
+		{"HINCRBY", "s", "f", "1"},
+		{"HSCAN", "s", "0"},
+		{"HRANDFIELD", "s"},
+		{"GET", "h"},
+		{"LPUSH", "h", "a"},
+	} {
+		if got := mustRun(t, d, cmd[0], cmd[1:]...); got != wrongType {
+			t.Errorf("%q: got %q", cmd, got)
+		}
+	}
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"TYPE", "h"}, "+hash\r\n"},
+		{[]string{"OBJECT", "ENCODING", "h"}, "$8\r\nlistpack\r\n"},
+		{[]string{"SCAN", "0", "TYPE", "hash"}, "*2\r\n$1\r\n0\r\n*1\r\n$1\r\nh\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
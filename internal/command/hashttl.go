@@ -0,0 +1,243 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func registerHashTTL(d *Dispatcher, dbs *store.Databases) {
+	d.Register("HEXPIRE", 5, -1, true, hexpire(d, dbs, "hexpire", "EX"))
+	d.Register("HPEXPIRE", 5, -1, true, hexpire(d, dbs, "hpexpire", "PX"))
+	d.Register("HEXPIREAT", 5, -1, true, hexpire(d, dbs, "hexpireat", "EXAT"))
+	d.Register("HPEXPIREAT", 5, -1, true, hexpire(d, dbs, "hpexpireat", "PXAT"))
+
+	d.Register("HTTL", 4, -1, false, httl(dbs, time.Second))
+	d.Register("HPTTL", 4, -1, false, httl(dbs, time.Millisecond))
+	d.Register("HEXPIRETIME", 4, -1, false, hexpireTime(dbs, time.Second))
+	d.Register("HPEXPIRETIME", 4, -1, false, hexpireTime(dbs, time.Millisecond))
+
+	// HPERSIST key FIELDS numfields field [field ...]
+	d.Register("HPERSIST", 4, -1, true, func(c *Client, args []string) error {
+		fields, msg := parseFields(args[1:])
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+		res, _, _, err := dbs.DB(c.DB).HExpire(args[0], store.ExpireOptions{Persist: true}, fields...)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		var persisted []string
+		codes := make([]int64, len(res))
+		for i, r := range res {
+			switch r {
+			case store.ExpireNoField:
+				codes[i] = -2
+			case store.ExpireSkipped:
+				codes[i] = -1
+			default:
+				codes[i] = 1
+				persisted = append(persisted, fields[i])
+			}
+		}
+		if len(persisted) == 0 {
+			c.SkipPropagate()
+		} else {
+			c.Propagate(fieldsCmd("HPERSIST", args[0], persisted)...)
+			d.notify(c.DB, notify.Hash, "hpersist", args[0])
+		}
+		return intArray(c, codes)
+	})
+}
+
+// HEXPIRE key seconds [NX | XX | GT | LT] FIELDS numfields field [field ...],
+// and likewise HPEXPIRE, HEXPIREAT and HPEXPIREAT in the unit of the
+// matching SET option
+func hexpire(d *Dispatcher, dbs *store.Databases, name, unit string) Handler {
+	return func(c *Client, args []string) error {
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		if n < 0 {
+			return proto.Err(c, "invalid expire time, must be >= 0")
+		}
+		now := time.UnixMilli(time.Now().UnixMilli())
+		at, ok := expiryTime(unit, n, now)
+		if !ok {
+			return proto.Err(c, fmt.Sprintf("invalid expire time in '%s' command", name))
+		}
+
+		// unlike EXPIRE's, at most one condition
+		rest := args[2:]
+		var cond store.ExpireCond
+		switch strings.ToUpper(rest[0]) {
+		case "NX":
+			cond = store.ExpireNX
+		case "XX":
+			cond = store.ExpireXX
+		case "GT":
+			cond = store.ExpireGT
+		case "LT":
+			cond = store.ExpireLT
+		}
+		if cond != 0 {
+			rest = rest[1:]
+		}
+		fields, msg := parseFields(rest)
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+
+		opt := store.ExpireOptions{Cond: cond, At: at}
+		if unit == "EX" || unit == "PX" {
+			// relative expiries follow the store's clock
+			opt = store.ExpireOptions{Cond: cond, TTL: at.Sub(now)}
+		}
+		res, at, emptied, err := dbs.DB(c.DB).HExpire(args[0], opt, fields...)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		return hexpireReply(d, c, args[0], at, fields, res, emptied)
+	}
+}
+
+// hexpireReply replies to and propagates field expiry changes: -2 for a
+// missing field, 0 when the condition wasn't met, 1 when the expiry was set
+// and 2 when it had already passed and the field was deleted. As with
+// EXPIRE, the conditions don't matter on replay, so the fields are
+// journaled as a plain HPEXPIREAT and an HDEL.
+func hexpireReply(d *Dispatcher, c *Client, key string, at time.Time, fields []string, res []store.ExpireResult, emptied bool) error {
+	var set, deleted []string
+	codes := make([]int64, len(res))
+	for i, r := range res {
+		switch r {
+		case store.ExpireNoField:
+			codes[i] = -2
+		case store.ExpireSet:
+			codes[i] = 1
+			set = append(set, fields[i])
+		case store.ExpireDeleted:
+			codes[i] = 2
+			deleted = append(deleted, fields[i])
+		}
+	}
+
+	if len(set) == 0 && len(deleted) == 0 {
+		c.SkipPropagate()
+	}
+	if len(set) > 0 {
+		c.Propagate(fieldsCmd("HPEXPIREAT", key, set, strconv.FormatInt(at.UnixMilli(), 10))...)
+		d.notify(c.DB, notify.Hash, "hexpire", key)
+	}
+	if len(deleted) > 0 {
+		c.Propagate(append([]string{"HDEL", key}, deleted...)...)
+		d.notify(c.DB, notify.Hash, "hexpired", key)
+		if emptied {
+			d.notify(c.DB, notify.Generic, "del", key)
+		}
+	}
+	return intArray(c, codes)
+}
+
+// HTTL key FIELDS numfields field [field ...]: -2 for a missing field, -1
+// for one without an expiry and the remaining time otherwise; HPTTL in
+// milliseconds
+func httl(dbs *store.Databases, unit time.Duration) Handler {
+	return func(c *Client, args []string) error {
+		fields, msg := parseFields(args[1:])
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+		ttls, found, err := dbs.DB(c.DB).HTTL(args[0], fields...)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		codes := make([]int64, len(fields))
+		for i, left := range ttls {
+			switch {
+			case !found[i]:
+				codes[i] = -2
+			case left < 0:
+				codes[i] = -1
+			case unit == time.Second:
+				// rounded to the nearest second, like TTL
+				codes[i] = (left.Milliseconds() + 500) / 1000
+			default:
+				codes[i] = left.Milliseconds()
+			}
+		}
+		return intArray(c, codes)
+	}
+}
+
+// HEXPIRETIME and HPEXPIRETIME: each field's absolute expiry as a unix
+// timestamp, with the same -2 and -1 as HTTL
+func hexpireTime(dbs *store.Databases, unit time.Duration) Handler {
+	return func(c *Client, args []string) error {
+		fields, msg := parseFields(args[1:])
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+		ats, found, err := dbs.DB(c.DB).HExpireTime(args[0], fields...)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		codes := make([]int64, len(fields))
+		for i, at := range ats {
+			switch {
+			case !found[i]:
+				codes[i] = -2
+			case at.IsZero():
+				codes[i] = -1
+			case unit == time.Second:
+				codes[i] = (at.UnixMilli() + 500) / 1000
+			default:
+				codes[i] = at.UnixMilli()
+			}
+		}
+		return intArray(c, codes)
+	}
+}
+
+// parseFields parses "FIELDS numfields field [field ...]", the tail of
+// every field expiry command, returning the error to reply otherwise.
+func parseFields(args []string) ([]string, string) {
+	if len(args) < 2 || !strings.EqualFold(args[0], "FIELDS") {
+		return nil, "Mandatory argument FIELDS is missing or not at the right position"
+	}
+	n, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || n <= 0 {
+		return nil, "Parameter `numFields` should be greater than 0"
+	}
+	if n != int64(len(args)-2) {
+		return nil, "The `numfields` parameter must match the number of arguments"
+	}
+	return args[2:], ""
+}
+
+// fieldsCmd builds name key [extra ...] FIELDS numfields field [field ...].
+func fieldsCmd(name, key string, fields []string, extra ...string) []string {
+	cmd := make([]string, 0, 4+len(extra)+len(fields))
+	cmd = append(cmd, name, key)
+	cmd = append(cmd, extra...)
+	cmd = append(cmd, "FIELDS", strconv.Itoa(len(fields)))
+	return append(cmd, fields...)
+}
+
+func intArray(c *Client, ns []int64) error {
+	if err := proto.Array(c, len(ns)); err != nil {
+		return err
+	}
+	for _, n := range ns {
+		if err := proto.Int(c, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package command_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHashFieldTTL(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fc := newFakeClock(start)
+	d := newDispatcherWithClock(fc)
+	mustRun(t, d, "HSET", "h", "a", "1", "b", "2", "c", "3")
+	at := strconv.FormatInt(start.Add(time.Hour).UnixMilli(), 10)
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"HEXPIRE", "h", "100", "FIELDS", "2", "a", "nope"}, "*2\r\n:1\r\n:-2\r\n"},
+		{[]string{"HEXPIRE", "h", "50", "NX", "FIELDS", "1", "a"}, "*1\r\n:0\r\n"},
+		{[]string{"HEXPIRE", "h", "50", "GT", "FIELDS", "1", "a"}, "*1\r\n:0\r\n"},
+		{[]string{"HPEXPIRE", "h", "50000", "lt", "FIELDS", "1", "a"}, "*1\r\n:1\r\n"},
+		{[]string{"HPEXPIREAT", "h", at, "XX", "FIELDS", "2", "a", "b"}, "*2\r\n:1\r\n:0\r\n"},
+		{[]string{"HEXPIRE", "missing", "10", "FIELDS", "1", "a"}, "*1\r\n:-2\r\n"},
+		{[]string{"HTTL", "h", "FIELDS", "3", "a", "b", "nope"}, "*3\r\n:3600\r\n:-1\r\n:-2\r\n"},
+		{[]string{"HPTTL", "h", "FIELDS", "1", "a"}, "*1\r\n:3600000\r\n"},
+		{[]string{"HEXPIRETIME", "h", "FIELDS", "1", "a"}, "*1\r\n:1700003600\r\n"},
+		{[]string{"HPEXPIRETIME", "h", "FIELDS", "2", "a", "b"}, "*2\r\n:" + at + "\r\n:-1\r\n"},
+		{[]string{"HPERSIST", "h", "FIELDS", "3", "a", "b", "nope"}, "*3\r\n:1\r\n:-1\r\n:-2\r\n"},
+		{[]string{"HTTL", "h", "FIELDS", "1", "a"}, "*1\r\n:-1\r\n"},
+		{[]string{"HEXPIREAT", "h", "1", "FIELDS", "1", "c"}, "*1\r\n:2\r\n"},
+		{[]string{"HEXISTS", "h", "c"}, ":0\r\n"},
+		{[]string{"HEXPIRE", "h", "0", "FIELDS", "2", "a", "b"}, "*2\r\n:2\r\n:2\r\n"},
+		{[]string{"EXISTS", "h"}, ":0\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestHashFieldTTL_Expiry(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	d := newDispatcherWithClock(fc)
+	mustRun(t, d, "HSET", "h", "a", "1", "b", "2")
+	mustRun(t, d, "HEXPIRE", "h", "10", "FIELDS", "1", "a")
+
+	fc.Advance(11 * time.Second)
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"HGET", "h", "a"}, "$-1\r\n"},
+		{[]string{"HGETALL", "h"}, "*2\r\n$1\r\nb\r\n$1\r\n2\r\n"},
+		{[]string{"HLEN", "h"}, ":1\r\n"},
+		{[]string{"HEXPIRE", "h", "10", "FIELDS", "1", "b"}, "*1\r\n:1\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+
+	fc.Advance(11 * time.Second)
+	if got := mustRun(t, d, "TYPE", "h"); got != "+none\r\n" {
+		t.Fatalf("TYPE after the last field expired: got %q", got)
+	}
+}
+
+func TestHashFieldTTL_Errors(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "HSET", "h", "a", "1")
+	mustRun(t, d, "SET", "s", "v")
+
+	cases := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"HEXPIRE", "h", "x", "FIELDS", "1", "a"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"HEXPIRE", "h", "-1", "FIELDS", "1", "a"}, "-ERR invalid expire time, must be >= 0\r\n"},
+		{[]string{"HEXPIRE", "h", "9223372036854775807", "FIELDS", "1", "a"}, "-ERR invalid expire time in 'hexpire' command\r\n"},
+		{[]string{"HEXPIRE", "h", "10", "NX", "XX", "FIELDS", "1", "a"}, "-ERR Mandatory argument FIELDS is missing or not at the right position\r\n"},
+		{[]string{"HEXPIRE", "h", "10", "FIELDS", "0", "a"}, "-ERR Parameter `numFields` should be greater than 0\r\n"},
+		{[]string{"HEXPIRE", "h", "10", "FIELDS", "2", "a"}, "-ERR The `numfields` parameter must match the number of arguments\r\n"},
+		{[]string{"HTTL", "h", "FIELD", "1", "a"}, "-ERR Mandatory argument FIELDS is missing or not at the right position\r\n"},
+		{[]string{"HPERSIST", "h", "FIELDS", "x", "a"}, "-ERR Parameter `numFields` should be greater than 0\r\n"},
+		{[]string{"HTTL", "s", "FIELDS", "1", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"HEXPIRE", "s", "10", "FIELDS", "1", "a"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+	}
+	for _, c := range cases {
+		if got := mustRun(t, d, c.cmd[0], c.cmd[1:]...); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.cmd, got, c.want)
+		}
+	}
+}
+
+func TestHashFieldTTL_JournalAndNotify(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+	n := &fakeNotifier{}
+	d.SetNotifier(n)
+
+	mustRun(t, d, "HSET", "h", "a", "1", "b", "2")
+	before := time.Now().UnixMilli()
+	mustRun(t, d, "HEXPIRE", "h", "100", "FIELDS", "2", "a", "nope")
+	after := time.Now().UnixMilli()
+	mustRun(t, d, "HEXPIRE", "h", "100", "NX", "FIELDS", "1", "a")
+	mustRun(t, d, "HPERSIST", "h", "FIELDS", "2", "a", "b")
+	mustRun(t, d, "HPERSIST", "h", "FIELDS", "1", "a")
+	mustRun(t, d, "HEXPIREAT", "h", "1", "FIELDS", "2", "a", "b")
+
+	if len(j.records) != 4 {
+		t.Fatalf("journal: got %q", j.records)
+	}
+	rec := j.records[1]
+	ms, err := strconv.ParseInt(rec[2], 10, 64)
+	if rec[0] != "HPEXPIREAT" || err != nil || ms < before+100_000 || ms > after+100_000 {
+		t.Fatalf("HEXPIRE recorded as %q", rec)
+	}
+	if want := []string{"HPEXPIREAT", "h", rec[2], "FIELDS", "1", "a"}; !reflect.DeepEqual(rec, want) {
+		t.Fatalf("HEXPIRE recorded as %q, want %q", rec, want)
+	}
+	wantRest := [][]string{
+		{"HPERSIST", "h", "FIELDS", "1", "a"},
+		{"HDEL", "h", "a", "b"},
+	}
+	if !reflect.DeepEqual(j.records[2:], wantRest) {
+		t.Fatalf("journal:\n got %q\nwant %q", j.records[2:], wantRest)
+	}
+
+	wantEvents := []string{
+		"h hset h",
+		"h hexpire h",
+		"h hpersist h",
+		"h hexpired h",
+		"g del h",
+	}
+	if !reflect.DeepEqual(n.events, wantEvents) {
+		t.Fatalf("events:\n got %q\nwant %q", n.events, wantEvents)
+	}
+}
+
+func TestHashFieldTTL_IncrByFloatReplay(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	d := newDispatcherWithClock(fc)
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	mustRun(t, d, "HSET", "h", "f", "1")
+	mustRun(t, d, "HEXPIRE", "h", "100", "FIELDS", "1", "f")
+	mustRun(t, d, "HINCRBYFLOAT", "h", "f", "0.5")
+
+	// the journal alone must rebuild the field with its TTL
+	replica := newDispatcherWithClock(fc)
+	for _, rec := range j.records {
+		mustRun(t, replica, rec[0], rec[1:]...)
+	}
+	for _, cmd := range [][]string{{"HGET", "h", "f"}, {"HPEXPIRETIME", "h", "FIELDS", "1", "f"}} {
+		want := mustRun(t, d, cmd[0], cmd[1:]...)
+		if got := mustRun(t, replica, cmd[0], cmd[1:]...); got != want {
+			t.Fatalf("%q after replay: got %q, want %q", cmd, got, want)
+		}
+	}
+}
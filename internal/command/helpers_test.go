@@ -10,6 +10,7 @@ import (
 )
 
 type fakeClock struct{ t time.Time }
+
 func newFakeClock(t time.Time) *fakeClock    { return &fakeClock{t: t} }
 func (f *fakeClock) Now() time.Time          { return f.t }
 func (f *fakeClock) Advance(d time.Duration) { f.t = f.t.Add(d) }
@@ -17,28 +18,37 @@ func (f *fakeClock) Advance(d time.Duration) { f.t = f.t.Add(d) }
 func newDispatcher() *command.Dispatcher {
 	d := command.NewDispatcher()
 	command.RegisterBuiltins(d)
-	kv := store.NewMemory()
-	command.RegisterKV(d, kv)
-	command.RegisterTTL(d, kv)
+	dbs := store.NewDatabases()
+	command.RegisterKV(d, dbs)
+	command.RegisterTTL(d, dbs)
 	return d
 }
 
 func newDispatcherWithClock(c store.Clock) *command.Dispatcher {
 	d := command.NewDispatcher()
 	command.RegisterBuiltins(d)
-	kv := store.NewMemoryWithClock(c)
-	command.RegisterKV(d, kv)
-	command.RegisterTTL(d, kv)
+	dbs := store.NewDatabasesWithClock(c)
+	command.RegisterKV(d, dbs)
+	command.RegisterTTL(d, dbs)
 	return d
 }
 
 func run(d *command.Dispatcher, name string, args ...string) (string, error) {
 	var buf bytes.Buffer
-	err := d.Dispatch(&buf, name, args)
+	err := d.Dispatch(command.NewClient(&buf), name, args)
 	return buf.String(), err
 }
 
 func must(t *testing.T, err error) {
 	t.Helper()
-	if err != nil { t.Fatalf("unexpected error: %v", err) }
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func mustRun(t *testing.T, d *command.Dispatcher, name string, args ...string) string {
+	t.Helper()
+	got, err := run(d, name, args...)
+	must(t, err)
+	return got
 }
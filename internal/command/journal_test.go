@@ -0,0 +1,111 @@
+package command_test
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/command"
+)
+
+type fakeJournal struct {
+	records [][]string
+	dbs     []int
+}
+
+func (j *fakeJournal) Append(db int, args []string) error {
+	j.records = append(j.records, args)
+	j.dbs = append(j.dbs, db)
+	return nil
+}
+
+func TestJournal_RecordsSuccessfulMutatingCommands(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	mustRun(t, d, "set", "k", "v")
+	mustRun(t, d, "GET", "k")                // read-only
+	mustRun(t, d, "SETEX", "k", "nope", "v") // error reply
+	mustRun(t, d, "DEL", "k")
+
+	want := [][]string{{"SET", "k", "v"}, {"DEL", "k"}}
+	if !reflect.DeepEqual(j.records, want) {
+		t.Fatalf("journal: got %q, want %q", j.records, want)
+	}
+}
+
+func TestChanges_CountedWithoutJournals(t *testing.T) {
+	d := newDispatcher()
+	// one connection throughout, so state left by a failed or skipped
+	// command can't leak into the next
+	c := command.NewClient(io.Discard)
+	for _, cmd := range [][]string{
+		{"SET", "k", "v"},
+		{"GET", "k"},                // read-only
+		{"SETEX", "k", "nope", "v"}, // error reply
+		{"SET", "k", "v", "NX"},     // changed nothing
+		{"HSET", "h", "f", "1"},
+		{"HINCRBYFLOAT", "h", "f", "1"}, // propagated as an HSET
+		{"DEL", "k", "h"},
+	} {
+		if err := d.Dispatch(c, cmd[0], cmd[1:]); err != nil {
+			t.Fatalf("%q: %v", cmd, err)
+		}
+	}
+	if got := d.Changes(); got != 4 {
+		t.Fatalf("Changes: got %d, want 4", got)
+	}
+}
+
+func TestJournal_SETEXPropagatesAbsoluteExpiry(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	before := time.Now().Add(10 * time.Second).UnixMilli()
+	mustRun(t, d, "SETEX", "k", "10", "v")
+	after := time.Now().Add(10 * time.Second).UnixMilli()
+
+	if len(j.records) != 2 {
+		t.Fatalf("journal: got %q, want SET + PEXPIREAT", j.records)
+	}
+	if want := []string{"SET", "k", "v"}; !reflect.DeepEqual(j.records[0], want) {
+		t.Fatalf("first record: got %q, want %q", j.records[0], want)
+	}
+	rec := j.records[1]
+	at, err := strconv.ParseInt(rec[2], 10, 64)
+	if rec[0] != "PEXPIREAT" || rec[1] != "k" || err != nil || at < before || at > after {
+		t.Fatalf("second record: got %q, want PEXPIREAT k <%d..%d>", rec, before, after)
+	}
+}
+
+func TestJournal_RelativeExpiriesFollowTheStoreClock(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0) // far from the wall clock
+	d := newDispatcherWithClock(newFakeClock(start))
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	mustRun(t, d, "SET", "k", "v", "EX", "10")
+	mustRun(t, d, "SETEX", "k", "10", "v")
+	mustRun(t, d, "EXPIRE", "k", "10")
+	mustRun(t, d, "GETEX", "k", "PX", "10000")
+	mustRun(t, d, "HSET", "h", "f", "1")
+	mustRun(t, d, "HEXPIRE", "h", "10", "FIELDS", "1", "f")
+
+	at := strconv.FormatInt(start.Add(10*time.Second).UnixMilli(), 10)
+	want := [][]string{
+		{"SET", "k", "v", "PXAT", at},
+		{"SET", "k", "v"},
+		{"PEXPIREAT", "k", at},
+		{"PEXPIREAT", "k", at},
+		{"PEXPIREAT", "k", at},
+		{"HSET", "h", "f", "1"},
+		{"HPEXPIREAT", "h", at, "FIELDS", "1", "f"},
+	}
+	if !reflect.DeepEqual(j.records, want) {
+		t.Fatalf("journal:\n got %q\nwant %q", j.records, want)
+	}
+}
@@ -0,0 +1,225 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/glob"
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// keysBatch is how many keys KEYS takes from the store per lock
+// acquisition.
+const keysBatch = 1024
+
+func registerKeyspace(d *Dispatcher, dbs *store.Databases) {
+	d.Register("KEYS", 1, 1, false, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		var keys []string
+		for cursor := uint64(0); ; {
+			var batch []string
+			batch, cursor = kv.Scan(cursor, keysBatch)
+			keys = appendMatching(keys, batch, args[0])
+			if cursor == 0 {
+				break
+			}
+		}
+		return proto.BulkArray(c, keys)
+	})
+
+	d.Register("SCAN", 1, -1, false, func(c *Client, args []string) error {
+		return scan(dbs.DB(c.DB), c, args)
+	})
+
+	d.Register("RANDOMKEY", 0, 0, false, func(c *Client, _ []string) error {
+		kv := dbs.DB(c.DB)
+		if k, ok := kv.RandomKey(); ok {
+			return proto.Bulk(c, k)
+		}
+		return proto.NullBulk(c)
+	})
+
+	d.Register("DBSIZE", 0, 0, false, func(c *Client, _ []string) error {
+		kv := dbs.DB(c.DB)
+		return proto.Int(c, int64(kv.Len()))
+	})
+
+	d.Register("RENAME", 2, 2, true, rename(d, dbs, false))
+	d.Register("RENAMENX", 2, 2, true, rename(d, dbs, true))
+
+	d.Register("COPY", 2, -1, true, func(c *Client, args []string) error {
+		return copyKey(d, dbs, c, args)
+	})
+
+	d.Register("TYPE", 1, 1, false, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		return proto.Simple(c, kv.Type(args[0]).String())
+	})
+
+	d.Register("TOUCH", 1, -1, false, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		return proto.Int(c, int64(kv.Touch(args...)))
+	})
+
+	// OBJECT ENCODING | IDLETIME | FREQ key
+	d.Register("OBJECT", 1, -1, false, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		sub := strings.ToUpper(args[0])
+		switch sub {
+		case "ENCODING", "IDLETIME", "FREQ":
+		default:
+			return proto.Err(c, fmt.Sprintf("unknown subcommand '%s'. Try OBJECT HELP.", args[0]))
+		}
+		if len(args) != 2 {
+			return proto.Err(c, fmt.Sprintf("wrong number of arguments for 'object|%s' command", strings.ToLower(sub)))
+		}
+
+		info, ok := kv.Object(args[1])
+		if !ok {
+			return proto.NullBulk(c)
+		}
+		switch sub {
+		case "ENCODING":
+			return proto.Bulk(c, info.Encoding)
+		case "IDLETIME":
+			return proto.Int(c, int64(info.Idle/time.Second))
+		default:
+			return proto.Int(c, int64(info.Freq))
+		}
+	})
+}
+
+// RENAME key newkey, and RENAMENX which won't overwrite newkey
+func rename(d *Dispatcher, dbs *store.Databases, nx bool) Handler {
+	return func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		src, dst := args[0], args[1]
+		moved, err := kv.Rename(src, dst, nx)
+		if err != nil {
+			return proto.Err(c, err.Error())
+		}
+		if moved && src != dst {
+			d.notify(c.DB, notify.Generic, "rename_from", src)
+			d.notify(c.DB, notify.Generic, "rename_to", dst)
+			d.keyReady(c, dbs, c.DB, dst)
+		} else {
+			c.SkipPropagate()
+		}
+
+		if !nx {
+			return proto.OK(c)
+		}
+		if moved {
+			return proto.Int(c, 1)
+		}
+		return proto.Int(c, 0)
+	}
+}
+
+// COPY source destination [DB destination-db] [REPLACE]
+func copyKey(d *Dispatcher, dbs *store.Databases, c *Client, args []string) error {
+	src, dst := args[0], args[1]
+
+	to, replace := c.DB, false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "REPLACE":
+			replace = true
+		case "DB":
+			if i+1 >= len(args) {
+				return proto.Err(c, "syntax error")
+			}
+			db, msg := dbIndex(dbs, args[i+1])
+			if msg != "" {
+				return proto.Err(c, msg)
+			}
+			to = db
+			i++
+		default:
+			return proto.Err(c, "syntax error")
+		}
+	}
+
+	if src == dst && to == c.DB {
+		return proto.Err(c, "source and destination objects are the same")
+	}
+	if !dbs.Copy(src, c.DB, dst, to, replace) {
+		c.SkipPropagate()
+		return proto.Int(c, 0)
+	}
+	d.notify(to, notify.Generic, "copy_to", dst)
+	d.keyReady(c, dbs, to, dst)
+	return proto.Int(c, 1)
+}
+
+// SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+func scan(kv store.Keyspace, c *Client, args []string) error {
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return proto.Err(c, "invalid cursor")
+	}
+
+	pattern, count, typ := "*", 10, store.KindNone
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return proto.Err(c, "syntax error")
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return proto.Err(c, "value is not an integer or out of range")
+			}
+			if n < 1 {
+				return proto.Err(c, "syntax error")
+			}
+			count = int(min(n, math.MaxInt32))
+		case "TYPE":
+			var ok bool
+			if typ, ok = store.ParseKind(strings.ToLower(args[i+1])); !ok {
+				return proto.Err(c, fmt.Sprintf("unknown type name '%s'", args[i+1]))
+			}
+		default:
+			return proto.Err(c, "syntax error")
+		}
+		i++
+	}
+
+	keys, next := kv.Scan(cursor, count)
+	keys = appendMatching(nil, keys, pattern)
+	if typ != store.KindNone {
+		// a key deleted since the scan has type none and drops out too
+		keys = slices.DeleteFunc(keys, func(k string) bool { return kv.Type(k) != typ })
+	}
+
+	if err := proto.Array(c, 2); err != nil {
+		return err
+	}
+	if err := proto.Bulk(c, strconv.FormatUint(next, 10)); err != nil {
+		return err
+	}
+	return proto.BulkArray(c, keys)
+}
+
+// appendMatching appends the keys that match a glob pattern to dst. Like
+// redis, a lone "*" takes everything, including the empty key it wouldn't
+// otherwise match.
+func appendMatching(dst, keys []string, pattern string) []string {
+	if pattern == "*" {
+		return append(dst, keys...)
+	}
+	for _, k := range keys {
+		if glob.Match(pattern, k) {
+			dst = append(dst, k)
+		}
+	}
+	return dst
+}
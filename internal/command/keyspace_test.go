@@ -0,0 +1,235 @@
+package command_test
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKEYS(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "MSET", "firstname", "Jack", "lastname", "Stuntman", "age", "35", "", "empty")
+
+	cases := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*name*", []string{"firstname", "lastname"}},
+		{"a??", []string{"age"}},
+		{"*", []string{"", "age", "firstname", "lastname"}},
+		{"nothing", nil},
+	}
+	for _, c := range cases {
+		got := parseBulks(t, mustRun(t, d, "KEYS", c.pattern))
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(c.want, ",") || len(got) != len(c.want) {
+			t.Errorf("KEYS %s: got %q, want %q", c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestSCAN(t *testing.T) {
+	d := newDispatcher()
+	for _, k := range []string{"a1", "a2", "a3", "b1", "b2"} {
+		mustRun(t, d, "SET", k, "v")
+	}
+
+	var got []string
+	cursor := "0"
+	for {
+		reply := mustRun(t, d, "SCAN", cursor, "MATCH", "a*", "COUNT", "2")
+		lines := strings.Split(reply, "\r\n")
+		if lines[0] != "*2" {
+			t.Fatalf("SCAN reply: %q", reply)
+		}
+		cursor = lines[2]
+		got = append(got, parseBulks(t, strings.Join(lines[3:], "\r\n"))...)
+		if cursor == "0" {
+			break
+		}
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != "a1,a2,a3" {
+		t.Fatalf("SCAN MATCH a*: got %q", got)
+	}
+
+	if got := mustRun(t, d, "SCAN", "0", "TYPE", "hash"); got != "*2\r\n$1\r\n0\r\n*0\r\n" {
+		t.Fatalf("SCAN TYPE hash: got %q", got)
+	}
+	if got := mustRun(t, d, "SCAN", "0", "TYPE", "string", "COUNT", "100"); !strings.HasPrefix(got, "*2\r\n$1\r\n0\r\n*5\r\n") {
+		t.Fatalf("SCAN TYPE string: got %q", got)
+	}
+
+	errs := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"x"}, "-ERR invalid cursor\r\n"},
+		{[]string{"-1"}, "-ERR invalid cursor\r\n"},
+		{[]string{"0", "COUNT", "0"}, "-ERR syntax error\r\n"},
+		{[]string{"0", "COUNT", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"0", "MATCH"}, "-ERR syntax error\r\n"},
+		{[]string{"0", "BOGUS", "1"}, "-ERR syntax error\r\n"},
+		{[]string{"0", "TYPE", "nope"}, "-ERR unknown type name 'nope'\r\n"},
+	}
+	for _, e := range errs {
+		if got := mustRun(t, d, "SCAN", e.args...); got != e.want {
+			t.Errorf("SCAN %q: got %q, want %q", e.args, got, e.want)
+		}
+	}
+}
+
+func TestRANDOMKEY_DBSIZE(t *testing.T) {
+	d := newDispatcher()
+
+	if got := mustRun(t, d, "RANDOMKEY"); got != "$-1\r\n" {
+		t.Fatalf("RANDOMKEY on empty db: got %q", got)
+	}
+	if got := mustRun(t, d, "DBSIZE"); got != ":0\r\n" {
+		t.Fatalf("DBSIZE: got %q", got)
+	}
+
+	mustRun(t, d, "MSET", "a", "1", "b", "2")
+	if got := mustRun(t, d, "RANDOMKEY"); got != "$1\r\na\r\n" && got != "$1\r\nb\r\n" {
+		t.Fatalf("RANDOMKEY: got %q", got)
+	}
+	if got := mustRun(t, d, "DBSIZE"); got != ":2\r\n" {
+		t.Fatalf("DBSIZE: got %q", got)
+	}
+}
+
+func TestRENAME_RENAMENX(t *testing.T) {
+	d := newDispatcher()
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"RENAME", "missing", "b"}, "-ERR no such key\r\n"},
+		{[]string{"SET", "a", "1", "EX", "100"}, "+OK\r\n"},
+		{[]string{"SET", "b", "2"}, "+OK\r\n"},
+		{[]string{"RENAMENX", "a", "b"}, ":0\r\n"},
+		{[]string{"RENAME", "a", "b"}, "+OK\r\n"},
+		{[]string{"GET", "b"}, "$1\r\n1\r\n"},
+		{[]string{"TTL", "b"}, ":100\r\n"},
+		{[]string{"EXISTS", "a"}, ":0\r\n"},
+		{[]string{"RENAMENX", "b", "c"}, ":1\r\n"},
+		{[]string{"RENAME", "c", "c"}, "+OK\r\n"},
+		{[]string{"RENAMENX", "c", "c"}, ":0\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestCOPY(t *testing.T) {
+	d := newDispatcher()
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"COPY", "missing", "b"}, ":0\r\n"},
+		{[]string{"SET", "a", "1", "EX", "100"}, "+OK\r\n"},
+		{[]string{"SET", "b", "2"}, "+OK\r\n"},
+		{[]string{"COPY", "a", "b"}, ":0\r\n"},
+		{[]string{"COPY", "a", "b", "REPLACE"}, ":1\r\n"},
+		{[]string{"GET", "b"}, "$1\r\n1\r\n"},
+		{[]string{"TTL", "b"}, ":100\r\n"},
+		{[]string{"COPY", "a", "c", "DB", "0"}, ":1\r\n"},
+		{[]string{"COPY", "a", "a"}, "-ERR source and destination objects are the same\r\n"},
+		{[]string{"COPY", "a", "d", "DB", "16"}, "-ERR DB index is out of range\r\n"},
+		{[]string{"COPY", "a", "d", "DB", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"COPY", "a", "d", "DB"}, "-ERR syntax error\r\n"},
+		{[]string{"COPY", "a", "d", "BOGUS"}, "-ERR syntax error\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestTYPE_TOUCH_OBJECT(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "MSET", "n", "42", "s", "hello", "r", strings.Repeat("x", 100))
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"TYPE", "s"}, "+string\r\n"},
+		{[]string{"TYPE", "missing"}, "+none\r\n"},
+		{[]string{"TOUCH", "n", "s", "missing"}, ":2\r\n"},
+		{[]string{"OBJECT", "ENCODING", "n"}, "$3\r\nint\r\n"},
+		{[]string{"OBJECT", "encoding", "s"}, "$6\r\nembstr\r\n"},
+		{[]string{"OBJECT", "ENCODING", "r"}, "$3\r\nraw\r\n"},
+		{[]string{"OBJECT", "ENCODING", "missing"}, "$-1\r\n"},
+		{[]string{"OBJECT", "IDLETIME", "s"}, ":0\r\n"},
+		{[]string{"OBJECT", "ENCODING"}, "-ERR wrong number of arguments for 'object|encoding' command\r\n"},
+		{[]string{"OBJECT", "NOPE", "s"}, "-ERR unknown subcommand 'NOPE'. Try OBJECT HELP.\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+
+	if got := mustRun(t, d, "OBJECT", "FREQ", "s"); !strings.HasPrefix(got, ":") {
+		t.Fatalf("OBJECT FREQ: got %q", got)
+	}
+}
+
+func TestKeyManagement_JournalAndEvents(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+	n := &fakeNotifier{}
+	d.SetNotifier(n)
+
+	mustRun(t, d, "SET", "a", "1")
+	mustRun(t, d, "SET", "b", "2")
+	mustRun(t, d, "RENAMENX", "a", "b") // not journaled
+	mustRun(t, d, "RENAME", "a", "c")
+	mustRun(t, d, "RENAME", "c", "c") // not journaled
+	mustRun(t, d, "COPY", "c", "b")   // not journaled
+	mustRun(t, d, "COPY", "c", "b", "REPLACE")
+
+	wantJournal := [][]string{
+		{"SET", "a", "1"},
+		{"SET", "b", "2"},
+		{"RENAME", "a", "c"},
+		{"COPY", "c", "b", "REPLACE"},
+	}
+	if !reflect.DeepEqual(j.records, wantJournal) {
+		t.Fatalf("journal: got %q, want %q", j.records, wantJournal)
+	}
+	wantEvents := []string{"$ set a", "$ set b", "g rename_from a", "g rename_to c", "g copy_to b"}
+	if !reflect.DeepEqual(n.events, wantEvents) {
+		t.Fatalf("events:\n got %q\nwant %q", n.events, wantEvents)
+	}
+}
+
+// parseBulks decodes an array reply of bulk strings.
+func parseBulks(t *testing.T, reply string) []string {
+	t.Helper()
+	header, rest, ok := strings.Cut(reply, "\r\n")
+	n, err := strconv.Atoi(strings.TrimPrefix(header, "*"))
+	if !ok || !strings.HasPrefix(header, "*") || err != nil {
+		t.Fatalf("not an array reply: %q", reply)
+	}
+	out := make([]string, 0, n)
+	for range n {
+		header, rest, _ = strings.Cut(rest, "\r\n")
+		size, err := strconv.Atoi(strings.TrimPrefix(header, "$"))
+		if err != nil || len(rest) < size+2 {
+			t.Fatalf("bad bulk in %q", reply)
+		}
+		out, rest = append(out, rest[:size]), rest[size+2:]
+	}
+	return out
+}
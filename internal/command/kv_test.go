@@ -25,8 +25,8 @@ func TestGET_ExistingKey(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GET error: %v", err)
 	}
-	if got != "v\r\n" {
-		t.Fatalf("got %q, want %q", got, "v\r\n")
+	if got != "$1\r\nv\r\n" {
+		t.Fatalf("got %q, want %q", got, "$1\r\nv\r\n")
 	}
 }
 
@@ -36,8 +36,8 @@ func TestGET_MissingKey(t *testing.T) {
 	if err != nil {
 		t.Fatalf("dispatch error: %v", err)
 	}
-	if got != "-ERR key not found\r\n" {
-		t.Fatalf("got %q, want %q", got, "-ERR key not found\r\n")
+	if got != "$-1\r\n" {
+		t.Fatalf("got %q, want %q", got, "$-1\r\n")
 	}
 }
 
@@ -50,23 +50,23 @@ func TestDEL_ExistingKey_RemovesKey(t *testing.T) {
 	if err != nil {
 		t.Fatalf("DEL error: %v", err)
 	}
-	if got != "+OK\r\n" {
-		t.Fatalf("got %q, want %q", got, "+OK\r\n")
+	if got != ":1\r\n" {
+		t.Fatalf("got %q, want %q", got, ":1\r\n")
 	}
-	// follow-up GET should now error
-	if got, _ := run(d, "GET", "k"); got != "-ERR key not found\r\n" {
-		t.Fatalf("after DEL, GET got %q, want %q", got, "-ERR key not found\r\n")
+	// follow-up GET should now return a null bulk
+	if got, _ := run(d, "GET", "k"); got != "$-1\r\n" {
+		t.Fatalf("after DEL, GET got %q, want %q", got, "$-1\r\n")
 	}
 }
 
-func TestDEL_MissingKey_ReturnsErr(t *testing.T) {
+func TestDEL_MissingKey_ReturnsZero(t *testing.T) {
 	d := newDispatcher()
 	got, err := run(d, "DEL", "nope")
 	if err != nil {
 		t.Fatalf("dispatch error: %v", err)
 	}
-	if got != "-ERR key not found\r\n" {
-		t.Fatalf("got %q, want %q", got, "-ERR key not found\r\n")
+	if got != ":0\r\n" {
+		t.Fatalf("got %q, want %q", got, ":0\r\n")
 	}
 }
 
@@ -76,12 +76,10 @@ func TestKV_WrongArity(t *testing.T) {
 		name string
 		args []string
 	}{
-		{"GET", nil},                     // too few
-		{"GET", []string{"a", "b"}},      // too many
-		{"SET", []string{"k"}},           // too few
-		{"SET", []string{"k", "v", "x"}}, // too many
-		{"DEL", nil},                     // too few
-		{"DEL", []string{"a", "b"}},      // too many
+		{"GET", nil},                // too few
+		{"GET", []string{"a", "b"}}, // too many
+		{"SET", []string{"k"}},      // too few
+		{"DEL", nil},                // too few
 	}
 	for _, c := range cases {
 		got, err := run(d, c.name, c.args...)
@@ -93,3 +91,21 @@ func TestKV_WrongArity(t *testing.T) {
 		}
 	}
 }
+
+func TestSETGET_BinarySafe(t *testing.T) {
+	d := newDispatcher()
+	for b := range 256 {
+		k := string([]byte{'k', byte(b), ' '})
+		v := string([]byte{byte(b), '\r', '\n', byte(b)})
+		if got, _ := run(d, "SET", k, v); got != "+OK\r\n" {
+			t.Fatalf("byte %#02x: SET got %q", b, got)
+		}
+	}
+	for b := range 256 {
+		k := string([]byte{'k', byte(b), ' '})
+		v := string([]byte{byte(b), '\r', '\n', byte(b)})
+		if got, want := mustRun(t, d, "GET", k), "$4\r\n"+v+"\r\n"; got != want {
+			t.Fatalf("byte %#02x: GET got %q, want %q", b, got, want)
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package command
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// sideNames are the list ends as LMOVE takes them.
+var sideNames = [...]string{store.Left: "LEFT", store.Right: "RIGHT"}
+
+// sideEvent names an operation on one end of a list, e.g. lpush or rpop.
+func sideEvent(side store.Side, op string) string {
+	if side == store.Left {
+		return "l" + op
+	}
+	return "r" + op
+}
+
+func registerLists(d *Dispatcher, dbs *store.Databases) {
+	d.Register("LPUSH", 2, -1, true, push(d, dbs, store.Left, false))
+	d.Register("RPUSH", 2, -1, true, push(d, dbs, store.Right, false))
+	d.Register("LPUSHX", 2, -1, true, push(d, dbs, store.Left, true))
+	d.Register("RPUSHX", 2, -1, true, push(d, dbs, store.Right, true))
+
+	d.Register("LPOP", 1, 2, true, pop(d, dbs, store.Left))
+	d.Register("RPOP", 1, 2, true, pop(d, dbs, store.Right))
+	d.Register("BLPOP", 2, -1, true, bpop(d, dbs, store.Left))
+	d.Register("BRPOP", 2, -1, true, bpop(d, dbs, store.Right))
+
+	// LMPOP numkeys key [key ...] LEFT | RIGHT [COUNT count]
+	d.Register("LMPOP", 3, -1, true, func(c *Client, args []string) error {
+		keys, side, count, msg := parseMPop(args)
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+		if ok, err := mpop(d, dbs.DB(c.DB), c, keys, side, count); ok || err != nil {
+			return err
+		}
+		c.SkipPropagate()
+		return proto.NullArray(c)
+	})
+
+	// BLMPOP timeout numkeys key [key ...] LEFT | RIGHT [COUNT count]
+	d.Register("BLMPOP", 4, -1, true, func(c *Client, args []string) error {
+		timeout, msg := parseTimeout(args[0])
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+		keys, side, count, msg := parseMPop(args[1:])
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+		if ok, err := mpop(d, dbs.DB(c.DB), c, keys, side, count); ok || err != nil {
+			return err
+		}
+		return d.block(c, dbs.DB(c.DB), keys, timeout, "BLMPOP", args, proto.NullArray)
+	})
+
+	d.Register("LMOVE", 4, 4, true, func(c *Client, args []string) error {
+		from, ok1 := parseSide(args[2])
+		to, ok2 := parseSide(args[3])
+		if !ok1 || !ok2 {
+			return proto.Err(c, "syntax error")
+		}
+		if ok, err := move(d, dbs.DB(c.DB), c, args[0], args[1], from, to); ok || err != nil {
+			return err
+		}
+		c.SkipPropagate()
+		return proto.NullBulk(c)
+	})
+
+	// RPOPLPUSH source destination: LMOVE source destination RIGHT LEFT
+	d.Register("RPOPLPUSH", 2, 2, true, func(c *Client, args []string) error {
+		if ok, err := move(d, dbs.DB(c.DB), c, args[0], args[1], store.Right, store.Left); ok || err != nil {
+			return err
+		}
+		c.SkipPropagate()
+		return proto.NullBulk(c)
+	})
+
+	// BLMOVE source destination LEFT | RIGHT LEFT | RIGHT timeout
+	d.Register("BLMOVE", 5, 5, true, func(c *Client, args []string) error {
+		from, ok1 := parseSide(args[2])
+		to, ok2 := parseSide(args[3])
+		if !ok1 || !ok2 {
+			return proto.Err(c, "syntax error")
+		}
+		return bmove(d, dbs, c, "BLMOVE", args, from, to, args[4])
+	})
+
+	// BRPOPLPUSH source destination timeout
+	d.Register("BRPOPLPUSH", 3, 3, true, func(c *Client, args []string) error {
+		return bmove(d, dbs, c, "BRPOPLPUSH", args, store.Right, store.Left, args[2])
+	})
+
+	d.Register("LLEN", 1, 1, false, func(c *Client, args []string) error {
+		n, err := dbs.DB(c.DB).LLen(args[0])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		return proto.Int(c, int64(n))
+	})
+
+	d.Register("LRANGE", 3, 3, false, func(c *Client, args []string) error {
+		start, ok1 := parseIndex(args[1])
+		stop, ok2 := parseIndex(args[2])
+		if !ok1 || !ok2 {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		vals, err := dbs.DB(c.DB).LRange(args[0], start, stop)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		return proto.BulkArray(c, vals)
+	})
+
+	d.Register("LINDEX", 2, 2, false, func(c *Client, args []string) error {
+		i, ok := parseIndex(args[1])
+		if !ok {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		v, ok, err := dbs.DB(c.DB).LIndex(args[0], i)
+		switch {
+		case err != nil:
+			return storeErr(c, err)
+		case !ok:
+			return proto.NullBulk(c)
+		}
+		return proto.Bulk(c, v)
+	})
+
+	d.Register("LSET", 3, 3, true, func(c *Client, args []string) error {
+		i, ok := parseIndex(args[1])
+		if !ok {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		if err := dbs.DB(c.DB).LSet(args[0], i, args[2]); err != nil {
+			return storeErr(c, err)
+		}
+		d.notify(c.DB, notify.List, "lset", args[0])
+		return proto.OK(c)
+	})
+
+	// LREM key count element
+	d.Register("LREM", 3, 3, true, func(c *Client, args []string) error {
+		count, ok := parseIndex(args[1])
+		if !ok {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		n, emptied, err := dbs.DB(c.DB).LRem(args[0], count, args[2])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if n == 0 {
+			c.SkipPropagate()
+		} else {
+			d.notify(c.DB, notify.List, "lrem", args[0])
+			if emptied {
+				d.notify(c.DB, notify.Generic, "del", args[0])
+			}
+		}
+		return proto.Int(c, int64(n))
+	})
+
+	d.Register("LTRIM", 3, 3, true, func(c *Client, args []string) error {
+		start, ok1 := parseIndex(args[1])
+		stop, ok2 := parseIndex(args[2])
+		if !ok1 || !ok2 {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		existed, emptied, err := dbs.DB(c.DB).LTrim(args[0], start, stop)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if !existed {
+			c.SkipPropagate()
+			return proto.OK(c)
+		}
+		d.notify(c.DB, notify.List, "ltrim", args[0])
+		if emptied {
+			d.notify(c.DB, notify.Generic, "del", args[0])
+		}
+		return proto.OK(c)
+	})
+
+	// LINSERT key BEFORE | AFTER pivot element
+	d.Register("LINSERT", 4, 4, true, func(c *Client, args []string) error {
+		var before bool
+		switch strings.ToUpper(args[1]) {
+		case "BEFORE":
+			before = true
+		case "AFTER":
+		default:
+			return proto.Err(c, "syntax error")
+		}
+		n, err := dbs.DB(c.DB).LInsert(args[0], before, args[2], args[3])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if n > 0 {
+			d.notify(c.DB, notify.List, "linsert", args[0])
+		} else {
+			c.SkipPropagate()
+		}
+		return proto.Int(c, int64(n))
+	})
+
+	d.Register("LPOS", 2, -1, false, func(c *Client, args []string) error {
+		return lpos(dbs.DB(c.DB), c, args)
+	})
+}
+
+// LPUSH key element [element ...], and RPUSH and the X variants that only
+// push onto an existing list
+func push(d *Dispatcher, dbs *store.Databases, side store.Side, existing bool) Handler {
+	event := sideEvent(side, "push")
+	return func(c *Client, args []string) error {
+		n, err := dbs.DB(c.DB).Push(args[0], side, existing, args[1:]...)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if n == 0 {
+			c.SkipPropagate()
+		} else {
+			d.notify(c.DB, notify.List, event, args[0])
+			d.signal(c, c.DB, args[0])
+		}
+		return proto.Int(c, int64(n))
+	}
+}
+
+// LPOP key [count], and RPOP
+func pop(d *Dispatcher, dbs *store.Databases, side store.Side) Handler {
+	return func(c *Client, args []string) error {
+		count := 1
+		if len(args) > 1 {
+			n, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil || n < 0 {
+				return proto.Err(c, "value is out of range, must be positive")
+			}
+			count = int(min(n, math.MaxInt32))
+		}
+
+		vals, emptied, err := dbs.DB(c.DB).Pop(args[0], side, count)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if len(vals) == 0 {
+			c.SkipPropagate()
+		} else {
+			popped(d, c, args[0], side, len(vals), emptied)
+		}
+
+		switch {
+		case len(args) > 1 && vals == nil:
+			return proto.NullArray(c)
+		case len(args) > 1:
+			return proto.BulkArray(c, vals)
+		case vals == nil:
+			return proto.NullBulk(c)
+		}
+		return proto.Bulk(c, vals[0])
+	}
+}
+
+// popped journals and notifies n elements popped from side of the list
+// at k, as an LPOP or RPOP with a count so it replays the same whichever
+// command did it.
+func popped(d *Dispatcher, c *Client, k string, side store.Side, n int, emptied bool) {
+	event := sideEvent(side, "pop")
+	c.Propagate(strings.ToUpper(event), k, strconv.Itoa(n))
+	d.notify(c.DB, notify.List, event, k)
+	if emptied {
+		d.notify(c.DB, notify.Generic, "del", k)
+	}
+}
+
+// BLPOP key [key ...] timeout, and BRPOP
+func bpop(d *Dispatcher, dbs *store.Databases, side store.Side) Handler {
+	name := "B" + strings.ToUpper(sideEvent(side, "pop"))
+	return func(c *Client, args []string) error {
+		timeout, msg := parseTimeout(args[len(args)-1])
+		if msg != "" {
+			return proto.Err(c, msg)
+		}
+		keys := args[:len(args)-1]
+		kv := dbs.DB(c.DB)
+		k, vals, emptied, err := kv.MPop(keys, side, 1)
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if vals == nil {
+			return d.block(c, kv, keys, timeout, name, args, proto.NullArray)
+		}
+		popped(d, c, k, side, 1, emptied)
+		return proto.BulkArray(c, []string{k, vals[0]})
+	}
+}
+
+// mpop pops for LMPOP and BLMPOP, replying with the key and its elements.
+// It reports false, leaving the reply to the caller, if none of keys held
+// a list.
+func mpop(d *Dispatcher, kv store.Lists, c *Client, keys []string, side store.Side, count int) (bool, error) {
+	k, vals, emptied, err := kv.MPop(keys, side, count)
+	if err != nil {
+		return true, storeErr(c, err)
+	}
+	if vals == nil {
+		return false, nil
+	}
+	popped(d, c, k, side, len(vals), emptied)
+	if err := proto.Array(c, 2); err != nil {
+		return true, err
+	}
+	if err := proto.Bulk(c, k); err != nil {
+		return true, err
+	}
+	return true, proto.BulkArray(c, vals)
+}
+
+// move moves an element for LMOVE and the commands like it, replying with
+// the element. It reports false, leaving the reply to the caller, if src
+// doesn't exist.
+func move(d *Dispatcher, kv store.Lists, c *Client, src, dst string, from, to store.Side) (bool, error) {
+	v, ok, emptied, err := kv.Move(src, dst, from, to)
+	if err != nil {
+		return true, storeErr(c, err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	c.Propagate("LMOVE", src, dst, sideNames[from], sideNames[to])
+	d.notify(c.DB, notify.List, sideEvent(from, "pop"), src)
+	d.notify(c.DB, notify.List, sideEvent(to, "push"), dst)
+	if emptied {
+		d.notify(c.DB, notify.Generic, "del", src)
+	}
+	d.signal(c, c.DB, dst)
+	return true, proto.Bulk(c, v)
+}
+
+// bmove is BLMOVE and BRPOPLPUSH: a move that blocks while src is missing.
+func bmove(d *Dispatcher, dbs *store.Databases, c *Client, name string, args []string, from, to store.Side, timeout string) error {
+	t, msg := parseTimeout(timeout)
+	if msg != "" {
+		return proto.Err(c, msg)
+	}
+	kv := dbs.DB(c.DB)
+	if ok, err := move(d, kv, c, args[0], args[1], from, to); ok || err != nil {
+		return err
+	}
+	return d.block(c, kv, args[:1], t, name, args, proto.NullBulk)
+}
+
+// LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+func lpos(kv store.Lists, c *Client, args []string) error {
+	rank, count, maxlen := 1, 0, 0
+	hasCount := false
+	for i := 2; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return proto.Err(c, "syntax error")
+		}
+		n, err := strconv.ParseInt(args[i+1], 10, 64)
+		if err != nil {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		switch strings.ToUpper(args[i]) {
+		case "RANK":
+			if n == 0 || n == math.MinInt64 {
+				return proto.Err(c, "RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+			}
+			rank = int(max(min(n, math.MaxInt32), math.MinInt32))
+		case "COUNT":
+			if n < 0 {
+				return proto.Err(c, "COUNT can't be negative")
+			}
+			count, hasCount = int(min(n, math.MaxInt32)), true
+		case "MAXLEN":
+			if n < 0 {
+				return proto.Err(c, "MAXLEN can't be negative")
+			}
+			maxlen = int(min(n, math.MaxInt32))
+		default:
+			return proto.Err(c, "syntax error")
+		}
+	}
+	if !hasCount {
+		count = 1
+	}
+
+	found, err := kv.LPos(args[0], args[1], rank, count, maxlen)
+	if err != nil {
+		return storeErr(c, err)
+	}
+	if !hasCount {
+		if len(found) == 0 {
+			return proto.NullBulk(c)
+		}
+		return proto.Int(c, int64(found[0]))
+	}
+	if err := proto.Array(c, len(found)); err != nil {
+		return err
+	}
+	for _, i := range found {
+		if err := proto.Int(c, int64(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseMPop parses the arguments LMPOP and BLMPOP share after any timeout:
+// numkeys key [key ...] LEFT | RIGHT [COUNT count]. It returns the error
+// to reply with, if any.
+func parseMPop(args []string) ([]string, store.Side, int, string) {
+	n, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || n <= 0 {
+		return nil, 0, 0, "numkeys should be greater than 0"
+	}
+	if n > int64(len(args)-2) {
+		return nil, 0, 0, "syntax error"
+	}
+	keys, rest := args[1:1+n], args[1+n:]
+
+	side, ok := parseSide(rest[0])
+	if !ok {
+		return nil, 0, 0, "syntax error"
+	}
+	count := 1
+	switch {
+	case len(rest) == 1:
+	case len(rest) == 3 && strings.EqualFold(rest[1], "COUNT"):
+		c, err := strconv.ParseInt(rest[2], 10, 64)
+		if err != nil || c <= 0 {
+			return nil, 0, 0, "count should be greater than 0"
+		}
+		count = int(min(c, math.MaxInt32))
+	default:
+		return nil, 0, 0, "syntax error"
+	}
+	return keys, side, count, ""
+}
+
+// parseTimeout parses a blocking command's timeout, in seconds with an
+// optional fraction. Like redis it only counts whole milliseconds, and 0
+// waits forever.
+func parseTimeout(s string) (time.Duration, string) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, "timeout is not a float or out of range"
+	}
+	if f < 0 {
+		return 0, "timeout is negative"
+	}
+	ms := f * 1000
+	if ms >= math.MaxInt64/float64(time.Millisecond) {
+		return 0, "timeout is out of range"
+	}
+	return time.Duration(ms) * time.Millisecond, ""
+}
+
+func parseSide(s string) (store.Side, bool) {
+	switch strings.ToUpper(s) {
+	case "LEFT":
+		return store.Left, true
+	case "RIGHT":
+		return store.Right, true
+	}
+	return 0, false
+}
+
+// parseIndex parses a list index or count, clamped to what an int holds
+// everywhere; no list gets anywhere near that long.
+func parseIndex(s string) (int, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int(max(min(n, math.MaxInt32), math.MinInt32)), true
+}
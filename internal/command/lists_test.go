@@ -0,0 +1,179 @@
+package command_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListCommands(t *testing.T) {
+	d := newDispatcher()
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"LPUSHX", "k", "a"}, ":0\r\n"},
+		{[]string{"RPUSH", "k", "b", "c"}, ":2\r\n"},
+		{[]string{"LPUSH", "k", "a", "z"}, ":4\r\n"},
+		{[]string{"RPUSHX", "k", "d"}, ":5\r\n"},
+		{[]string{"LRANGE", "k", "0", "-1"}, "*5\r\n$1\r\nz\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\nd\r\n"},
+		{[]string{"LRANGE", "k", "-2", "100"}, "*2\r\n$1\r\nc\r\n$1\r\nd\r\n"},
+		{[]string{"LRANGE", "missing", "0", "-1"}, "*0\r\n"},
+		{[]string{"LLEN", "k"}, ":5\r\n"},
+		{[]string{"LINDEX", "k", "-1"}, "$1\r\nd\r\n"},
+		{[]string{"LINDEX", "k", "5"}, "$-1\r\n"},
+		{[]string{"LSET", "k", "0", "y"}, "+OK\r\n"},
+		{[]string{"LSET", "k", "9", "y"}, "-ERR index out of range\r\n"},
+		{[]string{"LSET", "missing", "0", "y"}, "-ERR no such key\r\n"},
+		{[]string{"LPOP", "k"}, "$1\r\ny\r\n"},
+		{[]string{"RPOP", "k", "2"}, "*2\r\n$1\r\nd\r\n$1\r\nc\r\n"},
+		{[]string{"LPOP", "k", "0"}, "*0\r\n"},
+		{[]string{"LPOP", "k", "-1"}, "-ERR value is out of range, must be positive\r\n"},
+		{[]string{"LPOP", "missing"}, "$-1\r\n"},
+		{[]string{"LPOP", "missing", "2"}, "*-1\r\n"},
+		{[]string{"LINSERT", "k", "BEFORE", "b", "x"}, ":3\r\n"},
+		{[]string{"LINSERT", "k", "after", "nope", "x"}, ":-1\r\n"},
+		{[]string{"LINSERT", "missing", "AFTER", "b", "x"}, ":0\r\n"},
+		{[]string{"LINSERT", "k", "AROUND", "b", "x"}, "-ERR syntax error\r\n"},
+		{[]string{"LRANGE", "k", "0", "-1"}, "*3\r\n$1\r\na\r\n$1\r\nx\r\n$1\r\nb\r\n"},
+		{[]string{"LREM", "k", "0", "x"}, ":1\r\n"},
+		{[]string{"LTRIM", "k", "1", "-1"}, "+OK\r\n"},
+		{[]string{"LRANGE", "k", "0", "-1"}, "*1\r\n$1\r\nb\r\n"},
+		{[]string{"LTRIM", "k", "1", "0"}, "+OK\r\n"},
+		{[]string{"EXISTS", "k"}, ":0\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestLPOS(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "RPUSH", "k", "a", "b", "c", "1", "2", "3", "c", "c")
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"c"}, ":2\r\n"},
+		{[]string{"c", "RANK", "2"}, ":6\r\n"},
+		{[]string{"c", "RANK", "-1"}, ":7\r\n"},
+		{[]string{"c", "COUNT", "2"}, "*2\r\n:2\r\n:6\r\n"},
+		{[]string{"c", "COUNT", "0"}, "*3\r\n:2\r\n:6\r\n:7\r\n"},
+		{[]string{"c", "RANK", "-1", "COUNT", "2"}, "*2\r\n:7\r\n:6\r\n"},
+		{[]string{"c", "COUNT", "0", "MAXLEN", "3"}, "*1\r\n:2\r\n"},
+		{[]string{"nope"}, "$-1\r\n"},
+		{[]string{"nope", "COUNT", "1"}, "*0\r\n"},
+		{[]string{"c", "RANK", "0"}, "-ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list\r\n"},
+		{[]string{"c", "COUNT", "-1"}, "-ERR COUNT can't be negative\r\n"},
+		{[]string{"c", "MAXLEN", "-1"}, "-ERR MAXLEN can't be negative\r\n"},
+		{[]string{"c", "COUNT"}, "-ERR syntax error\r\n"},
+		{[]string{"c", "FOO", "1"}, "-ERR syntax error\r\n"},
+	}
+	for _, c := range cases {
+		if got := mustRun(t, d, "LPOS", append([]string{"k"}, c.args...)...); got != c.want {
+			t.Errorf("LPOS k %q: got %q, want %q", c.args, got, c.want)
+		}
+	}
+}
+
+func TestLMOVE_LMPOP(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+	n := &fakeNotifier{}
+	d.SetNotifier(n)
+
+	mustRun(t, d, "RPUSH", "a", "1", "2", "3")
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"LMOVE", "a", "b", "LEFT", "RIGHT"}, "$1\r\n1\r\n"},
+		{[]string{"RPOPLPUSH", "a", "b"}, "$1\r\n3\r\n"},
+		{[]string{"LMOVE", "a", "b", "UP", "LEFT"}, "-ERR syntax error\r\n"},
+		{[]string{"LMOVE", "missing", "b", "LEFT", "LEFT"}, "$-1\r\n"},
+		{[]string{"LRANGE", "b", "0", "-1"}, "*2\r\n$1\r\n3\r\n$1\r\n1\r\n"},
+		{[]string{"LMPOP", "2", "missing", "a", "RIGHT", "COUNT", "5"}, "*2\r\n$1\r\na\r\n*1\r\n$1\r\n2\r\n"},
+		{[]string{"LMPOP", "1", "a", "LEFT"}, "*-1\r\n"},
+		{[]string{"LMPOP", "0", "a", "LEFT"}, "-ERR numkeys should be greater than 0\r\n"},
+		{[]string{"LMPOP", "3", "a", "LEFT"}, "-ERR syntax error\r\n"},
+		{[]string{"LMPOP", "1", "b", "LEFT", "COUNT", "0"}, "-ERR count should be greater than 0\r\n"},
+		{[]string{"LMPOP", "1", "b", "MIDDLE"}, "-ERR syntax error\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+
+	wantRecords := [][]string{
+		{"RPUSH", "a", "1", "2", "3"},
+		{"LMOVE", "a", "b", "LEFT", "RIGHT"},
+		{"LMOVE", "a", "b", "RIGHT", "LEFT"},
+		{"RPOP", "a", "1"},
+	}
+	if !reflect.DeepEqual(j.records, wantRecords) {
+		t.Fatalf("journal:\n got %q\nwant %q", j.records, wantRecords)
+	}
+	wantEvents := []string{
+		"l rpush a",
+		"l lpop a",
+		"l rpush b",
+		"l rpop a",
+		"l lpush b",
+		"l rpop a",
+		"g del a",
+	}
+	if !reflect.DeepEqual(n.events, wantEvents) {
+		t.Fatalf("events:\n got %q\nwant %q", n.events, wantEvents)
+	}
+}
+
+func TestLists_WrongType(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "SET", "s", "v")
+	mustRun(t, d, "RPUSH", "l", "a")
+
+	const wrongType = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	for _, cmd := range [][]string{
+		{"LPUSH", "s", "a"},
+		{"LPOP", "s"},
+		{"LRANGE", "s", "0", "-1"},
+		{"LMOVE", "l", "s", "LEFT", "LEFT"},
+		{"GET", "l"},
+		{"GETEX", "l", "PERSIST"},
+		{"SET", "l", "v", "GET"},
+		{"GETSET", "l", "v"},
+		{"GETDEL", "l"},
+		{"APPEND", "l", "v"},
+		{"INCR", "l"},
+		{"INCRBYFLOAT", "l", "1"},
+		{"STRLEN", "l"},
+		{"GETRANGE", "l", "0", "-1"},
+		{"SETRANGE", "l", "0", "v"},
+	} {
+		if got := mustRun(t, d, cmd[0], cmd[1:]...); got != wrongType {
+			t.Errorf("%q: got %q", cmd, got)
+		}
+	}
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"TYPE", "l"}, "+list\r\n"},
+		{[]string{"OBJECT", "ENCODING", "l"}, "$8\r\nlistpack\r\n"},
+		{[]string{"MGET", "s", "l"}, "*2\r\n$1\r\nv\r\n$-1\r\n"},
+		{[]string{"SCAN", "0", "TYPE", "list"}, "*2\r\n$1\r\n0\r\n*1\r\n$1\r\nl\r\n"},
+		{[]string{"SET", "l", "v"}, "+OK\r\n"},
+		{[]string{"TYPE", "l"}, "+string\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
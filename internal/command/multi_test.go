@@ -0,0 +1,73 @@
+package command_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMGET_MSET(t *testing.T) {
+	d := newDispatcher()
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"MSET", "a", "1", "b", "2"}, "+OK\r\n"},
+		{[]string{"MGET", "a", "missing", "b"}, "*3\r\n$1\r\n1\r\n$-1\r\n$1\r\n2\r\n"},
+		{[]string{"MSET", "a", "1", "b"}, "-ERR wrong number of arguments for 'mset' command\r\n"},
+		{[]string{"MSETNX", "b", "3", "c", "4"}, ":0\r\n"},
+		{[]string{"GET", "c"}, "$-1\r\n"},
+		{[]string{"MSETNX", "c", "4", "d", "5"}, ":1\r\n"},
+		{[]string{"MGET", "c", "d"}, "*2\r\n$1\r\n4\r\n$1\r\n5\r\n"},
+		{[]string{"MSETNX", "e"}, "-ERR wrong number of arguments for 'msetnx' command\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestDEL_EXISTS_UNLINK(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "MSET", "a", "1", "b", "2", "c", "3")
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"EXISTS", "a", "a", "missing", "b"}, ":3\r\n"},
+		{[]string{"DEL", "a", "a", "missing"}, ":1\r\n"},
+		{[]string{"UNLINK", "b", "c"}, ":2\r\n"},
+		{[]string{"EXISTS", "a", "b", "c"}, ":0\r\n"},
+		{[]string{"DEL", "a"}, ":0\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestMulti_Journal(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	mustRun(t, d, "MSET", "a", "1", "b", "2")
+	mustRun(t, d, "MSETNX", "a", "3")
+	mustRun(t, d, "MSETNX", "c", "3")
+	mustRun(t, d, "DEL", "a", "missing")
+	mustRun(t, d, "DEL", "missing")
+	mustRun(t, d, "UNLINK", "b")
+
+	want := [][]string{
+		{"MSET", "a", "1", "b", "2"},
+		{"MSETNX", "c", "3"},
+		{"DEL", "a", "missing"},
+		{"UNLINK", "b"},
+	}
+	if !reflect.DeepEqual(j.records, want) {
+		t.Fatalf("journal: got %q, want %q", j.records, want)
+	}
+}
@@ -0,0 +1,52 @@
+package command_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/notify"
+)
+
+type fakeNotifier struct{ events []string }
+
+// events are recorded as "class event key", with " @db" appended outside
+// database 0
+func (n *fakeNotifier) Notify(class notify.Class, event, key string, db int) {
+	ev := class.String() + " " + event + " " + key
+	if db != 0 {
+		ev += " @" + strconv.Itoa(db)
+	}
+	n.events = append(n.events, ev)
+}
+
+func TestNotify_KeyspaceEvents(t *testing.T) {
+	d := newDispatcher()
+	n := &fakeNotifier{}
+	d.SetNotifier(n)
+
+	future := strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10)
+	mustRun(t, d, "SET", "k", "v")
+	mustRun(t, d, "GET", "k")
+	mustRun(t, d, "SETEX", "t", "10", "v")
+	mustRun(t, d, "PERSIST", "t")
+	mustRun(t, d, "PERSIST", "t") // no expiry left, no event
+	mustRun(t, d, "PEXPIREAT", "t", future)
+	mustRun(t, d, "PEXPIREAT", "t", "1") // in the past: deletes
+	mustRun(t, d, "DEL", "k")
+	mustRun(t, d, "DEL", "k") // already gone, no event
+
+	want := []string{
+		"$ set k",
+		"$ set t",
+		"g expire t",
+		"g persist t",
+		"g expire t",
+		"g del t",
+		"g del k",
+	}
+	if !reflect.DeepEqual(n.events, want) {
+		t.Fatalf("events:\n got %q\nwant %q", n.events, want)
+	}
+}
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"strings"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// Rewriter compacts the append-only file in the background.
+type Rewriter interface {
+	Rewrite() error
+}
+
+// Saver writes point-in-time snapshots of the dataset.
+type Saver interface {
+	Save() error
+	BGSave() error
+	LastSave() time.Time
+}
+
+// Dataset is what a save copies: the live keys of every database.
+type Dataset interface {
+	Snapshot() map[int][]store.Entry
+}
+
+// Settled returns src with each Snapshot taken under Exclusive. The store
+// copies in batches without stopping writers, so that's what makes a save
+// a point in time: a RENAME or MSET can't land halfway through the copy.
+func (d *Dispatcher) Settled(src Dataset) Dataset {
+	return settled{d, src}
+}
+
+type settled struct {
+	d   *Dispatcher
+	src Dataset
+}
+
+func (s settled) Snapshot() (dbs map[int][]store.Entry) {
+	s.d.Exclusive(func() { dbs = s.src.Snapshot() })
+	return dbs
+}
+
+// RegisterPersistence wires the persistence admin commands. rw is nil when
+// the append-only file is disabled.
+func RegisterPersistence(d *Dispatcher, rw Rewriter, sv Saver) {
+	d.Register("BGREWRITEAOF", 0, 0, false, func(c *Client, _ []string) error {
+		if rw == nil {
+			return proto.Err(c, "append only file is disabled")
+		}
+		var err error
+		d.Exclusive(func() { err = rw.Rewrite() })
+		if err != nil {
+			return proto.Err(c, err.Error())
+		}
+		return proto.Simple(c, "Background append only file rewriting started")
+	})
+
+	d.Register("SAVE", 0, 0, false, func(c *Client, _ []string) error {
+		if err := sv.Save(); err != nil {
+			return proto.Err(c, err.Error())
+		}
+		return proto.OK(c)
+	})
+
+	d.Register("BGSAVE", 0, 1, false, func(c *Client, args []string) error {
+		// SCHEDULE is accepted for compatibility; saves never overlap
+		// rewrites here, so there's nothing to wait for.
+		if len(args) == 1 && !strings.EqualFold(args[0], "SCHEDULE") {
+			return proto.Err(c, "syntax error")
+		}
+		if err := sv.BGSave(); err != nil {
+			return proto.Err(c, err.Error())
+		}
+		return proto.Simple(c, "Background saving started")
+	})
+
+	d.Register("LASTSAVE", 0, 0, false, func(c *Client, _ []string) error {
+		return proto.Int(c, sv.LastSave().Unix())
+	})
+}
@@ -0,0 +1,137 @@
+package command_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/command"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+type fakeRewriter struct {
+	calls int
+	err   error
+}
+
+func (r *fakeRewriter) Rewrite() error {
+	r.calls++
+	return r.err
+}
+
+type fakeSaver struct {
+	saves, bgsaves int
+	err            error
+	last           time.Time
+}
+
+func (s *fakeSaver) Save() error         { s.saves++; return s.err }
+func (s *fakeSaver) BGSave() error       { s.bgsaves++; return s.err }
+func (s *fakeSaver) LastSave() time.Time { return s.last }
+
+func TestBGREWRITEAOF_StartsRewrite(t *testing.T) {
+	d := newDispatcher()
+	rw := &fakeRewriter{}
+	command.RegisterPersistence(d, rw, &fakeSaver{})
+
+	if got, want := mustRun(t, d, "BGREWRITEAOF"), "+Background append only file rewriting started\r\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if rw.calls != 1 {
+		t.Fatalf("Rewrite called %d times, want 1", rw.calls)
+	}
+
+	rw.err = errors.New("background append only file rewriting already in progress")
+	if got, want := mustRun(t, d, "BGREWRITEAOF"), "-ERR background append only file rewriting already in progress\r\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestBGREWRITEAOF_Disabled(t *testing.T) {
+	d := newDispatcher()
+	command.RegisterPersistence(d, nil, &fakeSaver{})
+
+	if got, want := mustRun(t, d, "BGREWRITEAOF"), "-ERR append only file is disabled\r\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSAVE_BGSAVE_LASTSAVE(t *testing.T) {
+	d := newDispatcher()
+	sv := &fakeSaver{last: time.Unix(1_700_000_000, 0)}
+	command.RegisterPersistence(d, nil, sv)
+
+	if got := mustRun(t, d, "SAVE"); got != "+OK\r\n" {
+		t.Fatalf("SAVE: got %q", got)
+	}
+	if got, want := mustRun(t, d, "BGSAVE"), "+Background saving started\r\n"; got != want {
+		t.Fatalf("BGSAVE: got %q, want %q", got, want)
+	}
+	if got, want := mustRun(t, d, "BGSAVE", "schedule"), "+Background saving started\r\n"; got != want {
+		t.Fatalf("BGSAVE SCHEDULE: got %q, want %q", got, want)
+	}
+	if got, want := mustRun(t, d, "BGSAVE", "bogus"), "-ERR syntax error\r\n"; got != want {
+		t.Fatalf("BGSAVE bogus: got %q, want %q", got, want)
+	}
+	if sv.saves != 1 || sv.bgsaves != 2 {
+		t.Fatalf("saves=%d bgsaves=%d, want 1 and 2", sv.saves, sv.bgsaves)
+	}
+	if got, want := mustRun(t, d, "LASTSAVE"), ":1700000000\r\n"; got != want {
+		t.Fatalf("LASTSAVE: got %q, want %q", got, want)
+	}
+
+	sv.err = errors.New("background save already in progress")
+	if got, want := mustRun(t, d, "BGSAVE"), "-ERR background save already in progress\r\n"; got != want {
+		t.Fatalf("BGSAVE in progress: got %q, want %q", got, want)
+	}
+}
+
+func TestSettled_RenameDuringSnapshot(t *testing.T) {
+	d := command.NewDispatcher()
+	dbs := store.NewDatabases()
+	command.RegisterKV(d, dbs)
+	const n = 5000 // several of the store's batches
+	for i := range n {
+		mustRun(t, d, "SET", fmt.Sprint("k", i), "v")
+	}
+
+	// renames keys back and forth, from the end, so some are renamed
+	// ahead of the copy to names past its end
+	started := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		from, to := "k", "r"
+		for round := 0; ; round++ {
+			for i := n - 1; i >= 0; i-- {
+				select {
+				case <-stop:
+					return
+				default:
+					_, _ = run(d, "RENAME", fmt.Sprint(from, i), fmt.Sprint(to, i))
+				}
+			}
+			if round == 0 {
+				close(started)
+			}
+			from, to = to, from
+		}
+	}()
+	<-started
+	snap := d.Settled(dbs).Snapshot()
+	close(stop)
+	<-done
+
+	seen := make(map[string]int)
+	for _, e := range snap[0] {
+		seen[strings.TrimLeft(e.Key, "kr")]++
+	}
+	for i := range n {
+		if got := seen[fmt.Sprint(i)]; got != 1 {
+			t.Fatalf("key %d copied %d times, want 1", i, got)
+		}
+	}
+}
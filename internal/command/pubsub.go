@@ -0,0 +1,121 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/pubsub"
+)
+
+func RegisterPubSub(d *Dispatcher, hub *pubsub.Hub) {
+	d.Register("SUBSCRIBE", 1, -1, false, func(c *Client, args []string) error {
+		sub := c.subscriber(hub)
+		return subscribe(c, "subscribe", args, sub.Subscribe)
+	})
+	d.Register("PSUBSCRIBE", 1, -1, false, func(c *Client, args []string) error {
+		sub := c.subscriber(hub)
+		return subscribe(c, "psubscribe", args, sub.PSubscribe)
+	})
+
+	// UNSUBSCRIBE [channel ...]; with no channels it leaves all of them
+	d.Register("UNSUBSCRIBE", 0, -1, false, func(c *Client, args []string) error {
+		if len(args) == 0 && c.Sub != nil {
+			args = c.Sub.Channels()
+		}
+		return unsubscribe(c, "unsubscribe", args, func(ch string) int {
+			return c.subscriber(hub).Unsubscribe(ch)
+		})
+	})
+	d.Register("PUNSUBSCRIBE", 0, -1, false, func(c *Client, args []string) error {
+		if len(args) == 0 && c.Sub != nil {
+			args = c.Sub.Patterns()
+		}
+		return unsubscribe(c, "punsubscribe", args, func(pat string) int {
+			return c.subscriber(hub).PUnsubscribe(pat)
+		})
+	})
+
+	d.Register("PUBLISH", 2, 2, false, func(c *Client, args []string) error {
+		return proto.Int(c, int64(hub.Publish(args[0], args[1])))
+	})
+
+	// PUBSUB CHANNELS [pattern] | NUMSUB [channel ...] | NUMPAT
+	d.Register("PUBSUB", 1, -1, false, func(c *Client, args []string) error {
+		switch strings.ToUpper(args[0]) {
+		case "CHANNELS":
+			if len(args) > 2 {
+				return proto.Err(c, "wrong number of arguments for 'pubsub|channels' command")
+			}
+			var pattern string
+			if len(args) == 2 {
+				pattern = args[1]
+			}
+			return proto.BulkArray(c, hub.Channels(pattern))
+		case "NUMSUB":
+			if err := proto.Array(c, 2*(len(args)-1)); err != nil {
+				return err
+			}
+			for _, ch := range args[1:] {
+				_ = proto.Bulk(c, ch)
+				if err := proto.Int(c, int64(hub.NumSub(ch))); err != nil {
+					return err
+				}
+			}
+			return nil
+		case "NUMPAT":
+			if len(args) != 1 {
+				return proto.Err(c, "wrong number of arguments for 'pubsub|numpat' command")
+			}
+			return proto.Int(c, int64(hub.NumPat()))
+		default:
+			return proto.Err(c, fmt.Sprintf("unknown subcommand '%s'. Try PUBSUB HELP.", args[0]))
+		}
+	})
+}
+
+func (c *Client) subscriber(hub *pubsub.Hub) *pubsub.Subscriber {
+	if c.Sub == nil {
+		c.Sub = hub.NewSubscriber()
+	}
+	return c.Sub
+}
+
+func subscribe(c *Client, kind string, names []string, add func(string) int) error {
+	for _, name := range names {
+		if err := subscription(c, kind, name, add(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unsubscribe confirms each removal; with nothing to leave it still sends
+// one confirmation, with a null channel.
+func unsubscribe(c *Client, kind string, names []string, remove func(string) int) error {
+	if len(names) == 0 {
+		if err := proto.Push(c, 3); err != nil {
+			return err
+		}
+		_ = proto.Bulk(c, kind)
+		_ = proto.Null(c)
+		return proto.Int(c, 0)
+	}
+	for _, name := range names {
+		if err := subscription(c, kind, name, remove(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscription confirms a (un)subscribe with the client's new subscription
+// count.
+func subscription(c *Client, kind, name string, count int) error {
+	if err := proto.Push(c, 3); err != nil {
+		return err
+	}
+	_ = proto.Bulk(c, kind)
+	_ = proto.Bulk(c, name)
+	return proto.Int(c, int64(count))
+}
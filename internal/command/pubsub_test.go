@@ -0,0 +1,151 @@
+package command_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/command"
+	"github.com/amir-aharon/goliath/internal/pubsub"
+)
+
+func newPubSubDispatcher() *command.Dispatcher {
+	d := newDispatcher()
+	command.RegisterPubSub(d, pubsub.NewHub())
+	return d
+}
+
+// dispatch runs a command for a long-lived client and returns its reply
+func dispatch(t *testing.T, d *command.Dispatcher, c *command.Client, buf *bytes.Buffer, args ...string) string {
+	t.Helper()
+	buf.Reset()
+	must(t, d.Dispatch(c, args[0], args[1:]))
+	return buf.String()
+}
+
+func TestSUBSCRIBE_ConfirmsEachChannel(t *testing.T) {
+	d := newPubSubDispatcher()
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+
+	got := dispatch(t, d, c, &buf, "SUBSCRIBE", "a", "b", "a")
+	want := "*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n" +
+		"*3\r\n$9\r\nsubscribe\r\n$1\r\nb\r\n:2\r\n" +
+		"*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:2\r\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	got = dispatch(t, d, c, &buf, "UNSUBSCRIBE")
+	want = "*3\r\n$11\r\nunsubscribe\r\n$1\r\na\r\n:1\r\n" +
+		"*3\r\n$11\r\nunsubscribe\r\n$1\r\nb\r\n:0\r\n"
+	if got != want {
+		t.Fatalf("UNSUBSCRIBE all: got %q, want %q", got, want)
+	}
+
+	got = dispatch(t, d, c, &buf, "UNSUBSCRIBE")
+	if want = "*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:0\r\n"; got != want {
+		t.Fatalf("UNSUBSCRIBE with none: got %q, want %q", got, want)
+	}
+}
+
+func TestPUBLISH_CountsReceivers(t *testing.T) {
+	d := newPubSubDispatcher()
+	var buf bytes.Buffer
+	sub := command.NewClient(&buf)
+	dispatch(t, d, sub, &buf, "SUBSCRIBE", "ch")
+
+	if got := mustRun(t, d, "PUBLISH", "ch", "hello"); got != ":1\r\n" {
+		t.Fatalf("PUBLISH: got %q, want :1", got)
+	}
+	if got := mustRun(t, d, "PUBLISH", "other", "hello"); got != ":0\r\n" {
+		t.Fatalf("PUBLISH: got %q, want :0", got)
+	}
+	if m := <-sub.Sub.Messages(); m.Channel != "ch" || m.Payload != "hello" {
+		t.Fatalf("delivered %+v", m)
+	}
+}
+
+func TestSubscriberMode_RestrictsCommands(t *testing.T) {
+	d := newPubSubDispatcher()
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+	dispatch(t, d, c, &buf, "SUBSCRIBE", "ch")
+
+	got := dispatch(t, d, c, &buf, "GET", "k")
+	want := "-ERR Can't execute 'get': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context\r\n"
+	if got != want {
+		t.Fatalf("GET while subscribed: got %q, want %q", got, want)
+	}
+	if got := dispatch(t, d, c, &buf, "PING"); got != "*2\r\n$4\r\npong\r\n$0\r\n\r\n" {
+		t.Fatalf("PING while subscribed: got %q", got)
+	}
+
+	// RESP3 clients may interleave regular commands with pushes
+	c.Proto = 3
+	if got := dispatch(t, d, c, &buf, "GET", "k"); got != "_\r\n" {
+		t.Fatalf("GET under RESP3: got %q", got)
+	}
+	if got := dispatch(t, d, c, &buf, "PING"); got != "+PONG\r\n" {
+		t.Fatalf("PING under RESP3: got %q", got)
+	}
+	c.Proto = 2
+
+	dispatch(t, d, c, &buf, "UNSUBSCRIBE", "ch")
+	if got := dispatch(t, d, c, &buf, "GET", "k"); got != "$-1\r\n" {
+		t.Fatalf("GET after unsubscribing: got %q", got)
+	}
+}
+
+func TestPSUBSCRIBE_ConfirmsAndCounts(t *testing.T) {
+	d := newPubSubDispatcher()
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+
+	dispatch(t, d, c, &buf, "SUBSCRIBE", "ch")
+	got := dispatch(t, d, c, &buf, "PSUBSCRIBE", "a.*", "b.?")
+	want := "*3\r\n$10\r\npsubscribe\r\n$3\r\na.*\r\n:2\r\n" +
+		"*3\r\n$10\r\npsubscribe\r\n$3\r\nb.?\r\n:3\r\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if got := mustRun(t, d, "PUBLISH", "a.x", "hi"); got != ":1\r\n" {
+		t.Fatalf("PUBLISH: got %q", got)
+	}
+	if m := <-c.Sub.Messages(); !m.ByPattern || m.Pattern != "a.*" || m.Channel != "a.x" {
+		t.Fatalf("delivered %+v", m)
+	}
+
+	got = dispatch(t, d, c, &buf, "PUNSUBSCRIBE")
+	want = "*3\r\n$12\r\npunsubscribe\r\n$3\r\na.*\r\n:2\r\n" +
+		"*3\r\n$12\r\npunsubscribe\r\n$3\r\nb.?\r\n:1\r\n"
+	if got != want {
+		t.Fatalf("PUNSUBSCRIBE all: got %q, want %q", got, want)
+	}
+}
+
+func TestPUBSUB(t *testing.T) {
+	d := newPubSubDispatcher()
+	var buf bytes.Buffer
+	c := command.NewClient(&buf)
+	dispatch(t, d, c, &buf, "SUBSCRIBE", "news.tech", "weather")
+	dispatch(t, d, c, &buf, "PSUBSCRIBE", "news.*")
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"CHANNELS"}, "*2\r\n$9\r\nnews.tech\r\n$7\r\nweather\r\n"},
+		{[]string{"channels", "news.*"}, "*1\r\n$9\r\nnews.tech\r\n"},
+		{[]string{"NUMSUB", "news.tech", "nope"}, "*4\r\n$9\r\nnews.tech\r\n:1\r\n$4\r\nnope\r\n:0\r\n"},
+		{[]string{"NUMSUB"}, "*0\r\n"},
+		{[]string{"NUMPAT"}, ":1\r\n"},
+		{[]string{"NUMPAT", "x"}, "-ERR wrong number of arguments for 'pubsub|numpat' command\r\n"},
+		{[]string{"nope"}, "-ERR unknown subcommand 'nope'. Try PUBSUB HELP.\r\n"},
+	}
+	for _, tc := range cases {
+		if got := mustRun(t, d, "PUBSUB", tc.args...); got != tc.want {
+			t.Errorf("PUBSUB %q: got %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
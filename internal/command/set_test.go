@@ -0,0 +1,144 @@
+package command_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSET_Conditions(t *testing.T) {
+	d := newDispatcher()
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"k", "a", "XX"}, "$-1\r\n"},
+		{[]string{"k", "a", "NX"}, "+OK\r\n"},
+		{[]string{"k", "b", "NX"}, "$-1\r\n"},
+		{[]string{"k", "b", "xx"}, "+OK\r\n"},
+		{[]string{"k", "c", "GET"}, "$1\r\nb\r\n"},
+		{[]string{"k", "d", "NX", "GET"}, "$1\r\nc\r\n"}, // not written, old value still returned
+		{[]string{"new", "v", "GET"}, "$-1\r\n"},
+		{[]string{"missing", "v", "XX", "GET"}, "$-1\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, "SET", s.args...); got != s.want {
+			t.Fatalf("SET %q: got %q, want %q", s.args, got, s.want)
+		}
+	}
+
+	if got := mustRun(t, d, "GET", "k"); got != "$1\r\nc\r\n" {
+		t.Fatalf("GET k: got %q", got)
+	}
+	if got := mustRun(t, d, "GET", "missing"); got != "$-1\r\n" {
+		t.Fatalf("GET missing: got %q", got)
+	}
+}
+
+func TestSET_Expiry(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	d := newDispatcherWithClock(fc)
+
+	mustRun(t, d, "SET", "ex", "v", "EX", "10")
+	mustRun(t, d, "SET", "px", "v", "PX", "2400")
+	mustRun(t, d, "SET", "plain", "v")
+
+	if got := mustRun(t, d, "TTL", "ex"); got != ":10\r\n" {
+		t.Fatalf("TTL ex: got %q", got)
+	}
+	if got := mustRun(t, d, "TTL", "px"); got != ":2\r\n" {
+		t.Fatalf("TTL px: got %q", got)
+	}
+
+	// a plain SET clears the TTL, KEEPTTL keeps it
+	mustRun(t, d, "SET", "ex", "w", "KEEPTTL")
+	if got := mustRun(t, d, "TTL", "ex"); got != ":10\r\n" {
+		t.Fatalf("TTL after KEEPTTL: got %q", got)
+	}
+	mustRun(t, d, "SET", "ex", "w")
+	if got := mustRun(t, d, "TTL", "ex"); got != ":-1\r\n" {
+		t.Fatalf("TTL after plain SET: got %q", got)
+	}
+
+	fc.Advance(3 * time.Second)
+	if got := mustRun(t, d, "GET", "px"); got != "$-1\r\n" {
+		t.Fatalf("GET px after expiry: got %q", got)
+	}
+}
+
+func TestSET_AbsoluteExpiry(t *testing.T) {
+	d := newDispatcher()
+	at := time.Now().Add(time.Hour).Unix()
+
+	mustRun(t, d, "SET", "k", "v", "EXAT", strconv.FormatInt(at, 10))
+	got := mustRun(t, d, "TTL", "k")
+	if got != ":3599\r\n" && got != ":3600\r\n" {
+		t.Fatalf("TTL after EXAT: got %q", got)
+	}
+
+	// already in the past: the key is gone right away
+	mustRun(t, d, "SET", "k", "v", "PXAT", "1000")
+	if got := mustRun(t, d, "GET", "k"); got != "$-1\r\n" {
+		t.Fatalf("GET after past PXAT: got %q", got)
+	}
+}
+
+func TestSET_Errors(t *testing.T) {
+	d := newDispatcher()
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"k", "v", "x"}, "-ERR syntax error\r\n"},
+		{[]string{"k", "v", "NX", "XX"}, "-ERR syntax error\r\n"},
+		{[]string{"k", "v", "EX", "10", "PX", "10"}, "-ERR syntax error\r\n"},
+		{[]string{"k", "v", "EX", "10", "KEEPTTL"}, "-ERR syntax error\r\n"},
+		{[]string{"k", "v", "EX"}, "-ERR syntax error\r\n"},
+		{[]string{"k", "v", "EX", "abc", "NX", "XX"}, "-ERR syntax error\r\n"},
+		{[]string{"k", "v", "EX", "abc"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"k", "v", "EX", "0"}, "-ERR invalid expire time in 'set' command\r\n"},
+		{[]string{"k", "v", "PX", "-5"}, "-ERR invalid expire time in 'set' command\r\n"},
+		{[]string{"k", "v", "EX", "9223372036854775807"}, "-ERR invalid expire time in 'set' command\r\n"},
+		{[]string{"k", "v", "PXAT", "9223372036854775807"}, "+OK\r\n"},
+	}
+	for _, c := range cases {
+		if got := mustRun(t, d, "SET", c.args...); got != c.want {
+			t.Errorf("SET %q: got %q, want %q", c.args, got, c.want)
+		}
+	}
+}
+
+func TestSET_Journal(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	mustRun(t, d, "SET", "k", "v", "NX", "GET")
+	mustRun(t, d, "SET", "k", "w", "NX") // not written
+	mustRun(t, d, "SET", "k", "w", "XX", "KEEPTTL")
+	mustRun(t, d, "SET", "k", "x", "PXAT", "4102444800000")
+	before := time.Now().Add(10 * time.Second).UnixMilli()
+	mustRun(t, d, "SET", "k", "y", "EX", "10")
+	after := time.Now().Add(10 * time.Second).UnixMilli()
+
+	if len(j.records) != 4 {
+		t.Fatalf("journal: got %q, want 4 records", j.records)
+	}
+	want := [][]string{
+		{"SET", "k", "v"},
+		{"SET", "k", "w", "KEEPTTL"},
+		{"SET", "k", "x", "PXAT", "4102444800000"},
+	}
+	if !reflect.DeepEqual(j.records[:3], want) {
+		t.Fatalf("journal: got %q, want %q", j.records[:3], want)
+	}
+	last := j.records[3]
+	if len(last) != 5 || last[3] != "PXAT" {
+		t.Fatalf("EX not journaled as PXAT: %q", last)
+	}
+	if ms, _ := strconv.ParseInt(last[4], 10, 64); ms < before || ms > after {
+		t.Fatalf("PXAT %d outside [%d, %d]", ms, before, after)
+	}
+}
@@ -0,0 +1,168 @@
+package command
+
+import (
+	"strconv"
+
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func registerStrings(d *Dispatcher, dbs *store.Databases) {
+	d.Register("MGET", 1, -1, false, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		vals, ok := kv.MGet(args...)
+		if err := proto.Array(c, len(args)); err != nil {
+			return err
+		}
+		for i := range args {
+			var err error
+			if ok[i] {
+				err = proto.Bulk(c, vals[i])
+			} else {
+				err = proto.NullBulk(c)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// MSET key value [key value ...]
+	d.Register("MSET", 2, -1, true, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		if len(args)%2 != 0 {
+			return proto.Err(c, "wrong number of arguments for 'mset' command")
+		}
+		kv.MSet(args...)
+		for i := 0; i < len(args); i += 2 {
+			d.notify(c.DB, notify.String, "set", args[i])
+		}
+		return proto.OK(c)
+	})
+
+	d.Register("MSETNX", 2, -1, true, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		if len(args)%2 != 0 {
+			return proto.Err(c, "wrong number of arguments for 'msetnx' command")
+		}
+		if !kv.MSetNX(args...) {
+			c.SkipPropagate()
+			return proto.Int(c, 0)
+		}
+		for i := 0; i < len(args); i += 2 {
+			d.notify(c.DB, notify.String, "set", args[i])
+		}
+		return proto.Int(c, 1)
+	})
+
+	d.Register("APPEND", 2, 2, true, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		n, err := kv.Append(args[0], args[1])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		d.notify(c.DB, notify.String, "append", args[0])
+		return proto.Int(c, int64(n))
+	})
+
+	d.Register("STRLEN", 1, 1, false, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		v, _, err := kv.Get(args[0])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		return proto.Int(c, int64(len(v)))
+	})
+
+	// GETRANGE key start end, with inclusive, possibly negative, offsets
+	d.Register("GETRANGE", 3, 3, false, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		start, err1 := strconv.ParseInt(args[1], 10, 64)
+		end, err2 := strconv.ParseInt(args[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		v, _, err := kv.Get(args[0])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		return proto.Bulk(c, substr(v, start, end))
+	})
+
+	d.Register("SETRANGE", 3, 3, true, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		offset, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		if offset < 0 {
+			return proto.Err(c, "offset is out of range")
+		}
+		if offset > store.MaxStringLen {
+			return proto.Err(c, store.ErrTooLarge.Error())
+		}
+		n, err := kv.SetRange(args[0], int(offset), args[2])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if args[2] == "" {
+			c.SkipPropagate()
+		} else {
+			d.notify(c.DB, notify.String, "setrange", args[0])
+		}
+		return proto.Int(c, int64(n))
+	})
+
+	d.Register("GETDEL", 1, 1, true, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		v, ok, err := kv.GetDel(args[0])
+		if err != nil {
+			return storeErr(c, err)
+		}
+		if !ok {
+			c.SkipPropagate()
+			return proto.NullBulk(c)
+		}
+		c.Propagate("DEL", args[0])
+		d.notify(c.DB, notify.Generic, "del", args[0])
+		return proto.Bulk(c, v)
+	})
+
+	// GETSET key value: SET key value GET, clearing any TTL
+	d.Register("GETSET", 2, 2, true, func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		old, existed, _, _, err := kv.SetWith(args[0], args[1], store.SetOptions{Get: true})
+		if err != nil {
+			return storeErr(c, err)
+		}
+		c.Propagate("SET", args[0], args[1])
+		d.notify(c.DB, notify.String, "set", args[0])
+		if !existed {
+			return proto.NullBulk(c)
+		}
+		return proto.Bulk(c, old)
+	})
+}
+
+// substr applies redis' GETRANGE rules: negative offsets count from the
+// end, both ends are clamped to the string, and an empty or inverted range
+// yields "".
+func substr(s string, start, end int64) string {
+	n := int64(len(s))
+	if start < 0 && end < 0 && start > end {
+		return ""
+	}
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if end < 0 {
+		end = max(n+end, 0)
+	}
+	end = min(end, n-1)
+	if n == 0 || start > end {
+		return ""
+	}
+	return s[start : end+1]
+}
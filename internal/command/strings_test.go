@@ -0,0 +1,131 @@
+package command_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAPPEND_STRLEN(t *testing.T) {
+	d := newDispatcher()
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"STRLEN", "k"}, ":0\r\n"},
+		{[]string{"APPEND", "k", "Hello"}, ":5\r\n"},
+		{[]string{"APPEND", "k", " World"}, ":11\r\n"},
+		{[]string{"STRLEN", "k"}, ":11\r\n"},
+		{[]string{"GET", "k"}, "$11\r\nHello World\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestGETRANGE(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "SET", "k", "This is a string")
+
+	cases := []struct {
+		start, end string
+		want       string
+	}{
+		{"0", "3", "This"},
+		{"-3", "-1", "ing"},
+		{"0", "-1", "This is a string"},
+		{"10", "100", "string"},
+		{"5", "3", ""},
+		{"-1", "-5", ""},
+		{"-100", "3", "This"},
+		{"100", "200", ""},
+	}
+	for _, c := range cases {
+		want := "$" + strconv.Itoa(len(c.want)) + "\r\n" + c.want + "\r\n"
+		if got := mustRun(t, d, "GETRANGE", "k", c.start, c.end); got != want {
+			t.Errorf("GETRANGE %s %s: got %q, want %q", c.start, c.end, got, want)
+		}
+	}
+
+	if got := mustRun(t, d, "GETRANGE", "missing", "0", "-1"); got != "$0\r\n\r\n" {
+		t.Fatalf("GETRANGE missing: got %q", got)
+	}
+	if got := mustRun(t, d, "GETRANGE", "k", "a", "1"); got != "-ERR value is not an integer or out of range\r\n" {
+		t.Fatalf("GETRANGE bad offset: got %q", got)
+	}
+}
+
+func TestSETRANGE(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "SET", "k", "Hello World")
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"SETRANGE", "k", "6", "Redis"}, ":11\r\n"},
+		{[]string{"GET", "k"}, "$11\r\nHello Redis\r\n"},
+		{[]string{"SETRANGE", "pad", "2", "x"}, ":3\r\n"},
+		{[]string{"GET", "pad"}, "$3\r\n\x00\x00x\r\n"},
+		{[]string{"SETRANGE", "none", "5", ""}, ":0\r\n"},
+		{[]string{"GET", "none"}, "$-1\r\n"},
+		{[]string{"SETRANGE", "k", "-1", "x"}, "-ERR offset is out of range\r\n"},
+		{[]string{"SETRANGE", "k", "x", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SETRANGE", "k", "536870912", "x"}, "-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestGETDEL_GETSET(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	d := newDispatcherWithClock(fc)
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"GETSET", "k", "a"}, "$-1\r\n"},
+		{[]string{"EXPIRE", "k", "100"}, ":1\r\n"},
+		{[]string{"GETSET", "k", "b"}, "$1\r\na\r\n"},
+		{[]string{"TTL", "k"}, ":-1\r\n"}, // GETSET clears the TTL
+		{[]string{"GETDEL", "k"}, "$1\r\nb\r\n"},
+		{[]string{"GETDEL", "k"}, "$-1\r\n"},
+		{[]string{"GET", "k"}, "$-1\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestStrings_Journal(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+
+	mustRun(t, d, "APPEND", "k", "a")
+	mustRun(t, d, "SETRANGE", "k", "0", "")
+	mustRun(t, d, "SETRANGE", "k", "1", "b")
+	mustRun(t, d, "GETSET", "k", "c")
+	mustRun(t, d, "GETDEL", "k")
+	mustRun(t, d, "GETDEL", "k")
+
+	want := [][]string{
+		{"APPEND", "k", "a"},
+		{"SETRANGE", "k", "1", "b"},
+		{"SET", "k", "c"},
+		{"DEL", "k"},
+	}
+	if !reflect.DeepEqual(j.records, want) {
+		t.Fatalf("journal: got %q, want %q", j.records, want)
+	}
+}
@@ -0,0 +1,233 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func RegisterTTL(d *Dispatcher, dbs *store.Databases) {
+	d.Register("TTL", 1, 1, false, ttl(dbs, store.KV.TTL))
+	d.Register("PTTL", 1, 1, false, ttl(dbs, store.KV.PTTL))
+	d.Register("EXPIRETIME", 1, 1, false, expireTime(dbs, time.Second))
+	d.Register("PEXPIRETIME", 1, 1, false, expireTime(dbs, time.Millisecond))
+
+	d.Register("EXPIRE", 2, -1, true, expire(d, dbs, "expire", "EX"))
+	d.Register("PEXPIRE", 2, -1, true, expire(d, dbs, "pexpire", "PX"))
+	d.Register("EXPIREAT", 2, -1, true, expire(d, dbs, "expireat", "EXAT"))
+	d.Register("PEXPIREAT", 2, -1, true, expire(d, dbs, "pexpireat", "PXAT"))
+
+	d.Register("PERSIST", 1, 1, true, func(c *Client, args []string) error {
+		if res, _ := dbs.DB(c.DB).ExpireWith(args[0], store.ExpireOptions{Persist: true}); res == store.ExpireSet {
+			d.notify(c.DB, notify.Generic, "persist", args[0])
+			return proto.Int(c, 1)
+		}
+		c.SkipPropagate()
+		return proto.Int(c, 0)
+	})
+
+	d.Register("GETEX", 1, -1, true, func(c *Client, args []string) error {
+		return getex(d, dbs.DB(c.DB), c, args)
+	})
+}
+
+// ttl replies -2 for a missing key, -1 for one without an expiry and the
+// remaining time otherwise.
+func ttl(dbs *store.Databases, get func(kv store.KV, k string) (int64, bool, bool)) Handler {
+	return func(c *Client, args []string) error {
+		n, exists, hasExp := get(dbs.DB(c.DB), args[0])
+		switch {
+		case !exists:
+			return proto.Int(c, -2)
+		case !hasExp:
+			return proto.Int(c, -1)
+		default:
+			return proto.Int(c, n)
+		}
+	}
+}
+
+// EXPIRETIME and PEXPIRETIME: the absolute expiry as a unix timestamp
+func expireTime(dbs *store.Databases, unit time.Duration) Handler {
+	return func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		at, exists := kv.ExpireTime(args[0])
+		switch {
+		case !exists:
+			return proto.Int(c, -2)
+		case at.IsZero():
+			return proto.Int(c, -1)
+		case unit == time.Second:
+			return proto.Int(c, (at.UnixMilli()+500)/1000)
+		default:
+			return proto.Int(c, at.UnixMilli())
+		}
+	}
+}
+
+// EXPIRE key seconds [NX | XX | GT | LT], and likewise PEXPIRE, EXPIREAT and
+// PEXPIREAT in the unit of the matching SET option
+func expire(d *Dispatcher, dbs *store.Databases, name, unit string) Handler {
+	return func(c *Client, args []string) error {
+		kv := dbs.DB(c.DB)
+		var cond store.ExpireCond
+		for _, a := range args[2:] {
+			switch strings.ToUpper(a) {
+			case "NX":
+				cond |= store.ExpireNX
+			case "XX":
+				cond |= store.ExpireXX
+			case "GT":
+				cond |= store.ExpireGT
+			case "LT":
+				cond |= store.ExpireLT
+			default:
+				return proto.Err(c, fmt.Sprintf("Unsupported option %s", a))
+			}
+		}
+		if cond&store.ExpireNX != 0 && cond != store.ExpireNX {
+			return proto.Err(c, "NX and XX, GT or LT options at the same time are not compatible")
+		}
+		if cond&store.ExpireGT != 0 && cond&store.ExpireLT != 0 {
+			return proto.Err(c, "GT and LT options at the same time are not compatible")
+		}
+
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		now := time.UnixMilli(time.Now().UnixMilli())
+		at, ok := expiryTime(unit, n, now)
+		if !ok {
+			return proto.Err(c, fmt.Sprintf("invalid expire time in '%s' command", name))
+		}
+
+		opt := store.ExpireOptions{Cond: cond, At: at}
+		if unit == "EX" || unit == "PX" {
+			// relative expiries follow the store's clock
+			opt = store.ExpireOptions{Cond: cond, TTL: at.Sub(now)}
+		}
+		res, at := kv.ExpireWith(args[0], opt)
+		return expireReply(d, c, args[0], at, res)
+	}
+}
+
+// expireReply replies to and propagates an expiry change. The conditions
+// don't matter on replay, so it's journaled as a plain PEXPIREAT, or as a
+// DEL when the time had already passed.
+func expireReply(d *Dispatcher, c *Client, key string, at time.Time, res store.ExpireResult) error {
+	switch res {
+	case store.ExpireSet:
+		c.Propagate("PEXPIREAT", key, strconv.FormatInt(at.UnixMilli(), 10))
+		d.notify(c.DB, notify.Generic, "expire", key)
+		return proto.Int(c, 1)
+	case store.ExpireDeleted:
+		c.Propagate("DEL", key)
+		d.notify(c.DB, notify.Generic, "del", key)
+		return proto.Int(c, 1)
+	default:
+		c.SkipPropagate()
+		return proto.Int(c, 0)
+	}
+}
+
+// GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds |
+// PXAT unix-time-milliseconds | PERSIST]
+func getex(d *Dispatcher, kv store.Strings, c *Client, args []string) error {
+	key := args[0]
+
+	var unit, expire string
+	for i := 1; i < len(args); i++ {
+		switch a := strings.ToUpper(args[i]); {
+		case a == "PERSIST" && (unit == "" || unit == a):
+			unit = a
+		case (a == "EX" || a == "PX" || a == "EXAT" || a == "PXAT") && (unit == "" || unit == a) && i+1 < len(args):
+			unit, expire = a, args[i+1]
+			i++
+		default:
+			return proto.Err(c, "syntax error")
+		}
+	}
+
+	var opt store.ExpireOptions
+	var at time.Time
+	switch unit {
+	case "":
+		c.SkipPropagate()
+		v, ok, err := kv.Get(key)
+		switch {
+		case err != nil:
+			return storeErr(c, err)
+		case ok:
+			return proto.Bulk(c, v)
+		}
+		return proto.NullBulk(c)
+	case "PERSIST":
+		opt.Persist = true
+	default:
+		n, err := strconv.ParseInt(expire, 10, 64)
+		if err != nil {
+			return proto.Err(c, "value is not an integer or out of range")
+		}
+		now := time.UnixMilli(time.Now().UnixMilli())
+		var ok bool
+		at, ok = expiryTime(unit, n, now)
+		if n <= 0 || !ok {
+			return proto.Err(c, "invalid expire time in 'getex' command")
+		}
+		opt.At = at
+		if unit == "EX" || unit == "PX" {
+			opt = store.ExpireOptions{TTL: at.Sub(now)}
+		}
+	}
+
+	v, ok, res, at, err := kv.GetEx(key, opt)
+	if err != nil {
+		return storeErr(c, err)
+	}
+	switch {
+	case opt.Persist && res == store.ExpireSet:
+		c.Propagate("PERSIST", key)
+		d.notify(c.DB, notify.Generic, "persist", key)
+	case res == store.ExpireSet:
+		c.Propagate("PEXPIREAT", key, strconv.FormatInt(at.UnixMilli(), 10))
+		d.notify(c.DB, notify.Generic, "expire", key)
+	case res == store.ExpireDeleted:
+		c.Propagate("DEL", key)
+		d.notify(c.DB, notify.Generic, "del", key)
+	default:
+		c.SkipPropagate()
+	}
+
+	if !ok {
+		return proto.NullBulk(c)
+	}
+	return proto.Bulk(c, v)
+}
+
+// expiryTime turns a count in the given unit (the SET option names EX, PX,
+// EXAT and PXAT) into an absolute time, failing like redis when it
+// overflows in milliseconds.
+func expiryTime(unit string, n int64, now time.Time) (time.Time, bool) {
+	ms := n
+	if unit == "EX" || unit == "EXAT" {
+		if n > math.MaxInt64/1000 || n < math.MinInt64/1000 {
+			return time.Time{}, false
+		}
+		ms = n * 1000
+	}
+	if unit == "EX" || unit == "PX" {
+		base := now.UnixMilli()
+		if ms > math.MaxInt64-base {
+			return time.Time{}, false
+		}
+		ms += base
+	}
+	return time.UnixMilli(ms), true
+}
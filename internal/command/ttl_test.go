@@ -1,6 +1,8 @@
 package command_test
 
 import (
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -11,7 +13,7 @@ func TestSETEX_NonIntegerSeconds(t *testing.T) {
 	if err != nil {
 		t.Fatalf("dispatch error: %v", err)
 	}
-	want := "-ERR seconds must be a positive integer\r\n"
+	want := "-ERR value is not an integer or out of range\r\n"
 	if got != want {
 		t.Fatalf("got %q, want %q", got, want)
 	}
@@ -23,7 +25,7 @@ func TestSETEX_NonPositiveSeconds_Zero(t *testing.T) {
 	if err != nil {
 		t.Fatalf("dispatch error: %v", err)
 	}
-	want := "-ERR seconds must be a positive integer\r\n"
+	want := "-ERR invalid expire time in 'setex' command\r\n"
 	if got != want {
 		t.Fatalf("got %q, want %q", got, want)
 	}
@@ -35,7 +37,7 @@ func TestSETEX_NonPositiveSeconds_Negative(t *testing.T) {
 	if err != nil {
 		t.Fatalf("dispatch error: %v", err)
 	}
-	want := "-ERR seconds must be a positive integer\r\n"
+	want := "-ERR invalid expire time in 'setex' command\r\n"
 	if got != want {
 		t.Fatalf("got %q, want %q", got, want)
 	}
@@ -61,8 +63,8 @@ func TestTTL_MissingKey_ReturnsMinus2(t *testing.T) {
 	if err != nil {
 		t.Fatalf("dispatch error: %v", err)
 	}
-	if got != "-2\r\n" {
-		t.Fatalf("got %q, want %q", got, "-2\r\n")
+	if got != ":-2\r\n" {
+		t.Fatalf("got %q, want %q", got, ":-2\r\n")
 	}
 }
 
@@ -78,8 +80,8 @@ func TestTTL_NoExpiry_ReturnsMinus1(t *testing.T) {
 	if err != nil {
 		t.Fatalf("TTL error: %v", err)
 	}
-	if got != "-1\r\n" {
-		t.Fatalf("got %q, want %q", got, "-1\r\n")
+	if got != ":-1\r\n" {
+		t.Fatalf("got %q, want %q", got, ":-1\r\n")
 	}
 }
 
@@ -92,13 +94,13 @@ func TestTTL_CountsDown_WithExpiry(t *testing.T) {
 		t.Fatalf("SETEX error: %v", err)
 	}
 
-	if got, _ := run(d, "TTL", "k"); got != "5\r\n" {
-		t.Fatalf("after setex: got %q, want %q", got, "5\r\n")
+	if got, _ := run(d, "TTL", "k"); got != ":5\r\n" {
+		t.Fatalf("after setex: got %q, want %q", got, ":5\r\n")
 	}
 
 	fc.Advance(3 * time.Second)
-	if got, _ := run(d, "TTL", "k"); got != "2\r\n" {
-		t.Fatalf("after +3s: got %q, want %q", got, "2\r\n")
+	if got, _ := run(d, "TTL", "k"); got != ":2\r\n" {
+		t.Fatalf("after +3s: got %q, want %q", got, ":2\r\n")
 	}
 }
 
@@ -113,8 +115,8 @@ func TestTTL_AfterExpiry_ReturnsMinus2(t *testing.T) {
 
 	// Advance beyond expiry
 	fc.Advance(3 * time.Second)
-	if got, _ := run(d, "TTL", "k"); got != "-2\r\n" {
-		t.Fatalf("after expiry: got %q, want %q", got, "-2\r\n")
+	if got, _ := run(d, "TTL", "k"); got != ":-2\r\n" {
+		t.Fatalf("after expiry: got %q, want %q", got, ":-2\r\n")
 	}
 }
 
@@ -127,17 +129,17 @@ func TestPERSIST_OnKeyWithExpiry_Returns1_ThenTTLMinus1(t *testing.T) {
 		t.Fatalf("SETEX error: %v", err)
 	}
 
-	if got, _ := run(d, "PERSIST", "k"); got != "1\r\n" {
-		t.Fatalf("PERSIST: got %q, want %q", got, "1\r\n")
+	if got, _ := run(d, "PERSIST", "k"); got != ":1\r\n" {
+		t.Fatalf("PERSIST: got %q, want %q", got, ":1\r\n")
 	}
 
-	if got, _ := run(d, "TTL", "k"); got != "-1\r\n" {
-		t.Fatalf("TTL after persist: got %q, want %q", got, "-1\r\n")
+	if got, _ := run(d, "TTL", "k"); got != ":-1\r\n" {
+		t.Fatalf("TTL after persist: got %q, want %q", got, ":-1\r\n")
 	}
 
 	fc.Advance(10 * time.Second)
-	if got, _ := run(d, "GET", "k"); got != "v\r\n" {
-		t.Fatalf("GET after persist+advance: got %q, want %q", got, "v\r\n")
+	if got, _ := run(d, "GET", "k"); got != "$1\r\nv\r\n" {
+		t.Fatalf("GET after persist+advance: got %q, want %q", got, "$1\r\nv\r\n")
 	}
 }
 
@@ -149,8 +151,8 @@ func TestPERSIST_OnKeyWithoutExpiry_Returns0(t *testing.T) {
 	if _, err := run(d, "SET", "k", "v"); err != nil {
 		t.Fatalf("SET error: %v", err)
 	}
-	if got, _ := run(d, "PERSIST", "k"); got != "0\r\n" {
-		t.Fatalf("PERSIST: got %q, want %q", got, "0\r\n")
+	if got, _ := run(d, "PERSIST", "k"); got != ":0\r\n" {
+		t.Fatalf("PERSIST: got %q, want %q", got, ":0\r\n")
 	}
 }
 
@@ -159,15 +161,223 @@ func TestPERSIST_OnMissingOrExpiredKey_Returns0(t *testing.T) {
 	fc := newFakeClock(start)
 	d := newDispatcherWithClock(fc)
 
-	if got, _ := run(d, "PERSIST", "nope"); got != "0\r\n" {
-		t.Fatalf("PERSIST missing: got %q, want %q", got, "0\r\n")
+	if got, _ := run(d, "PERSIST", "nope"); got != ":0\r\n" {
+		t.Fatalf("PERSIST missing: got %q, want %q", got, ":0\r\n")
 	}
 
 	if _, err := run(d, "SETEX", "gone", "2", "v"); err != nil {
 		t.Fatalf("SETEX error: %v", err)
 	}
 	fc.Advance(3 * time.Second)
-	if got, _ := run(d, "PERSIST", "gone"); got != "0\r\n" {
-		t.Fatalf("PERSIST expired: got %q, want %q", got, "0\r\n")
+	if got, _ := run(d, "PERSIST", "gone"); got != ":0\r\n" {
+		t.Fatalf("PERSIST expired: got %q, want %q", got, ":0\r\n")
+	}
+}
+
+func TestPEXPIREAT(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fc := newFakeClock(start)
+	d := newDispatcherWithClock(fc)
+
+	at := strconv.FormatInt(start.Add(5*time.Second).UnixMilli(), 10)
+	if got := mustRun(t, d, "PEXPIREAT", "nope", at); got != ":0\r\n" {
+		t.Fatalf("PEXPIREAT missing: got %q, want %q", got, ":0\r\n")
+	}
+
+	mustRun(t, d, "SET", "k", "v")
+	if got := mustRun(t, d, "PEXPIREAT", "k", at); got != ":1\r\n" {
+		t.Fatalf("PEXPIREAT: got %q, want %q", got, ":1\r\n")
+	}
+	if got := mustRun(t, d, "TTL", "k"); got != ":5\r\n" {
+		t.Fatalf("TTL after PEXPIREAT: got %q, want %q", got, ":5\r\n")
+	}
+
+	past := strconv.FormatInt(start.Add(-time.Second).UnixMilli(), 10)
+	if got := mustRun(t, d, "PEXPIREAT", "k", past); got != ":1\r\n" {
+		t.Fatalf("PEXPIREAT in the past: got %q, want %q", got, ":1\r\n")
+	}
+	if got := mustRun(t, d, "GET", "k"); got != "$-1\r\n" {
+		t.Fatalf("GET after past PEXPIREAT: got %q, want %q", got, "$-1\r\n")
+	}
+
+	if got := mustRun(t, d, "PEXPIREAT", "k", "soon"); got != "-ERR value is not an integer or out of range\r\n" {
+		t.Fatalf("PEXPIREAT non-integer: got %q", got)
+	}
+}
+
+func TestEXPIRE_Family(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fc := newFakeClock(start)
+	d := newDispatcherWithClock(fc)
+	mustRun(t, d, "SET", "k", "v")
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"EXPIRE", "missing", "10"}, ":0\r\n"},
+		{[]string{"EXPIRE", "k", "10"}, ":1\r\n"},
+		{[]string{"TTL", "k"}, ":10\r\n"},
+		{[]string{"PTTL", "k"}, ":10000\r\n"},
+		{[]string{"PEXPIRE", "k", "2500"}, ":1\r\n"},
+		{[]string{"PTTL", "k"}, ":2500\r\n"},
+		{[]string{"TTL", "k"}, ":3\r\n"}, // rounded, like redis
+		{[]string{"EXPIREAT", "k", strconv.FormatInt(start.Unix()+100, 10)}, ":1\r\n"},
+		{[]string{"EXPIRETIME", "k"}, ":" + strconv.FormatInt(start.Unix()+100, 10) + "\r\n"},
+		{[]string{"PEXPIRETIME", "k"}, ":" + strconv.FormatInt(start.UnixMilli()+100_000, 10) + "\r\n"},
+		{[]string{"PERSIST", "k"}, ":1\r\n"},
+		{[]string{"PTTL", "k"}, ":-1\r\n"},
+		{[]string{"EXPIRETIME", "k"}, ":-1\r\n"},
+		{[]string{"PTTL", "missing"}, ":-2\r\n"},
+		{[]string{"PEXPIRETIME", "missing"}, ":-2\r\n"},
+		{[]string{"EXPIRE", "k", "0"}, ":1\r\n"}, // deletes
+		{[]string{"GET", "k"}, "$-1\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestEXPIRE_Conditions(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	d := newDispatcherWithClock(fc)
+	mustRun(t, d, "SET", "k", "v")
+
+	steps := []struct {
+		args []string
+		want string
+		ttl  string
+	}{
+		{[]string{"k", "100", "XX"}, ":0\r\n", ":-1\r\n"},
+		{[]string{"k", "100", "GT"}, ":0\r\n", ":-1\r\n"}, // no expiry counts as infinite
+		{[]string{"k", "100", "NX"}, ":1\r\n", ":100\r\n"},
+		{[]string{"k", "200", "NX"}, ":0\r\n", ":100\r\n"},
+		{[]string{"k", "50", "GT"}, ":0\r\n", ":100\r\n"},
+		{[]string{"k", "200", "gt"}, ":1\r\n", ":200\r\n"},
+		{[]string{"k", "300", "LT"}, ":0\r\n", ":200\r\n"},
+		{[]string{"k", "150", "XX", "LT"}, ":1\r\n", ":150\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, "EXPIRE", s.args...); got != s.want {
+			t.Fatalf("EXPIRE %q: got %q, want %q", s.args, got, s.want)
+		}
+		if got := mustRun(t, d, "TTL", "k"); got != s.ttl {
+			t.Fatalf("TTL after EXPIRE %q: got %q, want %q", s.args, got, s.ttl)
+		}
+	}
+
+	mustRun(t, d, "PERSIST", "k")
+	if got := mustRun(t, d, "EXPIRE", "k", "10", "LT"); got != ":1\r\n" {
+		t.Fatalf("LT on a key without expiry: got %q, want :1", got)
+	}
+}
+
+func TestEXPIRE_Errors(t *testing.T) {
+	d := newDispatcher()
+	mustRun(t, d, "SET", "k", "v")
+
+	cases := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"EXPIRE", "k", "abc"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"EXPIRE", "k", "10", "YY"}, "-ERR Unsupported option YY\r\n"},
+		{[]string{"EXPIRE", "k", "10", "NX", "XX"}, "-ERR NX and XX, GT or LT options at the same time are not compatible\r\n"},
+		{[]string{"PEXPIRE", "k", "10", "GT", "NX"}, "-ERR NX and XX, GT or LT options at the same time are not compatible\r\n"},
+		{[]string{"EXPIREAT", "k", "10", "GT", "LT"}, "-ERR GT and LT options at the same time are not compatible\r\n"},
+		{[]string{"EXPIRE", "k", "9223372036854775807"}, "-ERR invalid expire time in 'expire' command\r\n"},
+		{[]string{"PEXPIRE", "k", "9223372036854775807"}, "-ERR invalid expire time in 'pexpire' command\r\n"},
+		{[]string{"EXPIREAT", "k", "-9223372036854775807"}, "-ERR invalid expire time in 'expireat' command\r\n"},
+	}
+	for _, c := range cases {
+		if got := mustRun(t, d, c.cmd[0], c.cmd[1:]...); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.cmd, got, c.want)
+		}
+	}
+}
+
+func TestPSETEX(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	d := newDispatcherWithClock(fc)
+
+	if got := mustRun(t, d, "PSETEX", "k", "1500", "v"); got != "+OK\r\n" {
+		t.Fatalf("PSETEX: got %q", got)
+	}
+	if got := mustRun(t, d, "PTTL", "k"); got != ":1500\r\n" {
+		t.Fatalf("PTTL: got %q", got)
+	}
+	if got := mustRun(t, d, "PSETEX", "k", "0", "v"); got != "-ERR invalid expire time in 'psetex' command\r\n" {
+		t.Fatalf("PSETEX 0: got %q", got)
+	}
+	fc.Advance(2 * time.Second)
+	if got := mustRun(t, d, "GET", "k"); got != "$-1\r\n" {
+		t.Fatalf("GET after expiry: got %q", got)
+	}
+}
+
+func TestGETEX(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fc := newFakeClock(start)
+	d := newDispatcherWithClock(fc)
+	mustRun(t, d, "SET", "k", "v")
+
+	steps := []struct {
+		cmd  []string
+		want string
+	}{
+		{[]string{"GETEX", "missing", "EX", "10"}, "$-1\r\n"},
+		{[]string{"GETEX", "k"}, "$1\r\nv\r\n"},
+		{[]string{"TTL", "k"}, ":-1\r\n"},
+		{[]string{"GETEX", "k", "EX", "10"}, "$1\r\nv\r\n"},
+		{[]string{"TTL", "k"}, ":10\r\n"},
+		{[]string{"GETEX", "k", "PX", "500"}, "$1\r\nv\r\n"},
+		{[]string{"PTTL", "k"}, ":500\r\n"},
+		{[]string{"GETEX", "k", "EXAT", strconv.FormatInt(start.Unix()+60, 10)}, "$1\r\nv\r\n"},
+		{[]string{"TTL", "k"}, ":60\r\n"},
+		{[]string{"GETEX", "k", "PERSIST"}, "$1\r\nv\r\n"},
+		{[]string{"TTL", "k"}, ":-1\r\n"},
+		{[]string{"GETEX", "k", "PXAT", "1000"}, "$1\r\nv\r\n"}, // in the past: returned, then deleted
+		{[]string{"GET", "k"}, "$-1\r\n"},
+		{[]string{"GETEX", "k", "EX", "10", "PERSIST"}, "-ERR syntax error\r\n"},
+		{[]string{"GETEX", "k", "EX"}, "-ERR syntax error\r\n"},
+		{[]string{"GETEX", "k", "EX", "0"}, "-ERR invalid expire time in 'getex' command\r\n"},
+		{[]string{"GETEX", "k", "PX", "x"}, "-ERR value is not an integer or out of range\r\n"},
+	}
+	for _, s := range steps {
+		if got := mustRun(t, d, s.cmd[0], s.cmd[1:]...); got != s.want {
+			t.Fatalf("%q: got %q, want %q", s.cmd, got, s.want)
+		}
+	}
+}
+
+func TestExpiry_Journal(t *testing.T) {
+	d := newDispatcher()
+	j := &fakeJournal{}
+	d.AddJournal(j)
+	mustRun(t, d, "SET", "k", "v")
+	j.records = nil
+
+	at := strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10)
+	mustRun(t, d, "PEXPIREAT", "k", at, "NX")
+	mustRun(t, d, "PEXPIREAT", "k", at, "NX") // condition not met
+	mustRun(t, d, "PERSIST", "k")
+	mustRun(t, d, "PERSIST", "k") // nothing to remove
+	mustRun(t, d, "GETEX", "k")
+	mustRun(t, d, "GETEX", "k", "PXAT", at)
+	mustRun(t, d, "GETEX", "k", "PERSIST")
+	mustRun(t, d, "EXPIRE", "missing", "10")
+	mustRun(t, d, "EXPIRE", "k", "-1")
+
+	want := [][]string{
+		{"PEXPIREAT", "k", at},
+		{"PERSIST", "k"},
+		{"PEXPIREAT", "k", at},
+		{"PERSIST", "k"},
+		{"DEL", "k"},
+	}
+	if !reflect.DeepEqual(j.records, want) {
+		t.Fatalf("journal:\n got %q\nwant %q", j.records, want)
 	}
 }
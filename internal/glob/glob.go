@@ -0,0 +1,109 @@
+// Package glob implements Redis-style glob matching, as used by
+// PSUBSCRIBE, KEYS and SCAN MATCH. '*' matches any run of bytes, including
+// none, and '?' exactly one byte. [abc] matches one of a, b or c, [^abc]
+// anything else and [a-z] a range. A backslash makes the next byte literal,
+// inside brackets too. Matching is byte-wise and case sensitive.
+package glob
+
+// patterns nested deeper than this (one level per '*') never match, so a
+// hostile pattern can't exhaust the stack
+const maxNesting = 1000
+
+// Match reports whether s matches pattern.
+func Match(pattern, s string) bool {
+	var skipLonger bool
+	return match(pattern, s, &skipLonger, 0)
+}
+
+// match follows redis' stringmatchlen. skipLonger is set once a '*' has
+// tried every suffix of s and failed: an outer '*' trying a shorter prefix
+// can't succeed either, which keeps patterns like "a*a*a*a*b" linear.
+func match(p, s string, skipLonger *bool, nesting int) bool {
+	if nesting > maxNesting {
+		return false
+	}
+
+	for len(p) > 0 && len(s) > 0 {
+		switch p[0] {
+		case '*':
+			for len(p) > 1 && p[1] == '*' {
+				p = p[1:]
+			}
+			if len(p) == 1 {
+				return true
+			}
+			for len(s) > 0 {
+				if match(p[1:], s, skipLonger, nesting+1) {
+					return true
+				}
+				if *skipLonger {
+					return false
+				}
+				s = s[1:]
+			}
+			*skipLonger = true
+			return false
+		case '?':
+			s = s[1:]
+		case '[':
+			p = p[1:]
+			not := len(p) > 0 && p[0] == '^'
+			if not {
+				p = p[1:]
+			}
+			matched := false
+			for {
+				if len(p) >= 2 && p[0] == '\\' {
+					p = p[1:]
+					if p[0] == s[0] {
+						matched = true
+					}
+				} else if len(p) == 0 {
+					// unterminated class: stop at the end of the pattern
+					break
+				} else if p[0] == ']' {
+					break
+				} else if len(p) >= 3 && p[1] == '-' {
+					lo, hi := p[0], p[2]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					if s[0] >= lo && s[0] <= hi {
+						matched = true
+					}
+					p = p[2:]
+				} else if p[0] == s[0] {
+					matched = true
+				}
+				p = p[1:]
+			}
+			if matched == not {
+				return false
+			}
+			s = s[1:]
+			if len(p) == 0 {
+				// the class ran off the end; nothing left to consume
+				return len(s) == 0
+			}
+		case '\\':
+			if len(p) >= 2 {
+				p = p[1:]
+			}
+			fallthrough
+		default:
+			if p[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+		}
+		p = p[1:]
+
+		if len(s) == 0 {
+			for len(p) > 0 && p[0] == '*' {
+				p = p[1:]
+			}
+			break
+		}
+	}
+	return len(p) == 0 && len(s) == 0
+}
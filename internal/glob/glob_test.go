@@ -0,0 +1,69 @@
+package glob_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/glob"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"", "", true},
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"abc", "ab", false},
+		{"*", "anything", true},
+		{"**", "anything", true},
+		{"a*", "a", true},
+		{"a*c", "abbbc", true},
+		{"a*c", "abbbd", false},
+		{"*.created", "orders.1.created", true},
+		{"orders.*.created", "orders.42.created", true},
+		{"orders.*.created", "orders.42.deleted", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hallo", true}, // reversed ranges are swapped
+		{"h[a-b]llo", "hcllo", false},
+		{"[\\]]", "]", true},
+		{"[\\-]", "-", true},
+		{"\\*", "*", true},
+		{"\\*", "x", false},
+		{"a\\?c", "a?c", true},
+		{"a\\?c", "abc", false},
+		{"[abc", "a", true}, // unterminated class runs to the end
+		{"[abc", "ab", false},
+		{"x\\", "x\\", true}, // trailing backslash is literal
+		{"a*b", "a\x00\xffb", true},
+		{"ABC", "abc", false},
+	}
+	for _, c := range cases {
+		if got := glob.Match(c.pattern, c.s); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.s, got, c.want)
+		}
+	}
+}
+
+func TestMatch_PathologicalPattern(t *testing.T) {
+	p := strings.Repeat("a*", 30) + "b"
+	s := strings.Repeat("a", 60)
+	if glob.Match(p, s) {
+		t.Fatalf("unexpected match")
+	}
+}
+
+func TestMatch_NestingLimit(t *testing.T) {
+	p := strings.Repeat("*a", 2000)
+	s := strings.Repeat("a", 2000)
+	if glob.Match(p, s) {
+		t.Fatalf("pattern deeper than the nesting limit should not match")
+	}
+}
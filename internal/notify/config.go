@@ -0,0 +1,21 @@
+package notify
+
+import "os"
+
+type Config struct {
+	Flags Class
+}
+
+// LoadConfig reads NOTIFY_KEYSPACE_EVENTS; notifications are off by
+// default, like in redis.
+func LoadConfig() Config {
+	var cfg Config
+
+	if v := os.Getenv("NOTIFY_KEYSPACE_EVENTS"); v != "" {
+		if flags, err := ParseFlags(v); err == nil {
+			cfg.Flags = flags
+		}
+	}
+
+	return cfg
+}
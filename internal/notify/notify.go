@@ -0,0 +1,118 @@
+// Package notify publishes keyspace notifications: for every change to a
+// key, a message on __keyspace@<db>__:<key> carrying the event name and one
+// on __keyevent@<db>__:<event> carrying the key. Which of them are sent is
+// chosen with a notify-keyspace-events style class mask.
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/amir-aharon/goliath/internal/pubsub"
+)
+
+type Class int
+
+const (
+	Keyspace Class = 1 << iota // K
+	Keyevent                   // E
+	Generic                    // g: del, expire, persist, ...
+	String                     // $
+	List                       // l
+	Set                        // s
+	Hash                       // h
+	ZSet                       // z
+	Expired                    // x
+	Evicted                    // e
+	Stream                     // t
+	KeyMiss                    // m
+	Module                     // d
+	New                        // n
+
+	// All is "A", everything but the K/E selectors and the opt-in m and n
+	All = Generic | String | List | Set | Hash | ZSet | Expired | Evicted | Stream | Module
+)
+
+var classChars = []struct {
+	c     byte
+	class Class
+}{
+	{'g', Generic}, {'$', String}, {'l', List}, {'s', Set}, {'h', Hash},
+	{'z', ZSet}, {'x', Expired}, {'e', Evicted}, {'t', Stream}, {'d', Module},
+	{'K', Keyspace}, {'E', Keyevent}, {'m', KeyMiss}, {'n', New},
+}
+
+// ParseFlags parses a notify-keyspace-events string such as "KEA" or "Ex".
+func ParseFlags(s string) (Class, error) {
+	var flags Class
+	for i := 0; i < len(s); i++ {
+		if s[i] == 'A' {
+			flags |= All
+			continue
+		}
+		ok := false
+		for _, cc := range classChars {
+			if cc.c == s[i] {
+				flags |= cc.class
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return 0, fmt.Errorf("invalid notify-keyspace-events class %q", s[i])
+		}
+	}
+	return flags, nil
+}
+
+// String formats flags the way redis reports them, folding the classes in
+// All back into "A".
+func (flags Class) String() string {
+	var b strings.Builder
+	if flags&All == All {
+		b.WriteByte('A')
+	}
+	for _, cc := range classChars {
+		if flags&cc.class == 0 || (flags&All == All && cc.class&All != 0) {
+			continue
+		}
+		b.WriteByte(cc.c)
+	}
+	return b.String()
+}
+
+type Notifier struct {
+	hub   *pubsub.Hub
+	flags atomic.Int64
+}
+
+func NewNotifier(hub *pubsub.Hub, flags Class) *Notifier {
+	n := &Notifier{hub: hub}
+	n.SetFlags(flags)
+	return n
+}
+
+func (n *Notifier) Flags() Class {
+	return Class(n.flags.Load())
+}
+
+func (n *Notifier) SetFlags(flags Class) {
+	n.flags.Store(int64(flags))
+}
+
+// Notify publishes event for key if class is enabled. Nothing is sent
+// unless K or E is enabled too.
+func (n *Notifier) Notify(class Class, event, key string, db int) {
+	flags := n.Flags()
+	if flags&class == 0 {
+		return
+	}
+	if flags&Keyspace != 0 {
+		n.hub.Publish("__keyspace@"+strconv.Itoa(db)+"__:"+key, event)
+	}
+	if flags&Keyevent != 0 {
+		n.hub.Publish("__keyevent@"+strconv.Itoa(db)+"__:"+event, key)
+	}
+}
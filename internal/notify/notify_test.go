@@ -0,0 +1,67 @@
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/notify"
+	"github.com/amir-aharon/goliath/internal/pubsub"
+)
+
+func TestParseFlags(t *testing.T) {
+	cases := []struct {
+		in   string
+		want notify.Class
+		str  string
+	}{
+		{"", 0, ""},
+		{"KEA", notify.Keyspace | notify.Keyevent | notify.All, "AKE"},
+		{"Ex", notify.Keyevent | notify.Expired, "xE"},
+		{"g$K", notify.Generic | notify.String | notify.Keyspace, "g$K"},
+		{"Anm", notify.All | notify.New | notify.KeyMiss, "Amn"},
+	}
+	for _, c := range cases {
+		got, err := notify.ParseFlags(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("ParseFlags(%q) = %v, %v; want %v", c.in, got, err, c.want)
+		}
+		if s := got.String(); s != c.str {
+			t.Errorf("ParseFlags(%q).String() = %q, want %q", c.in, s, c.str)
+		}
+	}
+
+	if _, err := notify.ParseFlags("KEq"); err == nil {
+		t.Errorf("ParseFlags(KEq): expected an error")
+	}
+}
+
+func TestNotifier_PublishesSelectedChannels(t *testing.T) {
+	hub := pubsub.NewHub()
+	sub := hub.NewSubscriber()
+	sub.PSubscribe("__key*__:*")
+
+	n := notify.NewNotifier(hub, notify.Keyspace|notify.Keyevent|notify.Generic)
+	n.Notify(notify.Generic, "del", "k", 0)
+
+	if m := <-sub.Messages(); m.Channel != "__keyspace@0__:k" || m.Payload != "del" {
+		t.Fatalf("keyspace message: got %+v", m)
+	}
+	if m := <-sub.Messages(); m.Channel != "__keyevent@0__:del" || m.Payload != "k" {
+		t.Fatalf("keyevent message: got %+v", m)
+	}
+
+	// class not enabled
+	n.Notify(notify.String, "set", "k", 0)
+	// class enabled but neither K nor E
+	n.SetFlags(notify.All)
+	n.Notify(notify.Generic, "del", "k", 0)
+	// only keyevent
+	n.SetFlags(notify.Keyevent | notify.Expired)
+	n.Notify(notify.Expired, "expired", "k", 3)
+
+	if m := <-sub.Messages(); m.Channel != "__keyevent@3__:expired" || m.Payload != "k" {
+		t.Fatalf("got %+v, want only the expired keyevent", m)
+	}
+	if len(sub.Messages()) != 0 {
+		t.Fatalf("unexpected extra messages queued")
+	}
+}
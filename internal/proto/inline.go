@@ -0,0 +1,118 @@
+package proto
+
+import "strings"
+
+// splitInline tokenizes an inline request the way redis-cli expects:
+// arguments are separated by whitespace, and may be wrapped in double quotes
+// (supporting \n \r \t \b \a \\ \" and \xHH escapes) or single quotes
+// (supporting only \'). A closing quote must be followed by whitespace or the
+// end of the line.
+func splitInline(line string) ([]string, error) {
+	var args []string
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var (
+			cur    strings.Builder
+			inDq   bool
+			inSq   bool
+			closed bool
+		)
+		switch line[i] {
+		case '"':
+			inDq = true
+			i++
+		case '\'':
+			inSq = true
+			i++
+		}
+
+		for !closed {
+			if i == len(line) {
+				if inDq || inSq {
+					return nil, protoErr("unbalanced quotes in request")
+				}
+				break
+			}
+			c := line[i]
+			switch {
+			case inDq:
+				if c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]) {
+					cur.WriteByte(unhex(line[i+2])<<4 | unhex(line[i+3]))
+					i += 3
+				} else if c == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						cur.WriteByte('\n')
+					case 'r':
+						cur.WriteByte('\r')
+					case 't':
+						cur.WriteByte('\t')
+					case 'b':
+						cur.WriteByte('\b')
+					case 'a':
+						cur.WriteByte('\a')
+					default:
+						cur.WriteByte(line[i])
+					}
+				} else if c == '"' {
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, protoErr("unbalanced quotes in request")
+					}
+					closed = true
+				} else {
+					cur.WriteByte(c)
+				}
+			case inSq:
+				if c == '\\' && i+1 < len(line) && line[i+1] == '\'' {
+					cur.WriteByte('\'')
+					i++
+				} else if c == '\'' {
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, protoErr("unbalanced quotes in request")
+					}
+					closed = true
+				} else {
+					cur.WriteByte(c)
+				}
+			default:
+				if isSpace(c) {
+					closed = true
+					continue
+				}
+				cur.WriteByte(c)
+			}
+			i++
+		}
+		args = append(args, cur.String())
+	}
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
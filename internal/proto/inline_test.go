@@ -0,0 +1,41 @@
+package proto_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/proto"
+)
+
+func TestReadCommand_InlineQuoting(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`SET k "hello world"`, []string{"SET", "k", "hello world"}},
+		{`SET k 'it\'s'`, []string{"SET", "k", "it's"}},
+		{`SET k "a\r\nb\t\"c\""`, []string{"SET", "k", "a\r\nb\t\"c\""}},
+		{`SET k "\x00\xff\x7F"`, []string{"SET", "k", "\x00\xff\x7f"}},
+		{`SET k ""`, []string{"SET", "k", ""}},
+		{`SET k 'no \n escapes'`, []string{"SET", "k", `no \n escapes`}},
+	}
+	for _, tc := range tests {
+		got, err := proto.NewReader(strings.NewReader(tc.in + "\r\n")).ReadCommand()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.in, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestReadCommand_InlineUnbalancedQuotes(t *testing.T) {
+	for _, in := range []string{`SET k "open`, `SET k 'open`, `SET k "a"b`} {
+		_, err := proto.NewReader(strings.NewReader(in + "\r\n")).ReadCommand()
+		if err == nil || err.Error() != "Protocol error: unbalanced quotes in request" {
+			t.Fatalf("%s: got %v, want unbalanced quotes error", in, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package proto
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	DefaultMaxBulkLen   = 512 << 20 // 512MB, same as redis proto-max-bulk-len
+	DefaultMaxArrayLen  = 1 << 20
+	DefaultMaxInlineLen = 64 << 10
+)
+
+// ProtocolError is returned for malformed requests. The connection should be
+// closed after replying with it, since the stream can't be resynchronized.
+type ProtocolError struct {
+	msg string
+}
+
+func (e *ProtocolError) Error() string {
+	return "Protocol error: " + e.msg
+}
+
+func protoErr(format string, a ...any) error {
+	return &ProtocolError{msg: fmt.Sprintf(format, a...)}
+}
+
+// Reader decodes client requests: RESP2 arrays of bulk strings
+// (*2\r\n$4\r\nECHO\r\n$2\r\nhi\r\n) as well as inline commands (ECHO hi\r\n).
+type Reader struct {
+	r            *bufio.Reader
+	MaxBulkLen   int
+	MaxArrayLen  int
+	MaxInlineLen int
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r:            bufio.NewReader(r),
+		MaxBulkLen:   DefaultMaxBulkLen,
+		MaxArrayLen:  DefaultMaxArrayLen,
+		MaxInlineLen: DefaultMaxInlineLen,
+	}
+}
+
+// ReadCommand returns the next request's arguments. An empty request (blank
+// inline line or *0) yields an empty slice and a nil error. io.EOF is only
+// returned on a clean boundary between requests.
+func (r *Reader) ReadCommand() ([]string, error) {
+	b, err := r.r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] == '*' {
+		return r.readArray()
+	}
+	return r.readInline()
+}
+
+func (r *Reader) readArray() ([]string, error) {
+	line, err := r.readLine(r.MaxInlineLen, "too big mbulk count string")
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n > r.MaxArrayLen {
+		return nil, protoErr("invalid multibulk length")
+	}
+	if n <= 0 {
+		return []string{}, nil
+	}
+
+	args := make([]string, 0, min(n, 1024))
+	for range n {
+		arg, err := r.readBulk()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+func (r *Reader) readBulk() (string, error) {
+	line, err := r.readLine(r.MaxInlineLen, "too big bulk count string")
+	if err != nil {
+		return "", unexpected(err)
+	}
+	if len(line) == 0 {
+		return "", protoErr("expected '$', got end of line")
+	}
+	if line[0] != '$' {
+		return "", protoErr("expected '$', got '%c'", line[0])
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n < 0 || n > r.MaxBulkLen {
+		return "", protoErr("invalid bulk length")
+	}
+
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return "", unexpected(err)
+	}
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		return "", protoErr("expected CRLF after bulk string")
+	}
+	return string(buf[:n]), nil
+}
+
+func (r *Reader) readInline() ([]string, error) {
+	line, err := r.readLine(r.MaxInlineLen, "too big inline request")
+	if err != nil {
+		return nil, err
+	}
+	return splitInline(string(line))
+}
+
+// readLine reads up to and including '\n' and returns the line without its
+// terminator (\r\n or a bare \n). Lines longer than limit are rejected.
+func (r *Reader) readLine(limit int, tooBig string) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.r.ReadSlice('\n')
+		if len(line)+len(chunk) > limit+2 {
+			return nil, protoErr("%s", tooBig)
+		}
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		break
+	}
+	return bytes.TrimSuffix(line[:len(line)-1], []byte{'\r'}), nil
+}
+
+// unexpected turns a clean EOF into io.ErrUnexpectedEOF; inside a request
+// the peer hanging up is never a clean boundary.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Watch reads ahead until reading fails, returning the error, e.g. to
+// notice a client hanging up while nothing is reading its commands. Input
+// that arrives meanwhile stays buffered for ReadCommand; once the buffer is
+// full Watch stops reading and returns nil.
+func (r *Reader) Watch() error {
+	for {
+		_, err := r.r.Peek(r.r.Buffered() + 1)
+		if err == bufio.ErrBufferFull {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Buffered returns the number of bytes read from the underlying reader but
+// not yet consumed by ReadCommand.
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
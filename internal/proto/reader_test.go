@@ -0,0 +1,130 @@
+package proto_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/proto"
+)
+
+func TestReadCommand_Multibulk(t *testing.T) {
+	r := proto.NewReader(strings.NewReader("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$5\r\nvalue\r\n"))
+	got, err := r.ReadCommand()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"SET", "k", "value"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if _, err := r.ReadCommand(); err != io.EOF {
+		t.Fatalf("expected io.EOF after last command, got %v", err)
+	}
+}
+
+func TestReadCommand_Inline(t *testing.T) {
+	r := proto.NewReader(strings.NewReader("ECHO  hi\r\nPING\n"))
+	for _, want := range [][]string{{"ECHO", "hi"}, {"PING"}} {
+		got, err := r.ReadCommand()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestReadCommand_Empty(t *testing.T) {
+	for _, in := range []string{"\r\n", "*0\r\n", "*-1\r\n"} {
+		got, err := proto.NewReader(strings.NewReader(in)).ReadCommand()
+		if err != nil || len(got) != 0 {
+			t.Fatalf("%q: got (%q, %v), want empty command", in, got, err)
+		}
+	}
+}
+
+func TestReadCommand_Pipelined(t *testing.T) {
+	r := proto.NewReader(strings.NewReader("*1\r\n$4\r\nPING\r\nPING\r\n*2\r\n$4\r\nECHO\r\n$0\r\n\r\n"))
+	want := [][]string{{"PING"}, {"PING"}, {"ECHO", ""}}
+	for _, w := range want {
+		got, err := r.ReadCommand()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Fatalf("got %q, want %q", got, w)
+		}
+	}
+}
+
+func TestReadCommand_ProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bad multibulk length", "*x\r\n", "Protocol error: invalid multibulk length"},
+		{"missing dollar", "*1\r\n:3\r\n", "Protocol error: expected '$', got ':'"},
+		{"bad bulk length", "*1\r\n$abc\r\n", "Protocol error: invalid bulk length"},
+		{"negative bulk length", "*1\r\n$-1\r\n", "Protocol error: invalid bulk length"},
+		{"missing bulk terminator", "*1\r\n$2\r\nhiXX", "Protocol error: expected CRLF after bulk string"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := proto.NewReader(strings.NewReader(tc.in)).ReadCommand()
+			var perr *proto.ProtocolError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected *ProtocolError, got %v", err)
+			}
+			if err.Error() != tc.want {
+				t.Fatalf("got %q, want %q", err.Error(), tc.want)
+			}
+		})
+	}
+}
+
+func TestReadCommand_Limits(t *testing.T) {
+	r := proto.NewReader(strings.NewReader("*3\r\n"))
+	r.MaxArrayLen = 2
+	if _, err := r.ReadCommand(); err == nil || err.Error() != "Protocol error: invalid multibulk length" {
+		t.Fatalf("array limit: got %v", err)
+	}
+
+	r = proto.NewReader(strings.NewReader("*1\r\n$11\r\nhello world\r\n"))
+	r.MaxBulkLen = 10
+	if _, err := r.ReadCommand(); err == nil || err.Error() != "Protocol error: invalid bulk length" {
+		t.Fatalf("bulk limit: got %v", err)
+	}
+
+	r = proto.NewReader(strings.NewReader(strings.Repeat("a", 100) + "\r\n"))
+	r.MaxInlineLen = 10
+	if _, err := r.ReadCommand(); err == nil || err.Error() != "Protocol error: too big inline request" {
+		t.Fatalf("inline limit: got %v", err)
+	}
+}
+
+func TestReadCommand_TruncatedInput(t *testing.T) {
+	for _, in := range []string{"*2\r\n$4\r\nECHO\r\n", "*1\r\n$4\r\nEC", "*1\r\n$4"} {
+		_, err := proto.NewReader(strings.NewReader(in)).ReadCommand()
+		if err != io.ErrUnexpectedEOF {
+			t.Fatalf("%q: got %v, want io.ErrUnexpectedEOF", in, err)
+		}
+	}
+}
+
+func TestReadCommand_BinarySafeBulk(t *testing.T) {
+	for b := range 256 {
+		v := string([]byte{'<', byte(b), '>'})
+		in := "*2\r\n$4\r\nECHO\r\n$3\r\n" + v + "\r\n"
+		got, err := proto.NewReader(strings.NewReader(in)).ReadCommand()
+		if err != nil {
+			t.Fatalf("byte %#02x: unexpected error: %v", b, err)
+		}
+		if len(got) != 2 || got[1] != v {
+			t.Fatalf("byte %#02x: got %q, want [ECHO %q]", b, got, v)
+		}
+	}
+}
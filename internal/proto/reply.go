@@ -3,25 +3,28 @@ package proto
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
+// simple strings and errors can't carry CR or LF, so they're flattened to
+// spaces the same way redis does for error messages.
+var lineSafe = strings.NewReplacer("\r", " ", "\n", " ")
+
 func OK(w io.Writer) error {
-	_, err := fmt.Fprint(w, "+OK\r\n")
-	return err
+	return Simple(w, "OK")
 }
 
-func Err(w io.Writer, msg string) error {
-	_, err := fmt.Fprintf(w, "-ERR %s\r\n", msg)
-	return err
+func PONG(w io.Writer) error {
+	return Simple(w, "PONG")
 }
 
-func PONG(w io.Writer) error {
-	_, err := fmt.Fprint(w, "+PONG\r\n")
+func Simple(w io.Writer, s string) error {
+	_, err := fmt.Fprintf(w, "+%s\r\n", lineSafe.Replace(s))
 	return err
 }
 
-func Line(w io.Writer, text string) error {
-	_, err := fmt.Fprintf(w, "%s\r\n", text)
+func Err(w io.Writer, msg string) error {
+	_, err := fmt.Fprintf(w, "-ERR %s\r\n", lineSafe.Replace(msg))
 	return err
 }
 
@@ -30,7 +33,42 @@ func Bulk(w io.Writer, s string) error {
 	return err
 }
 
+func NullBulk(w io.Writer) error {
+	if resp3(w) {
+		return Null(w)
+	}
+	_, err := fmt.Fprint(w, "$-1\r\n")
+	return err
+}
+
 func Int(w io.Writer, n int64) error {
-	_, err := fmt.Fprintf(w, "%d\r\n", n)
+	_, err := fmt.Fprintf(w, ":%d\r\n", n)
 	return err
 }
+
+// Array writes the header of an n element array; the caller writes the
+// elements right after it.
+func Array(w io.Writer, n int) error {
+	_, err := fmt.Fprintf(w, "*%d\r\n", n)
+	return err
+}
+
+func NullArray(w io.Writer) error {
+	if resp3(w) {
+		return Null(w)
+	}
+	_, err := fmt.Fprint(w, "*-1\r\n")
+	return err
+}
+
+func BulkArray(w io.Writer, items []string) error {
+	if err := Array(w, len(items)); err != nil {
+		return err
+	}
+	for _, s := range items {
+		if err := Bulk(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
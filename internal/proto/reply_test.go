@@ -32,13 +32,22 @@ func TestPONG(t *testing.T) {
 	}
 }
 
-func TestLine(t *testing.T) {
+func TestSimple(t *testing.T) {
 	var buf bytes.Buffer
-	if err := proto.Line(&buf, "hello"); err != nil {
+	if err := proto.Simple(&buf, "hello"); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if got, want := buf.String(), "hello\r\n"; got != want {
-		t.Errorf("proto.Line() wrote %q, want %q", got, want)
+	if got, want := buf.String(), "+hello\r\n"; got != want {
+		t.Errorf("proto.Simple() wrote %q, want %q", got, want)
+	}
+}
+
+func TestSimpleAndErrStripCRLF(t *testing.T) {
+	var buf bytes.Buffer
+	_ = proto.Simple(&buf, "a\r\nb")
+	_ = proto.Err(&buf, "c\nd")
+	if got, want := buf.String(), "+a  b\r\n-ERR c d\r\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
 	}
 }
 
@@ -58,9 +67,9 @@ func TestInt(t *testing.T) {
 		in   int64
 		want string
 	}{
-		{"zero", 0, "0\r\n"},
-		{"positive", 42, "42\r\n"},
-		{"negative", -2, "-2\r\n"},
+		{"zero", 0, ":0\r\n"},
+		{"positive", 42, ":42\r\n"},
+		{"negative", -2, ":-2\r\n"},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -96,3 +105,59 @@ func TestBulkUTF8(t *testing.T) {
 	}
 }
 
+func TestBulkAllBytes(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+
+	var buf bytes.Buffer
+	if err := proto.Bulk(&buf, string(all)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "$256\r\n"+string(all)+"\r\n"; got != want {
+		t.Errorf("proto.Bulk() wrote %q, want %q", got, want)
+	}
+}
+
+func TestNullBulk(t *testing.T) {
+	var buf bytes.Buffer
+	if err := proto.NullBulk(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "$-1\r\n"; got != want {
+		t.Errorf("proto.NullBulk() wrote %q, want %q", got, want)
+	}
+}
+
+func TestArray(t *testing.T) {
+	var buf bytes.Buffer
+	if err := proto.Array(&buf, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = proto.Bulk(&buf, "a")
+	_ = proto.NullBulk(&buf)
+	if got, want := buf.String(), "*2\r\n$1\r\na\r\n$-1\r\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNullArray(t *testing.T) {
+	var buf bytes.Buffer
+	if err := proto.NullArray(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "*-1\r\n"; got != want {
+		t.Errorf("proto.NullArray() wrote %q, want %q", got, want)
+	}
+}
+
+func TestBulkArray(t *testing.T) {
+	var buf bytes.Buffer
+	if err := proto.BulkArray(&buf, []string{"x", ""}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "*2\r\n$1\r\nx\r\n$0\r\n\r\n"; got != want {
+		t.Errorf("proto.BulkArray() wrote %q, want %q", got, want)
+	}
+}
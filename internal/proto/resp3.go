@@ -0,0 +1,147 @@
+package proto
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Writers that pass a *command.Client (or anything else with a Protocol
+// method) get RESP3 encodings once HELLO 3 has been negotiated. Everything
+// else is treated as RESP2, where each RESP3 type falls back to its closest
+// RESP2 form.
+type protocoler interface {
+	Protocol() int
+}
+
+func Protocol(w io.Writer) int {
+	if p, ok := w.(protocoler); ok {
+		return p.Protocol()
+	}
+	return 2
+}
+
+func resp3(w io.Writer) bool {
+	return Protocol(w) >= 3
+}
+
+// Error writes an error whose first word is its own code, e.g.
+// "NOPROTO unsupported protocol version".
+func Error(w io.Writer, msg string) error {
+	_, err := fmt.Fprintf(w, "-%s\r\n", lineSafe.Replace(msg))
+	return err
+}
+
+func Null(w io.Writer) error {
+	if resp3(w) {
+		_, err := fmt.Fprint(w, "_\r\n")
+		return err
+	}
+	return NullBulk(w)
+}
+
+// Map writes the header of an n pair map; the caller writes 2*n elements.
+// Under RESP2 it's a flat array of alternating keys and values.
+func Map(w io.Writer, n int) error {
+	if resp3(w) {
+		_, err := fmt.Fprintf(w, "%%%d\r\n", n)
+		return err
+	}
+	return Array(w, 2*n)
+}
+
+func Set(w io.Writer, n int) error {
+	if resp3(w) {
+		_, err := fmt.Fprintf(w, "~%d\r\n", n)
+		return err
+	}
+	return Array(w, n)
+}
+
+// Push writes the header of an out-of-band push message, as used for pubsub
+// deliveries. Under RESP2 pushes are plain arrays.
+func Push(w io.Writer, n int) error {
+	if resp3(w) {
+		_, err := fmt.Fprintf(w, ">%d\r\n", n)
+		return err
+	}
+	return Array(w, n)
+}
+
+func Double(w io.Writer, f float64) error {
+	s := FormatFloat(f)
+	if resp3(w) {
+		_, err := fmt.Fprintf(w, ",%s\r\n", s)
+		return err
+	}
+	return Bulk(w, s)
+}
+
+func Bool(w io.Writer, b bool) error {
+	if resp3(w) {
+		c := 'f'
+		if b {
+			c = 't'
+		}
+		_, err := fmt.Fprintf(w, "#%c\r\n", c)
+		return err
+	}
+	if b {
+		return Int(w, 1)
+	}
+	return Int(w, 0)
+}
+
+// BigNumber writes an arbitrary precision integer given in decimal form.
+func BigNumber(w io.Writer, n string) error {
+	if resp3(w) {
+		_, err := fmt.Fprintf(w, "(%s\r\n", n)
+		return err
+	}
+	return Bulk(w, n)
+}
+
+// Verbatim writes text tagged with a three letter format such as "txt" or
+// "mkd". Any other format is an error, and nothing is written.
+func Verbatim(w io.Writer, format, s string) error {
+	if len(format) != 3 {
+		return fmt.Errorf("verbatim format %q is not 3 bytes long", format)
+	}
+	if resp3(w) {
+		_, err := fmt.Fprintf(w, "=%d\r\n%s:%s\r\n", len(s)+4, format, s)
+		return err
+	}
+	return Bulk(w, s)
+}
+
+// Attribute writes key/value metadata that precedes the next reply. RESP2
+// has no equivalent, so nothing is written there.
+func Attribute(w io.Writer, kv ...string) error {
+	if !resp3(w) {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "|%d\r\n", len(kv)/2); err != nil {
+		return err
+	}
+	for _, s := range kv[:len(kv)/2*2] {
+		if err := Bulk(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatFloat renders f the way redis does in replies: shortest exact
+// representation, with inf/-inf/nan spelled out.
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,79 @@
+package proto_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/proto"
+)
+
+type resp3Writer struct{ bytes.Buffer }
+
+func (*resp3Writer) Protocol() int { return 3 }
+
+func TestRESP3Writers(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w io.Writer) error
+		resp2 string
+		resp3 string
+	}{
+		{"null", proto.Null, "$-1\r\n", "_\r\n"},
+		{"null bulk", proto.NullBulk, "$-1\r\n", "_\r\n"},
+		{"null array", proto.NullArray, "*-1\r\n", "_\r\n"},
+		{"map", func(w io.Writer) error { return proto.Map(w, 2) }, "*4\r\n", "%2\r\n"},
+		{"set", func(w io.Writer) error { return proto.Set(w, 3) }, "*3\r\n", "~3\r\n"},
+		{"push", func(w io.Writer) error { return proto.Push(w, 3) }, "*3\r\n", ">3\r\n"},
+		{"double", func(w io.Writer) error { return proto.Double(w, 1.5) }, "$3\r\n1.5\r\n", ",1.5\r\n"},
+		{"double inf", func(w io.Writer) error { return proto.Double(w, math.Inf(-1)) }, "$4\r\n-inf\r\n", ",-inf\r\n"},
+		{"bool true", func(w io.Writer) error { return proto.Bool(w, true) }, ":1\r\n", "#t\r\n"},
+		{"bool false", func(w io.Writer) error { return proto.Bool(w, false) }, ":0\r\n", "#f\r\n"},
+		{"big number", func(w io.Writer) error { return proto.BigNumber(w, "123456789012345678901234567890") },
+			"$30\r\n123456789012345678901234567890\r\n", "(123456789012345678901234567890\r\n"},
+		{"verbatim", func(w io.Writer) error { return proto.Verbatim(w, "txt", "hi") }, "$2\r\nhi\r\n", "=6\r\ntxt:hi\r\n"},
+		{"attribute", func(w io.Writer) error { return proto.Attribute(w, "ttl", "5") }, "", "|1\r\n$3\r\nttl\r\n$1\r\n5\r\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var v2 bytes.Buffer
+			if err := tc.write(&v2); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := v2.String(); got != tc.resp2 {
+				t.Errorf("RESP2 wrote %q, want %q", got, tc.resp2)
+			}
+
+			var v3 resp3Writer
+			if err := tc.write(&v3); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := v3.String(); got != tc.resp3 {
+				t.Errorf("RESP3 wrote %q, want %q", got, tc.resp3)
+			}
+		})
+	}
+}
+
+func TestVerbatim_FormatMustBeThreeBytes(t *testing.T) {
+	for _, format := range []string{"", "md", "text"} {
+		var w resp3Writer
+		if err := proto.Verbatim(&w, format, "hi"); err == nil {
+			t.Errorf("format %q: expected an error", format)
+		}
+		if w.Len() != 0 {
+			t.Errorf("format %q: wrote %q", format, w.String())
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	var buf bytes.Buffer
+	if err := proto.Error(&buf, "NOPROTO unsupported protocol version"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "-NOPROTO unsupported protocol version\r\n"; got != want {
+		t.Errorf("proto.Error() wrote %q, want %q", got, want)
+	}
+}
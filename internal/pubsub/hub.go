@@ -0,0 +1,284 @@
+// Package pubsub fans out messages published on a channel to everyone
+// subscribed to it, directly or through a glob pattern. Publishers never
+// wait on subscribers: each subscriber has a bounded queue, and one that
+// falls too far behind is dropped instead.
+package pubsub
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/amir-aharon/goliath/internal/glob"
+	"github.com/amir-aharon/goliath/internal/proto"
+)
+
+// DefaultQueueLen is how many undelivered messages a subscriber may have
+// pending before it's dropped.
+const DefaultQueueLen = 1024
+
+type Message struct {
+	Channel string
+	Payload string
+
+	// set for deliveries through a pattern subscription
+	ByPattern bool
+	Pattern   string
+}
+
+// Encode writes m as a "message" or "pmessage" frame: a push under RESP3, a
+// plain array under RESP2.
+func (m Message) Encode(w io.Writer) error {
+	if m.ByPattern {
+		if err := proto.Push(w, 4); err != nil {
+			return err
+		}
+		_ = proto.Bulk(w, "pmessage")
+		_ = proto.Bulk(w, m.Pattern)
+	} else {
+		if err := proto.Push(w, 3); err != nil {
+			return err
+		}
+		_ = proto.Bulk(w, "message")
+	}
+	_ = proto.Bulk(w, m.Channel)
+	return proto.Bulk(w, m.Payload)
+}
+
+type Hub struct {
+	// QueueLen bounds each subscriber's queue; set it before the first
+	// NewSubscriber call.
+	QueueLen int
+
+	mu       sync.RWMutex
+	channels map[string]map[*Subscriber]struct{}
+	patterns map[string]map[*Subscriber]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		QueueLen: DefaultQueueLen,
+		channels: make(map[string]map[*Subscriber]struct{}),
+		patterns: make(map[string]map[*Subscriber]struct{}),
+	}
+}
+
+// Publish queues payload for every subscriber of channel and every pattern
+// matching it, and returns how many messages were queued; a client
+// subscribed both ways receives, and counts, one of each. Subscribers whose
+// queue is full are dropped.
+func (h *Hub) Publish(channel, payload string) int {
+	var n int
+	var slow []*Subscriber
+	send := func(subs map[*Subscriber]struct{}, m Message) {
+		for s := range subs {
+			select {
+			case s.msgs <- m:
+				n++
+			default:
+				slow = append(slow, s)
+			}
+		}
+	}
+
+	h.mu.RLock()
+	send(h.channels[channel], Message{Channel: channel, Payload: payload})
+	for pat, subs := range h.patterns {
+		if glob.Match(pat, channel) {
+			send(subs, Message{Channel: channel, Payload: payload, ByPattern: true, Pattern: pat})
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		s.close(true)
+	}
+	return n
+}
+
+// Channels returns the channels with at least one subscriber, sorted. A
+// non-empty pattern limits them to the ones matching it. Pattern
+// subscriptions don't count.
+func (h *Hub) Channels(pattern string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var out []string
+	for ch := range h.channels {
+		if pattern == "" || glob.Match(pattern, ch) {
+			out = append(out, ch)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NumSub returns the number of subscribers of channel, not counting
+// pattern subscribers.
+func (h *Hub) NumSub(channel string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.channels[channel])
+}
+
+// NumPat returns the number of distinct patterns subscribed to.
+func (h *Hub) NumPat() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.patterns)
+}
+
+// Subscriber is one client's set of subscriptions and its message queue.
+// Its methods are safe to call concurrently with Publish.
+type Subscriber struct {
+	hub  *Hub
+	msgs chan Message
+
+	// guarded by hub.mu
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	closed   bool
+	dropped  bool
+	done     chan struct{}
+}
+
+func (h *Hub) NewSubscriber() *Subscriber {
+	return &Subscriber{
+		hub:      h,
+		msgs:     make(chan Message, h.QueueLen),
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Messages delivers queued messages in publish order.
+func (s *Subscriber) Messages() <-chan Message {
+	return s.msgs
+}
+
+// Done is closed once the subscriber is closed or dropped; after that no
+// more messages are queued.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped reports whether the subscriber was closed for falling behind.
+func (s *Subscriber) Dropped() bool {
+	s.hub.mu.RLock()
+	defer s.hub.mu.RUnlock()
+	return s.dropped
+}
+
+// Subscribe adds channel and returns the subscriber's subscription count,
+// channels and patterns together. Subscribing twice is a no-op.
+func (s *Subscriber) Subscribe(channel string) int {
+	return s.add(s.channels, s.hub.channels, channel)
+}
+
+// Unsubscribe removes channel and returns the remaining subscription count.
+func (s *Subscriber) Unsubscribe(channel string) int {
+	return s.remove(s.channels, s.hub.channels, channel)
+}
+
+// PSubscribe adds a glob pattern and returns the subscription count.
+func (s *Subscriber) PSubscribe(pattern string) int {
+	return s.add(s.patterns, s.hub.patterns, pattern)
+}
+
+// PUnsubscribe removes pattern and returns the remaining subscription count.
+func (s *Subscriber) PUnsubscribe(pattern string) int {
+	return s.remove(s.patterns, s.hub.patterns, pattern)
+}
+
+func (s *Subscriber) add(own map[string]struct{}, all map[string]map[*Subscriber]struct{}, name string) int {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	if s.closed {
+		return 0
+	}
+	if _, ok := own[name]; !ok {
+		own[name] = struct{}{}
+		subs := all[name]
+		if subs == nil {
+			subs = make(map[*Subscriber]struct{})
+			all[name] = subs
+		}
+		subs[s] = struct{}{}
+	}
+	return len(s.channels) + len(s.patterns)
+}
+
+func (s *Subscriber) remove(own map[string]struct{}, all map[string]map[*Subscriber]struct{}, name string) int {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	unsubscribe(s, own, all, name)
+	return len(s.channels) + len(s.patterns)
+}
+
+func unsubscribe(s *Subscriber, own map[string]struct{}, all map[string]map[*Subscriber]struct{}, name string) {
+	if _, ok := own[name]; !ok {
+		return
+	}
+	delete(own, name)
+	subs := all[name]
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(all, name)
+	}
+}
+
+// Channels returns the subscribed channels, sorted.
+func (s *Subscriber) Channels() []string {
+	return s.list(s.channels)
+}
+
+// Patterns returns the subscribed patterns, sorted.
+func (s *Subscriber) Patterns() []string {
+	return s.list(s.patterns)
+}
+
+func (s *Subscriber) list(own map[string]struct{}) []string {
+	s.hub.mu.RLock()
+	defer s.hub.mu.RUnlock()
+
+	out := make([]string, 0, len(own))
+	for name := range own {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Count returns the number of active subscriptions, channels and patterns
+// together.
+func (s *Subscriber) Count() int {
+	s.hub.mu.RLock()
+	defer s.hub.mu.RUnlock()
+	return len(s.channels) + len(s.patterns)
+}
+
+// Close drops every subscription. It's safe to call more than once.
+func (s *Subscriber) Close() {
+	s.close(false)
+}
+
+func (s *Subscriber) close(dropped bool) {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	for ch := range s.channels {
+		unsubscribe(s, s.channels, h.channels, ch)
+	}
+	for pat := range s.patterns {
+		unsubscribe(s, s.patterns, h.patterns, pat)
+	}
+	s.closed, s.dropped = true, dropped
+	close(s.done)
+}
@@ -0,0 +1,207 @@
+package pubsub_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/pubsub"
+)
+
+func TestHub_PublishReachesSubscribers(t *testing.T) {
+	h := pubsub.NewHub()
+	a, b := h.NewSubscriber(), h.NewSubscriber()
+	a.Subscribe("news")
+	b.Subscribe("news")
+	b.Subscribe("other")
+
+	if n := h.Publish("news", "hi"); n != 2 {
+		t.Fatalf("Publish: got %d receivers, want 2", n)
+	}
+	if n := h.Publish("nobody", "hi"); n != 0 {
+		t.Fatalf("Publish to unsubscribed channel: got %d, want 0", n)
+	}
+
+	want := pubsub.Message{Channel: "news", Payload: "hi"}
+	for _, s := range []*pubsub.Subscriber{a, b} {
+		if got := <-s.Messages(); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestSubscriber_Counts(t *testing.T) {
+	h := pubsub.NewHub()
+	s := h.NewSubscriber()
+
+	if n := s.Subscribe("b"); n != 1 {
+		t.Fatalf("first subscribe: got %d", n)
+	}
+	if n := s.Subscribe("b"); n != 1 {
+		t.Fatalf("repeat subscribe: got %d", n)
+	}
+	if n := s.Subscribe("a"); n != 2 {
+		t.Fatalf("second channel: got %d", n)
+	}
+	if got := s.Channels(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Channels: got %q", got)
+	}
+	if n := s.Unsubscribe("nope"); n != 2 {
+		t.Fatalf("unsubscribe unknown: got %d", n)
+	}
+	if n := s.Unsubscribe("b"); n != 1 {
+		t.Fatalf("unsubscribe: got %d", n)
+	}
+	if n := h.Publish("b", "x"); n != 0 {
+		t.Fatalf("publish after unsubscribe: got %d receivers", n)
+	}
+}
+
+func TestSubscriber_DroppedWhenQueueFull(t *testing.T) {
+	h := pubsub.NewHub()
+	h.QueueLen = 2
+	slow, fast := h.NewSubscriber(), h.NewSubscriber()
+	slow.Subscribe("ch")
+	fast.Subscribe("ch")
+
+	for i := 0; i < 2; i++ {
+		h.Publish("ch", "m")
+		<-fast.Messages()
+	}
+	if n := h.Publish("ch", "m"); n != 1 {
+		t.Fatalf("overflowing publish: got %d receivers, want 1", n)
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatalf("slow subscriber not dropped")
+	}
+	if !slow.Dropped() || slow.Count() != 0 {
+		t.Fatalf("dropped=%v count=%d, want dropped with no subscriptions", slow.Dropped(), slow.Count())
+	}
+	if slow.Subscribe("ch") != 0 {
+		t.Fatalf("subscribe after drop should be ignored")
+	}
+	if n := h.Publish("ch", "m"); n != 1 {
+		t.Fatalf("publish after drop: got %d receivers, want 1", n)
+	}
+}
+
+func TestSubscriber_Close(t *testing.T) {
+	h := pubsub.NewHub()
+	s := h.NewSubscriber()
+	s.Subscribe("ch")
+	s.Close()
+	s.Close()
+
+	if s.Dropped() {
+		t.Fatalf("Close should not count as a drop")
+	}
+	if n := h.Publish("ch", "m"); n != 0 {
+		t.Fatalf("publish after close: got %d receivers", n)
+	}
+}
+
+type resp3Writer struct{ bytes.Buffer }
+
+func (*resp3Writer) Protocol() int { return 3 }
+
+func TestMessage_Encode(t *testing.T) {
+	m := pubsub.Message{Channel: "ch", Payload: "hi"}
+
+	var buf bytes.Buffer
+	if err := m.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "*3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n"; got != want {
+		t.Fatalf("RESP2: got %q, want %q", got, want)
+	}
+
+	var w3 resp3Writer
+	if err := m.Encode(&w3); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := w3.String(), ">3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n"; got != want {
+		t.Fatalf("RESP3: got %q, want %q", got, want)
+	}
+}
+
+func TestHub_PatternSubscriptions(t *testing.T) {
+	h := pubsub.NewHub()
+	s := h.NewSubscriber()
+	if n := s.Subscribe("orders.1.created"); n != 1 {
+		t.Fatalf("Subscribe: got %d", n)
+	}
+	if n := s.PSubscribe("orders.*.created"); n != 2 {
+		t.Fatalf("PSubscribe: got %d, want channels and patterns counted together", n)
+	}
+
+	// one delivery per matching subscription
+	if n := h.Publish("orders.1.created", "x"); n != 2 {
+		t.Fatalf("Publish: got %d receivers, want 2", n)
+	}
+	if got, want := <-s.Messages(), (pubsub.Message{Channel: "orders.1.created", Payload: "x"}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	want := pubsub.Message{Channel: "orders.1.created", Payload: "x", ByPattern: true, Pattern: "orders.*.created"}
+	if got := <-s.Messages(); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	if n := h.Publish("orders.1.deleted", "x"); n != 0 {
+		t.Fatalf("non-matching publish: got %d receivers", n)
+	}
+	if n := s.PUnsubscribe("orders.*.created"); n != 1 {
+		t.Fatalf("PUnsubscribe: got %d", n)
+	}
+	if n := h.Publish("orders.2.created", "x"); n != 0 {
+		t.Fatalf("publish after PUnsubscribe: got %d receivers", n)
+	}
+}
+
+func TestHub_Introspection(t *testing.T) {
+	h := pubsub.NewHub()
+	a, b := h.NewSubscriber(), h.NewSubscriber()
+	a.Subscribe("news.tech")
+	a.Subscribe("news.art")
+	b.Subscribe("news.tech")
+	b.Subscribe("weather")
+	a.PSubscribe("news.*")
+	b.PSubscribe("news.*")
+	b.PSubscribe("w*")
+
+	if got := h.Channels(""); !reflect.DeepEqual(got, []string{"news.art", "news.tech", "weather"}) {
+		t.Fatalf("Channels: got %q", got)
+	}
+	if got := h.Channels("news.*"); !reflect.DeepEqual(got, []string{"news.art", "news.tech"}) {
+		t.Fatalf("Channels(news.*): got %q", got)
+	}
+	if n := h.NumSub("news.tech"); n != 2 {
+		t.Fatalf("NumSub: got %d", n)
+	}
+	if n := h.NumPat(); n != 2 {
+		t.Fatalf("NumPat: got %d, want distinct patterns", n)
+	}
+
+	b.Close()
+	if n, pats := h.NumSub("news.tech"), h.NumPat(); n != 1 || pats != 1 {
+		t.Fatalf("after Close: NumSub=%d NumPat=%d", n, pats)
+	}
+	if got := h.Channels(""); !reflect.DeepEqual(got, []string{"news.art", "news.tech"}) {
+		t.Fatalf("Channels after Close: got %q", got)
+	}
+}
+
+func TestMessage_EncodePattern(t *testing.T) {
+	m := pubsub.Message{Channel: "a.b", Payload: "hi", ByPattern: true, Pattern: "a.*"}
+
+	var buf bytes.Buffer
+	if err := m.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "*4\r\n$8\r\npmessage\r\n$3\r\na.*\r\n$3\r\na.b\r\n$2\r\nhi\r\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+package rdb
+
+// Redis checksums RDB files with CRC-64/Jones (reflected, zero init, no
+// final xor), which hash/crc64 can't express since it always inverts the
+// register.
+var crcTable = func() (t [256]uint64) {
+	const poly = 0x95ac9329ac4bc9b5 // 0xad93d23594c935a9 reflected
+	for i := range t {
+		crc := uint64(i)
+		for range 8 {
+			if crc&1 == 1 {
+				crc = crc>>1 ^ poly
+			} else {
+				crc >>= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}()
+
+func crc64(crc uint64, p []byte) uint64 {
+	for _, b := range p {
+		crc = crcTable[byte(crc)^b] ^ crc>>8
+	}
+	return crc
+}
+
+// crcWriter is an io.Writer that only updates a running checksum.
+type crcWriter struct{ sum uint64 }
+
+func (w *crcWriter) Write(p []byte) (int, error) {
+	w.sum = crc64(w.sum, p)
+	return len(p), nil
+}
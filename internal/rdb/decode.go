@@ -0,0 +1,712 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// sanity bound on lengths read from the file, same as the max bulk length
+const maxLen = 512 << 20
+
+var (
+	ErrBadMagic    = errors.New("rdb: not an RDB file")
+	ErrBadVersion  = errors.New("rdb: unsupported RDB version")
+	ErrBadChecksum = errors.New("rdb: checksum mismatch")
+)
+
+// Skipped is a key of a type goliath can't store.
+type Skipped struct {
+	DB   int
+	Key  string
+	Type string
+}
+
+// Dump is the loadable content of an RDB file.
+type Dump struct {
+	Version int
+	DBs     map[int][]store.Entry
+	Skipped []Skipped
+}
+
+type decoder struct {
+	r   *bufio.Reader
+	crc uint64
+	buf [8]byte
+}
+
+func (d *decoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	d.buf[0] = b
+	d.crc = crc64(d.crc, d.buf[:1])
+	return b, nil
+}
+
+func (d *decoder) readFull(n int) ([]byte, error) {
+	p := make([]byte, n)
+	if _, err := io.ReadFull(d.r, p); err != nil {
+		return nil, unexpected(err)
+	}
+	d.crc = crc64(d.crc, p)
+	return p, nil
+}
+
+func (d *decoder) readUint64() (uint64, error) {
+	p, err := d.readFull(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(p), nil
+}
+
+// readLen decodes a length. special reports the 0b11 prefix, in which case
+// n is the encoding of an integer or compressed string instead.
+func (d *decoder) readLen() (n uint64, special bool, err error) {
+	b, err := d.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		b2, err := d.readByte()
+		return uint64(b&0x3f)<<8 | uint64(b2), false, err
+	case 2:
+		switch b {
+		case 0x80:
+			p, err := d.readFull(4)
+			if err != nil {
+				return 0, false, err
+			}
+			return uint64(binary.BigEndian.Uint32(p)), false, nil
+		case 0x81:
+			p, err := d.readFull(8)
+			if err != nil {
+				return 0, false, err
+			}
+			return binary.BigEndian.Uint64(p), false, nil
+		}
+		return 0, false, fmt.Errorf("rdb: bad length encoding %#x", b)
+	}
+	return uint64(b & 0x3f), true, nil
+}
+
+func (d *decoder) len() (uint64, error) {
+	n, special, err := d.readLen()
+	if err == nil && special {
+		err = errors.New("rdb: unexpected encoded length")
+	}
+	return n, err
+}
+
+func (d *decoder) readString() (string, error) {
+	n, special, err := d.readLen()
+	if err != nil {
+		return "", err
+	}
+
+	if special {
+		switch n {
+		case 0:
+			b, err := d.readFull(1)
+			if err != nil {
+				return "", err
+			}
+			return strconv.Itoa(int(int8(b[0]))), nil
+		case 1:
+			b, err := d.readFull(2)
+			if err != nil {
+				return "", err
+			}
+			return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(b)))), nil
+		case 2:
+			b, err := d.readFull(4)
+			if err != nil {
+				return "", err
+			}
+			return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(b)))), nil
+		case 3:
+			clen, err := d.len()
+			if err != nil {
+				return "", err
+			}
+			ulen, err := d.len()
+			if err != nil {
+				return "", err
+			}
+			if clen > maxLen || ulen > maxLen {
+				return "", errors.New("rdb: compressed string too long")
+			}
+			c, err := d.readFull(int(clen))
+			if err != nil {
+				return "", err
+			}
+			s, err := lzfDecompress(c, int(ulen))
+			return string(s), err
+		}
+		return "", fmt.Errorf("rdb: unknown string encoding %d", n)
+	}
+
+	if n > maxLen {
+		return "", fmt.Errorf("rdb: string length %d out of range", n)
+	}
+	b, err := d.readFull(int(n))
+	return string(b), err
+}
+
+// Decode reads an RDB file. Keys whose type goliath can't represent are
+// listed in Dump.Skipped; data that can't even be skipped (pre-release
+// module or hash formats, unknown types) fails the whole decode.
+func Decode(r io.Reader) (*Dump, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+
+	hdr, err := d.readFull(9)
+	if err != nil {
+		return nil, err
+	}
+	if string(hdr[:5]) != "REDIS" {
+		return nil, ErrBadMagic
+	}
+	ver, err := strconv.Atoi(string(hdr[5:]))
+	if err != nil {
+		return nil, ErrBadMagic
+	}
+	if ver < 1 || ver > MaxVersion {
+		return nil, fmt.Errorf("%w %d", ErrBadVersion, ver)
+	}
+
+	dump := &Dump{Version: ver, DBs: make(map[int][]store.Entry)}
+	var (
+		db     int
+		expiry time.Time
+	)
+	for {
+		op, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case opEOF:
+			if ver < 5 {
+				return dump, nil
+			}
+			want := d.crc
+			got, err := d.readUint64()
+			if err != nil {
+				return nil, err
+			}
+			if got != 0 && got != want { // 0 means checksums were disabled
+				return nil, ErrBadChecksum
+			}
+			return dump, nil
+
+		case opSelectDB:
+			n, err := d.len()
+			if err != nil {
+				return nil, err
+			}
+			db = int(n)
+
+		case opResizeDB:
+			err = d.skipLens(2)
+		case opSlotInfo:
+			err = d.skipLens(3)
+		case opIdle:
+			err = d.skipLens(1)
+		case opFreq:
+			_, err = d.readByte()
+		case opAux:
+			err = d.skipStrings(2)
+		case opFunction2:
+			err = d.skipStrings(1)
+		case opModuleAux:
+			if err = d.skipLens(3); err == nil {
+				err = d.skipModuleData()
+			}
+		case opFunctionPreGA:
+			return nil, errors.New("rdb: pre-release function format is not supported")
+
+		case opExpireTimeMs:
+			ms, err := d.readUint64()
+			if err != nil {
+				return nil, err
+			}
+			expiry = time.UnixMilli(int64(ms))
+		case opExpireTime:
+			p, err := d.readFull(4)
+			if err != nil {
+				return nil, err
+			}
+			expiry = time.Unix(int64(int32(binary.LittleEndian.Uint32(p))), 0)
+
+		default:
+			key, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case op == typeString:
+				val, err := d.readString()
+				if err != nil {
+					return nil, err
+				}
+				dump.DBs[db] = append(dump.DBs[db], store.Entry{Key: key, Val: val, ExpiresAt: expiry})
+			case typeName(op) == "list":
+				list, err := d.readList(op)
+				if err != nil {
+					return nil, fmt.Errorf("rdb: key %q: %w", key, err)
+				}
+				if len(list) > 0 {
+					dump.DBs[db] = append(dump.DBs[db], store.Entry{Key: key, List: list, ExpiresAt: expiry})
+				}
+			case op == typeHash || op == typeHashZiplist || op == typeHashListpack ||
+				op == typeHashMetadata || op == typeHashListpackEx:
+				hash, err := d.readHash(op)
+				if err != nil {
+					return nil, fmt.Errorf("rdb: key %q: %w", key, err)
+				}
+				if len(hash) > 0 {
+					dump.DBs[db] = append(dump.DBs[db], store.Entry{Key: key, Hash: hash, ExpiresAt: expiry})
+				}
+			default: // including zipmaps
+				if err := d.skipValue(op); err != nil {
+					return nil, fmt.Errorf("rdb: key %q: %w", key, err)
+				}
+				dump.Skipped = append(dump.Skipped, Skipped{DB: db, Key: key, Type: typeName(op)})
+			}
+			expiry = time.Time{}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readList reads a list value in any of the encodings redis has used.
+func (d *decoder) readList(t byte) ([]string, error) {
+	if t == typeListZiplist {
+		blob, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return ziplistEntries([]byte(blob))
+	}
+
+	n, err := d.len()
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for range n {
+		switch t {
+		case typeList:
+			el, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, el)
+			continue
+		case typeListQuicklist2:
+			container, err := d.len()
+			if err != nil {
+				return nil, err
+			}
+			if container == 1 { // a single plain element
+				el, err := d.readString()
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, el)
+				continue
+			}
+		}
+
+		blob, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		var els []string
+		if t == typeListQuicklist {
+			els, err = ziplistEntries([]byte(blob))
+		} else {
+			els, err = listpackEntries([]byte(blob))
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, els...)
+	}
+	return list, nil
+}
+
+// readHash reads a hash value in its plain, ziplist or listpack encoding,
+// or either of the encodings of redis 7.4 that carry field expiries. The
+// packed ones hold fields and values alternating, and with expiries each
+// field's expiry in unix milliseconds after its value, 0 for none.
+func (d *decoder) readHash(t byte) ([]store.HashField, error) {
+	switch t {
+	case typeHash:
+		n, err := d.len()
+		if err != nil {
+			return nil, err
+		}
+		var hash []store.HashField
+		for range n {
+			var hf store.HashField
+			if hf.Field, err = d.readString(); err != nil {
+				return nil, err
+			}
+			if hf.Val, err = d.readString(); err != nil {
+				return nil, err
+			}
+			hash = append(hash, hf)
+		}
+		return hash, nil
+
+	case typeHashMetadata:
+		// expiries are stored relative to the earliest one, plus one so
+		// that 0 can mean none
+		p, err := d.readFull(8)
+		if err != nil {
+			return nil, err
+		}
+		minExpire := int64(binary.LittleEndian.Uint64(p))
+		n, err := d.len()
+		if err != nil {
+			return nil, err
+		}
+		var hash []store.HashField
+		for range n {
+			ttl, err := d.len()
+			if err != nil {
+				return nil, err
+			}
+			var hf store.HashField
+			if ttl != 0 {
+				hf.ExpiresAt = time.UnixMilli(minExpire + int64(ttl) - 1)
+			}
+			if hf.Field, err = d.readString(); err != nil {
+				return nil, err
+			}
+			if hf.Val, err = d.readString(); err != nil {
+				return nil, err
+			}
+			hash = append(hash, hf)
+		}
+		return hash, nil
+	}
+
+	if t == typeHashListpackEx {
+		if err := d.skipBytes(8); err != nil { // min expire
+			return nil, err
+		}
+	}
+	blob, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	var flat []string
+	if t == typeHashZiplist {
+		flat, err = ziplistEntries([]byte(blob))
+	} else {
+		flat, err = listpackEntries([]byte(blob))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	step := 2
+	if t == typeHashListpackEx {
+		step = 3
+	}
+	if len(flat)%step != 0 {
+		return nil, errBadBlob
+	}
+	hash := make([]store.HashField, 0, len(flat)/step)
+	for i := 0; i < len(flat); i += step {
+		hf := store.HashField{Field: flat[i], Val: flat[i+1]}
+		if step == 3 && flat[i+2] != "0" {
+			ms, err := strconv.ParseInt(flat[i+2], 10, 64)
+			if err != nil {
+				return nil, errBadBlob
+			}
+			hf.ExpiresAt = time.UnixMilli(ms)
+		}
+		hash = append(hash, hf)
+	}
+	return hash, nil
+}
+
+func (d *decoder) skipLens(n int) error {
+	for range n {
+		if _, _, err := d.readLen(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) skipStrings(n uint64) error {
+	for range n {
+		if _, err := d.readString(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) skipBytes(n int) error {
+	_, err := d.readFull(n)
+	return err
+}
+
+func (d *decoder) skipValue(t byte) error {
+	switch t {
+	case typeHashZipmap, typeListZiplist, typeSetIntset, typeZSetZiplist,
+		typeHashZiplist, typeHashListpack, typeZSetListpack, typeSetListpack:
+		return d.skipStrings(1) // a single serialized blob
+
+	case typeList, typeSet, typeListQuicklist:
+		n, err := d.len()
+		if err != nil {
+			return err
+		}
+		return d.skipStrings(n)
+
+	case typeHash:
+		n, err := d.len()
+		if err != nil {
+			return err
+		}
+		return d.skipStrings(2 * n)
+
+	case typeListQuicklist2:
+		n, err := d.len()
+		if err != nil {
+			return err
+		}
+		for range n {
+			if err := d.skipLens(1); err != nil { // container type
+				return err
+			}
+			if err := d.skipStrings(1); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case typeZSet, typeZSet2:
+		n, err := d.len()
+		if err != nil {
+			return err
+		}
+		for range n {
+			if err := d.skipStrings(1); err != nil {
+				return err
+			}
+			if t == typeZSet2 {
+				err = d.skipBytes(8) // binary double
+			} else {
+				err = d.skipDoubleString()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case typeHashMetadata:
+		if err := d.skipBytes(8); err != nil { // min expire
+			return err
+		}
+		n, err := d.len()
+		if err != nil {
+			return err
+		}
+		for range n {
+			if err := d.skipLens(1); err != nil { // field ttl
+				return err
+			}
+			if err := d.skipStrings(2); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case typeHashListpackEx:
+		if err := d.skipBytes(8); err != nil {
+			return err
+		}
+		return d.skipStrings(1)
+
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		return d.skipStream(t)
+
+	case typeModule2:
+		if err := d.skipLens(1); err != nil { // module id
+			return err
+		}
+		return d.skipModuleData()
+	}
+
+	return fmt.Errorf("can't skip %s values", typeName(t))
+}
+
+// skipDoubleString skips the RDB 1-7 textual double encoding.
+func (d *decoder) skipDoubleString() error {
+	n, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	if n >= 253 { // nan, +inf, -inf
+		return nil
+	}
+	return d.skipBytes(int(n))
+}
+
+// skipModuleData skips values saved with the module opcode framing.
+func (d *decoder) skipModuleData() error {
+	for {
+		op, err := d.len()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case 0: // EOF
+			return nil
+		case 1, 2: // signed, unsigned int
+			err = d.skipLens(1)
+		case 3: // float
+			err = d.skipBytes(4)
+		case 4: // double
+			err = d.skipBytes(8)
+		case 5: // string
+			err = d.skipStrings(1)
+		default:
+			return fmt.Errorf("rdb: unknown module opcode %d", op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (d *decoder) skipStream(t byte) error {
+	n, err := d.len()
+	if err != nil {
+		return err
+	}
+	if err := d.skipStrings(2 * n); err != nil { // master id + listpack
+		return err
+	}
+
+	meta := 3 // length, last id ms/seq
+	if t >= typeStreamListpacks2 {
+		meta += 5 // first id, max deleted id, entries added
+	}
+	if err := d.skipLens(meta); err != nil {
+		return err
+	}
+
+	groups, err := d.len()
+	if err != nil {
+		return err
+	}
+	for range groups {
+		if err := d.skipStrings(1); err != nil {
+			return err
+		}
+		gmeta := 2 // last id
+		if t >= typeStreamListpacks2 {
+			gmeta++ // entries read
+		}
+		if err := d.skipLens(gmeta); err != nil {
+			return err
+		}
+
+		pel, err := d.len()
+		if err != nil {
+			return err
+		}
+		for range pel {
+			// raw id, delivery time, delivery count
+			if err := d.skipBytes(16 + 8); err != nil {
+				return err
+			}
+			if err := d.skipLens(1); err != nil {
+				return err
+			}
+		}
+
+		consumers, err := d.len()
+		if err != nil {
+			return err
+		}
+		for range consumers {
+			if err := d.skipStrings(1); err != nil {
+				return err
+			}
+			times := 8 // seen time
+			if t >= typeStreamListpacks3 {
+				times += 8 // active time
+			}
+			if err := d.skipBytes(times); err != nil {
+				return err
+			}
+			cpel, err := d.len()
+			if err != nil {
+				return err
+			}
+			if cpel > maxLen/16 {
+				return errors.New("rdb: consumer PEL too long")
+			}
+			if err := d.skipBytes(16 * int(cpel)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// LoadFile decodes the RDB file at path.
+func LoadFile(path string) (*Dump, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// IsRDB reports whether the file at path starts with the RDB magic.
+func IsRDB(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	var hdr [5]byte
+	_, err = io.ReadFull(f, hdr[:])
+	return err == nil && string(hdr[:]) == "REDIS"
+}
@@ -0,0 +1,207 @@
+package rdb_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/rdb"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// empty dataset as written by Redis 7.2 (the payload of a full resync), with
+// aux fields using integer encodings and a real CRC-64 trailer
+const redisEmptyRDB = "UkVESVMwMDEx+glyZWRpcy12ZXIFNy4yLjD6CnJlZGlzLWJpdHPAQPoFY3RpbWXCbQi8ZfoIdXNlZC1tZW3CsMQQAPoIYW9mLWJhc2XAAP/wbjv+wP9aog=="
+
+func TestDecode_RealRedisFile(t *testing.T) {
+	data, _ := base64.StdEncoding.DecodeString(redisEmptyRDB)
+	dump, err := rdb.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if dump.Version != 11 || len(dump.DBs) != 0 || len(dump.Skipped) != 0 {
+		t.Fatalf("got %+v, want an empty v11 dump", dump)
+	}
+
+	data[20] ^= 0xff
+	if _, err := rdb.Decode(bytes.NewReader(data)); err == nil {
+		t.Fatalf("corrupted file decoded without error")
+	}
+}
+
+// fixture builds RDB bodies by hand; the checksum is left at zero, which
+// Redis treats as "checksum disabled".
+type fixture struct{ bytes.Buffer }
+
+func (f *fixture) str(s string) *fixture {
+	f.WriteByte(byte(len(s))) // all fixture strings are < 64 bytes
+	f.WriteString(s)
+	return f
+}
+
+func (f *fixture) raw(b ...byte) *fixture {
+	f.Write(b)
+	return f
+}
+
+func (f *fixture) bytes() []byte {
+	f.WriteByte(0xff)
+	f.Write(make([]byte, 8))
+	return f.Bytes()
+}
+
+// a listpack of "x", 5 and -2, and a ziplist of "a", 2 and -100; then a
+// hash's fields and values packed the same ways, f=7 and k=v
+var (
+	listpack = string([]byte{15, 0, 0, 0, 3, 0, 0x81, 'x', 2, 0x05, 1, 0xdf, 0xfe, 2, 0xff})
+	ziplist  = string([]byte{19, 0, 0, 0, 15, 0, 0, 0, 3, 0, 0, 0x01, 'a', 3, 0xf3, 2, 0xfe, 0x9c, 0xff})
+
+	hashListpack = string([]byte{12, 0, 0, 0, 2, 0, 0x81, 'f', 2, 0x07, 1, 0xff})
+	hashZiplist  = string([]byte{17, 0, 0, 0, 13, 0, 0, 0, 2, 0, 0, 0x01, 'k', 3, 0x01, 'v', 0xff})
+
+	// a redis 7.4 hash listpack with field expiries: a=b expiring at 2000ms
+	// and c=d without one
+	hashListpackEx = string([]byte{24, 0, 0, 0, 6, 0,
+		0x81, 'a', 2, 0x81, 'b', 2, 0xc7, 0xd0, 3,
+		0x81, 'c', 2, 0x81, 'd', 2, 0x00, 1, 0xff})
+)
+
+func TestDecode_StringsListsHashesAndSkippedTypes(t *testing.T) {
+	f := &fixture{}
+	f.WriteString("REDIS0012")
+	f.raw(0xfa).str("redis-ver").str("7.4.0")
+	f.raw(0xfe, 0x00, 0xfb, 0x09, 0x02)
+
+	f.raw(0x00).str("plain").str("value")
+	f.raw(0x00).str("int8").raw(0xc0, 0xf6)                    // -10
+	f.raw(0x00).str("int16").raw(0xc1, 0x39, 0x30)             // 12345
+	f.raw(0x00).str("int32").raw(0xc2, 0x15, 0xcd, 0x5b, 0x07) // 123456789
+	f.raw(0x00).str("lzf").raw(0xc3, 0x06, 0x09, 0x02, 'a', 'b', 'c', 0x80, 0x02)
+	f.raw(0xfc, 0xe8, 0x03, 0, 0, 0, 0, 0, 0) // expire at 1000ms
+	f.raw(0x00).str("ms").str("v")
+	f.raw(0xfd, 0x02, 0, 0, 0) // expire at 2s
+	f.raw(0x00).str("secs").str("v")
+	f.raw(0xf8, 0x05, 0xf9, 0x03) // idle and freq hints
+
+	f.raw(0x01).str("list").raw(0x02).str("a").str("b")
+	f.raw(0x12).str("qlist2").raw(0x02, 0x02).str(listpack).raw(0x01).str("plain")
+	f.raw(0x0a).str("ziplist").str(ziplist)
+	f.raw(0x0e).str("qlist").raw(0x02).str(ziplist).str(ziplist)
+	f.raw(0x02).str("set").raw(0x01).str("m")
+	f.raw(0x03).str("zset1").raw(0x01).str("m").raw(0x03, '1', '.', '5')
+	f.raw(0x05).str("zset2").raw(0x01).str("m").raw(0, 0, 0, 0, 0, 0, 0xf8, 0x3f)
+	f.raw(0x04).str("hash").raw(0x01).str("f").str("v")
+	f.raw(0x10).str("hashlp").str(hashListpack)
+	f.raw(0x0d).str("hashzl").str(hashZiplist)
+	f.raw(0x09).str("zipmap").str("zipmap-blob")
+	f.raw(0x18).str("hashttl").raw(0xe8, 0x03, 0, 0, 0, 0, 0, 0) // min expire 1000ms
+	f.raw(0x02, 0x00).str("f").str("v").raw(0x03).str("g").str("w")
+	f.raw(0x19).str("hashlpttl").raw(0xd0, 0x07, 0, 0, 0, 0, 0, 0).str(hashListpackEx)
+	f.raw(0x15).str("stream").raw(0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+	f.raw(0x07).str("mod").raw(0x05, 0x02, 0x07, 0x05).str("blob").raw(0x00)
+
+	f.raw(0xfe, 0x03)
+	f.raw(0x00).str("other").str("db")
+
+	dump, err := rdb.Decode(bytes.NewReader(f.bytes()))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := map[int][]store.Entry{
+		0: {
+			{Key: "plain", Val: "value"},
+			{Key: "int8", Val: "-10"},
+			{Key: "int16", Val: "12345"},
+			{Key: "int32", Val: "123456789"},
+			{Key: "lzf", Val: "abcabcabc"},
+			{Key: "ms", Val: "v", ExpiresAt: time.UnixMilli(1000)},
+			{Key: "secs", Val: "v", ExpiresAt: time.Unix(2, 0)},
+			{Key: "list", List: []string{"a", "b"}},
+			{Key: "qlist2", List: []string{"x", "5", "-2", "plain"}},
+			{Key: "ziplist", List: []string{"a", "2", "-100"}},
+			{Key: "qlist", List: []string{"a", "2", "-100", "a", "2", "-100"}},
+			{Key: "hash", Hash: []store.HashField{{Field: "f", Val: "v"}}},
+			{Key: "hashlp", Hash: []store.HashField{{Field: "f", Val: "7"}}},
+			{Key: "hashzl", Hash: []store.HashField{{Field: "k", Val: "v"}}},
+			{Key: "hashttl", Hash: []store.HashField{
+				{Field: "f", Val: "v"},
+				{Field: "g", Val: "w", ExpiresAt: time.UnixMilli(1002)},
+			}},
+			{Key: "hashlpttl", Hash: []store.HashField{
+				{Field: "a", Val: "b", ExpiresAt: time.UnixMilli(2000)},
+				{Field: "c", Val: "d"},
+			}},
+		},
+		3: {{Key: "other", Val: "db"}},
+	}
+	if !reflect.DeepEqual(dump.DBs, want) {
+		t.Fatalf("DBs: got %+v, want %+v", dump.DBs, want)
+	}
+
+	var skipped []string
+	for _, s := range dump.Skipped {
+		skipped = append(skipped, s.Key+":"+s.Type)
+	}
+	wantSkipped := []string{
+		"set:set", "zset1:zset", "zset2:zset",
+		"zipmap:hash", "stream:stream", "mod:module",
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Fatalf("Skipped: got %q, want %q", skipped, wantSkipped)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	if _, err := rdb.Decode(bytes.NewReader([]byte("GOLIATH\x01\x00"))); !errors.Is(err, rdb.ErrBadMagic) {
+		t.Fatalf("bad magic: got %v", err)
+	}
+	if _, err := rdb.Decode(bytes.NewReader([]byte("REDIS0099\xff"))); !errors.Is(err, rdb.ErrBadVersion) {
+		t.Fatalf("bad version: got %v", err)
+	}
+
+	f := &fixture{}
+	f.WriteString("REDIS0009")
+	f.raw(0x00).str("k")
+	if _, err := rdb.Decode(bytes.NewReader(f.Bytes())); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncated: got %v", err)
+	}
+
+	f = &fixture{}
+	f.WriteString("REDIS0009")
+	f.raw(0x06).str("oldmod").raw(0x00)
+	if _, err := rdb.Decode(bytes.NewReader(f.bytes())); err == nil {
+		t.Fatalf("pre-GA module value: expected an error")
+	}
+}
+
+func TestDecode_MalformedList(t *testing.T) {
+	for name, blob := range map[string]string{
+		"truncated listpack": listpack[:10],
+		"bad encoding":       listpack[:6] + "\xf5\xff",
+		"truncated ziplist":  ziplist[:14],
+	} {
+		f := &fixture{}
+		f.WriteString("REDIS0011")
+		if name == "truncated ziplist" {
+			f.raw(0x0a).str("k").str(blob)
+		} else {
+			f.raw(0x12).str("k").raw(0x01, 0x02).str(blob)
+		}
+		if _, err := rdb.Decode(bytes.NewReader(f.bytes())); err == nil {
+			t.Errorf("%s: decoded without error", name)
+		}
+	}
+
+	// a hash's listpack must pair every field with a value
+	f := &fixture{}
+	f.WriteString("REDIS0011")
+	f.raw(0x10).str("k").str(listpack)
+	if _, err := rdb.Decode(bytes.NewReader(f.bytes())); err == nil {
+		t.Errorf("odd hash listpack: decoded without error")
+	}
+}
@@ -0,0 +1,198 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+type encoder struct {
+	w   *bufio.Writer
+	buf [9]byte
+}
+
+func (e *encoder) writeLen(n uint64) {
+	switch {
+	case n < 1<<6:
+		e.w.WriteByte(byte(n))
+	case n < 1<<14:
+		e.w.Write([]byte{byte(n>>8) | 0x40, byte(n)})
+	case n <= 0xffffffff:
+		e.buf[0] = 0x80
+		binary.BigEndian.PutUint32(e.buf[1:], uint32(n))
+		e.w.Write(e.buf[:5])
+	default:
+		e.buf[0] = 0x81
+		binary.BigEndian.PutUint64(e.buf[1:], n)
+		e.w.Write(e.buf[:9])
+	}
+}
+
+func (e *encoder) writeString(s string) {
+	e.writeLen(uint64(len(s)))
+	e.w.WriteString(s)
+}
+
+// Encode writes dbs (keyed by database number) as an RDB file that stock
+// Redis can load. Entries whose expiry already passed are still written;
+// Redis drops them on load. The file is RDB 9 unless a hash has field
+// expiries: those are written as RDB 12, which only Redis 7.4 and newer
+// can load.
+func Encode(w io.Writer, dbs map[int][]store.Entry) error {
+	crc := &crcWriter{}
+	e := &encoder{w: bufio.NewWriter(io.MultiWriter(w, crc))}
+
+	version := Version
+	for _, entries := range dbs {
+		if slices.ContainsFunc(entries, hasFieldTTLs) {
+			version = HashTTLVersion
+			break
+		}
+	}
+	fmt.Fprintf(e.w, "REDIS%04d", version)
+	for _, aux := range [][2]string{
+		{"redis-bits", "64"},
+		{"ctime", strconv.FormatInt(time.Now().Unix(), 10)},
+	} {
+		e.w.WriteByte(opAux)
+		e.writeString(aux[0])
+		e.writeString(aux[1])
+	}
+
+	nums := make([]int, 0, len(dbs))
+	for n := range dbs {
+		nums = append(nums, n)
+	}
+	slices.Sort(nums)
+
+	for _, n := range nums {
+		entries := dbs[n]
+		if len(entries) == 0 {
+			continue
+		}
+
+		var expires uint64
+		for _, ent := range entries {
+			if !ent.ExpiresAt.IsZero() {
+				expires++
+			}
+		}
+
+		e.w.WriteByte(opSelectDB)
+		e.writeLen(uint64(n))
+		e.w.WriteByte(opResizeDB)
+		e.writeLen(uint64(len(entries)))
+		e.writeLen(expires)
+
+		for _, ent := range entries {
+			if !ent.ExpiresAt.IsZero() {
+				e.w.WriteByte(opExpireTimeMs)
+				binary.LittleEndian.PutUint64(e.buf[:8], uint64(ent.ExpiresAt.UnixMilli()))
+				e.w.Write(e.buf[:8])
+			}
+			switch ent.Kind() {
+			case store.KindList:
+				e.w.WriteByte(typeList)
+				e.writeString(ent.Key)
+				e.writeLen(uint64(len(ent.List)))
+				for _, el := range ent.List {
+					e.writeString(el)
+				}
+			case store.KindHash:
+				if hasFieldTTLs(ent) {
+					e.writeHashMetadata(ent)
+					break
+				}
+				e.w.WriteByte(typeHash)
+				e.writeString(ent.Key)
+				e.writeLen(uint64(len(ent.Hash)))
+				for _, hf := range ent.Hash {
+					e.writeString(hf.Field)
+					e.writeString(hf.Val)
+				}
+			default:
+				e.w.WriteByte(typeString)
+				e.writeString(ent.Key)
+				e.writeString(ent.Val)
+			}
+		}
+	}
+
+	e.w.WriteByte(opEOF)
+	if err := e.w.Flush(); err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint64(e.buf[:8], crc.sum)
+	_, err := w.Write(e.buf[:8])
+	return err
+}
+
+func hasFieldTTLs(ent store.Entry) bool {
+	return slices.ContainsFunc(ent.Hash, func(hf store.HashField) bool { return !hf.ExpiresAt.IsZero() })
+}
+
+// writeHashMetadata writes a hash with field expiries, each stored relative
+// to the earliest plus one, so that 0 can mean none.
+func (e *encoder) writeHashMetadata(ent store.Entry) {
+	minExpire := int64(math.MaxInt64)
+	for _, hf := range ent.Hash {
+		if !hf.ExpiresAt.IsZero() {
+			minExpire = min(minExpire, hf.ExpiresAt.UnixMilli())
+		}
+	}
+
+	e.w.WriteByte(typeHashMetadata)
+	e.writeString(ent.Key)
+	binary.LittleEndian.PutUint64(e.buf[:8], uint64(minExpire))
+	e.w.Write(e.buf[:8])
+	e.writeLen(uint64(len(ent.Hash)))
+	for _, hf := range ent.Hash {
+		var ttl uint64
+		if !hf.ExpiresAt.IsZero() {
+			ttl = uint64(hf.ExpiresAt.UnixMilli()-minExpire) + 1
+		}
+		e.writeLen(ttl)
+		e.writeString(hf.Field)
+		e.writeString(hf.Val)
+	}
+}
+
+// SaveFile writes an RDB file to path atomically via a temporary file.
+func SaveFile(path string, dbs map[int][]store.Entry) error {
+	tmp := fmt.Sprintf("%s.tmp-%d", path, os.Getpid())
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = Encode(f, dbs)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
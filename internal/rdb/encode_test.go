@@ -0,0 +1,113 @@
+package rdb_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/rdb"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	dbs := map[int][]store.Entry{
+		0: {
+			{Key: "a", Val: "1"},
+			{Key: "ttl", Val: "v", ExpiresAt: time.UnixMilli(1_700_000_000_123)},
+			{Key: "bin", Val: string(all)},
+			{Key: "long", Val: strings.Repeat("x", 70_000)}, // 32-bit length
+			{Key: "hash", Hash: []store.HashField{{Field: "f", Val: "1"}, {Field: "g", Val: ""}}},
+			{Key: "list", List: []string{"a", "", "c"}, ExpiresAt: time.UnixMilli(1_700_000_000_456)},
+		},
+		5: {{Key: "b", Val: "2"}},
+	}
+
+	var buf bytes.Buffer
+	if err := rdb.Encode(&buf, dbs); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("REDIS0009")) {
+		t.Fatalf("header: got %q", buf.Bytes()[:9])
+	}
+
+	dump, err := rdb.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if dump.Version != rdb.Version || !reflect.DeepEqual(dump.DBs, dbs) {
+		t.Fatalf("round trip mismatch: got v%d %d dbs", dump.Version, len(dump.DBs))
+	}
+
+	// the trailer is a real checksum, not the "disabled" zero
+	data := buf.Bytes()
+	data[len(data)-20] ^= 0x01
+	if _, err := rdb.Decode(bytes.NewReader(data)); !errors.Is(err, rdb.ErrBadChecksum) {
+		t.Fatalf("flipped byte: got %v, want ErrBadChecksum", err)
+	}
+}
+
+func TestEncodeDecode_HashFieldTTLs(t *testing.T) {
+	dbs := map[int][]store.Entry{
+		0: {
+			{Key: "h", Hash: []store.HashField{
+				{Field: "a", Val: "1", ExpiresAt: time.UnixMilli(1_700_000_005_000)},
+				{Field: "b", Val: "2"},
+				{Field: "c", Val: "3", ExpiresAt: time.UnixMilli(1_700_000_000_000)},
+			}},
+			{Key: "plain", Hash: []store.HashField{{Field: "f", Val: "v"}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := rdb.Encode(&buf, dbs); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	// only the version that has field expiries can hold them
+	if !bytes.HasPrefix(buf.Bytes(), []byte("REDIS0012")) {
+		t.Fatalf("header: got %q", buf.Bytes()[:9])
+	}
+
+	dump, err := rdb.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if dump.Version != rdb.HashTTLVersion || !reflect.DeepEqual(dump.DBs, dbs) {
+		t.Fatalf("round trip mismatch: got v%d %+v", dump.Version, dump.DBs)
+	}
+}
+
+func TestSaveFileLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	dbs := map[int][]store.Entry{0: {{Key: "k", Val: "v"}}}
+
+	if rdb.IsRDB(path) {
+		t.Fatalf("IsRDB on missing file: got true")
+	}
+	if err := rdb.SaveFile(path, dbs); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if !rdb.IsRDB(path) {
+		t.Fatalf("IsRDB: got false for a saved file")
+	}
+
+	dump, err := rdb.LoadFile(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !reflect.DeepEqual(dump.DBs, dbs) {
+		t.Fatalf("got %+v, want %+v", dump.DBs, dbs)
+	}
+
+	if _, err := rdb.LoadFile(filepath.Join(t.TempDir(), "nope.rdb")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing file: got %v", err)
+	}
+}
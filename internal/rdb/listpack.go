@@ -0,0 +1,207 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// Redis serializes small collections as one string blob holding a packed
+// array, a ziplist (before 7.0) or a listpack (since). These decode such a
+// blob into its elements; integers come back in decimal, as redis would
+// return them.
+
+var errBadBlob = errors.New("rdb: malformed packed collection")
+
+// listpackEntries decodes a listpack: a 6 byte header, entries, and a 0xff
+// terminator. Each entry is an encoding byte, its data and a back length
+// used for walking backwards, which is skipped here.
+func listpackEntries(b []byte) ([]string, error) {
+	if len(b) < 7 {
+		return nil, errBadBlob
+	}
+	var out []string
+	p := b[6:]
+	for {
+		if len(p) == 0 {
+			return nil, errBadBlob
+		}
+		enc := p[0]
+		if enc == 0xff {
+			return out, nil
+		}
+
+		var (
+			v    string
+			size int // encoding and data, which the back length covers
+		)
+		switch {
+		case enc&0x80 == 0: // 7 bit uint
+			v, size = strconv.Itoa(int(enc)), 1
+		case enc&0xc0 == 0x80: // string up to 63 bytes
+			n := int(enc & 0x3f)
+			size = 1 + n
+			if len(p) < size {
+				return nil, errBadBlob
+			}
+			v = string(p[1:size])
+		case enc&0xe0 == 0xc0: // 13 bit int
+			if len(p) < 2 {
+				return nil, errBadBlob
+			}
+			n := int(enc&0x1f)<<8 | int(p[1])
+			if n >= 1<<12 {
+				n -= 1 << 13
+			}
+			v, size = strconv.Itoa(n), 2
+		case enc&0xf0 == 0xe0: // string up to 4095 bytes
+			if len(p) < 2 {
+				return nil, errBadBlob
+			}
+			n := int(enc&0x0f)<<8 | int(p[1])
+			size = 2 + n
+			if len(p) < size {
+				return nil, errBadBlob
+			}
+			v = string(p[2:size])
+		case enc == 0xf0: // string with a 32 bit length
+			if len(p) < 5 {
+				return nil, errBadBlob
+			}
+			n := binary.LittleEndian.Uint32(p[1:])
+			if uint64(n) > uint64(len(p)-5) {
+				return nil, errBadBlob
+			}
+			size = 5 + int(n)
+			v = string(p[5:size])
+		case enc >= 0xf1 && enc <= 0xf4: // 16, 24, 32 and 64 bit ints
+			width := [...]int{2, 3, 4, 8}[enc-0xf1]
+			if len(p) < 1+width {
+				return nil, errBadBlob
+			}
+			v, size = strconv.FormatInt(littleEndianInt(p[1:1+width]), 10), 1+width
+		default:
+			return nil, errBadBlob
+		}
+
+		skip := size + backlenSize(size)
+		if len(p) < skip {
+			return nil, errBadBlob
+		}
+		out = append(out, v)
+		p = p[skip:]
+	}
+}
+
+// backlenSize is how many bytes a listpack back length takes for an entry
+// of size bytes: 7 bits per byte.
+func backlenSize(size int) int {
+	switch {
+	case size < 1<<7:
+		return 1
+	case size < 1<<14:
+		return 2
+	case size < 1<<21:
+		return 3
+	case size < 1<<28:
+		return 4
+	}
+	return 5
+}
+
+// ziplistEntries decodes a ziplist: a 10 byte header, entries, and a 0xff
+// terminator. Each entry is the previous entry's length, an encoding and
+// its data.
+func ziplistEntries(b []byte) ([]string, error) {
+	if len(b) < 11 {
+		return nil, errBadBlob
+	}
+	var out []string
+	p := b[10:]
+	for {
+		if len(p) == 0 {
+			return nil, errBadBlob
+		}
+		if p[0] == 0xff {
+			return out, nil
+		}
+
+		// the previous entry's length, 1 byte or 0xfe and 4 more
+		prev := 1
+		if p[0] == 0xfe {
+			prev = 5
+		}
+		if len(p) <= prev {
+			return nil, errBadBlob
+		}
+		p = p[prev:]
+
+		enc := p[0]
+		var (
+			v    string
+			size int
+		)
+		switch {
+		case enc>>6 == 0: // string up to 63 bytes
+			size = 1 + int(enc&0x3f)
+			if len(p) < size {
+				return nil, errBadBlob
+			}
+			v = string(p[1:size])
+		case enc>>6 == 1: // string up to 16383 bytes
+			if len(p) < 2 {
+				return nil, errBadBlob
+			}
+			size = 2 + (int(enc&0x3f)<<8 | int(p[1]))
+			if len(p) < size {
+				return nil, errBadBlob
+			}
+			v = string(p[2:size])
+		case enc == 0x80: // string with a 32 bit length, big endian
+			if len(p) < 5 {
+				return nil, errBadBlob
+			}
+			n := binary.BigEndian.Uint32(p[1:])
+			if uint64(n) > uint64(len(p)-5) {
+				return nil, errBadBlob
+			}
+			size = 5 + int(n)
+			v = string(p[5:size])
+		case enc >= 0xf1 && enc <= 0xfd: // 4 bit immediate, 0 to 12
+			v, size = strconv.Itoa(int(enc&0x0f)-1), 1
+		default:
+			var width int
+			switch enc {
+			case 0xfe:
+				width = 1
+			case 0xc0:
+				width = 2
+			case 0xf0:
+				width = 3
+			case 0xd0:
+				width = 4
+			case 0xe0:
+				width = 8
+			default:
+				return nil, errBadBlob
+			}
+			if len(p) < 1+width {
+				return nil, errBadBlob
+			}
+			v, size = strconv.FormatInt(littleEndianInt(p[1:1+width]), 10), 1+width
+		}
+
+		out = append(out, v)
+		p = p[size:]
+	}
+}
+
+// littleEndianInt decodes a signed little endian integer of 1 to 8 bytes.
+func littleEndianInt(b []byte) int64 {
+	var u uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		u = u<<8 | uint64(b[i])
+	}
+	shift := 64 - 8*len(b)
+	return int64(u<<shift) >> shift
+}
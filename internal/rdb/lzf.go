@@ -0,0 +1,51 @@
+package rdb
+
+import "errors"
+
+var errLZF = errors.New("rdb: corrupt LZF data")
+
+// lzfDecompress expands LZF-compressed strings, which redis uses for values
+// above 20 bytes when rdbcompression is on.
+func lzfDecompress(in []byte, outLen int) ([]byte, error) {
+	out := make([]byte, 0, outLen)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+
+		if ctrl < 1<<5 { // literal run of ctrl+1 bytes
+			n := ctrl + 1
+			if i+n > len(in) || len(out)+n > outLen {
+				return nil, errLZF
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		// back reference of n+2 bytes
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, errLZF
+			}
+			n += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, errLZF
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		if ref < 0 || len(out)+n+2 > outLen {
+			return nil, errLZF
+		}
+		for j := range n + 2 { // may overlap, so copy byte by byte
+			out = append(out, out[ref+j])
+		}
+	}
+
+	if len(out) != outLen {
+		return nil, errLZF
+	}
+	return out, nil
+}
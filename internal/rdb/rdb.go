@@ -0,0 +1,84 @@
+// Package rdb reads and writes the Redis RDB dump format, so data can be
+// migrated between goliath and stock Redis. String, list and hash keys map
+// onto goliath's store; every other type is recognized and skipped.
+package rdb
+
+import "fmt"
+
+const (
+	// Version written by Encode. RDB 9 is loadable by Redis 5.0 and newer,
+	// all of which still read lists and hashes in their plain, pre-ziplist
+	// encodings.
+	Version = 9
+
+	// written instead when a hash has field expiries, which need the
+	// metadata encoding of Redis 7.4
+	HashTTLVersion = 12
+
+	// newest format Decode understands (Redis 7.4)
+	MaxVersion = 12
+)
+
+const (
+	opSlotInfo      = 0xf4
+	opFunction2     = 0xf5
+	opFunctionPreGA = 0xf6
+	opModuleAux     = 0xf7
+	opIdle          = 0xf8
+	opFreq          = 0xf9
+	opAux           = 0xfa
+	opResizeDB      = 0xfb
+	opExpireTimeMs  = 0xfc
+	opExpireTime    = 0xfd
+	opSelectDB      = 0xfe
+	opEOF           = 0xff
+)
+
+const (
+	typeString              = 0
+	typeList                = 1
+	typeSet                 = 2
+	typeZSet                = 3
+	typeHash                = 4
+	typeZSet2               = 5
+	typeModulePreGA         = 6
+	typeModule2             = 7
+	typeHashZipmap          = 9
+	typeListZiplist         = 10
+	typeSetIntset           = 11
+	typeZSetZiplist         = 12
+	typeHashZiplist         = 13
+	typeListQuicklist       = 14
+	typeStreamListpacks     = 15
+	typeHashListpack        = 16
+	typeZSetListpack        = 17
+	typeListQuicklist2      = 18
+	typeStreamListpacks2    = 19
+	typeSetListpack         = 20
+	typeStreamListpacks3    = 21
+	typeHashMetadataPreGA   = 22
+	typeHashListpackExPreGA = 23
+	typeHashMetadata        = 24
+	typeHashListpackEx      = 25
+)
+
+func typeName(t byte) string {
+	switch t {
+	case typeString:
+		return "string"
+	case typeList, typeListZiplist, typeListQuicklist, typeListQuicklist2:
+		return "list"
+	case typeSet, typeSetIntset, typeSetListpack:
+		return "set"
+	case typeZSet, typeZSet2, typeZSetZiplist, typeZSetListpack:
+		return "zset"
+	case typeHash, typeHashZipmap, typeHashZiplist, typeHashListpack,
+		typeHashMetadataPreGA, typeHashListpackExPreGA, typeHashMetadata, typeHashListpackEx:
+		return "hash"
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		return "stream"
+	case typeModulePreGA, typeModule2:
+		return "module"
+	}
+	return fmt.Sprintf("type %d", t)
+}
@@ -3,6 +3,7 @@ package server_test
 import (
 	"bufio"
 	"errors"
+	"io"
 	"net"
 	"testing"
 	"time"
@@ -32,9 +33,9 @@ func TestServer_EchoSmoke(t *testing.T) {
 	// Build dispatcher + store
 	d := command.NewDispatcher()
 	command.RegisterBuiltins(d)
-	kv := store.NewMemory()
-	command.RegisterKV(d, kv)
-	command.RegisterTTL(d, kv)
+	dbs := store.NewDatabases()
+	command.RegisterKV(d, dbs)
+	command.RegisterTTL(d, dbs)
 
 	// Pick a free port by binding to :0, grab it, close it, then let server use it.
 	ln, err := net.Listen("tcp", "127.0.0.1:0")
@@ -71,15 +72,16 @@ func TestServer_EchoSmoke(t *testing.T) {
 	if _, err := conn.Write([]byte("ECHO hi\r\n")); err != nil {
 		t.Fatalf("write: %v", err)
 	}
-	resp, err := bufio.NewReader(conn).ReadString('\n')
-	if err != nil {
+	reader := bufio.NewReader(conn)
+	resp := make([]byte, len("$2\r\nhi\r\n"))
+	if _, err := io.ReadFull(reader, resp); err != nil {
 		t.Fatalf("read: %v", err)
 	}
-	if resp != "hi\r\n" {
-		t.Fatalf("got %q, want %q", resp, "hi\r\n")
+	if string(resp) != "$2\r\nhi\r\n" {
+		t.Fatalf("got %q, want %q", resp, "$2\r\nhi\r\n")
 	}
 
 	if _, err := conn.Write([]byte("QUIT\r\n")); err == nil {
-		_, _ = bufio.NewReader(conn).ReadString('\n') // "+OK\r\n"
+		_, _ = reader.ReadString('\n') // "+OK\r\n"
 	}
 }
@@ -0,0 +1,109 @@
+package session_test
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/command"
+	"github.com/amir-aharon/goliath/internal/session"
+)
+
+// mustDispatch runs a command straight through d, bypassing any session
+func mustDispatch(t *testing.T, d *command.Dispatcher, want, name string, args ...string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := d.Dispatch(command.NewClient(&buf), name, args); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if buf.String() != want {
+		t.Fatalf("%s %q: got %q, want %q", name, args, buf.String(), want)
+	}
+}
+
+func TestSession_BlockingPopServedByAnotherSession(t *testing.T) {
+	d := newDispatcher()
+
+	waitConn, waitClient := net.Pipe()
+	defer waitClient.Close()
+	go session.New(waitConn, d).Run()
+	pushConn, pushClient := net.Pipe()
+	defer pushClient.Close()
+	go session.New(pushConn, d).Run()
+
+	waitReader := bufio.NewReader(waitClient)
+	pushReader := bufio.NewReader(pushClient)
+	_ = waitClient.SetDeadline(time.Now().Add(2 * time.Second))
+	_ = pushClient.SetDeadline(time.Now().Add(2 * time.Second))
+
+	// replies to commands before the blocking one aren't held back by it
+	if _, err := waitClient.Write([]byte("PING\r\nBLPOP q 0\r\n")); err != nil {
+		t.Fatalf("write BLPOP: %v", err)
+	}
+	expect(t, waitReader, "+PONG\r\n")
+
+	if _, err := pushClient.Write([]byte("RPUSH q a\r\n")); err != nil {
+		t.Fatalf("write RPUSH: %v", err)
+	}
+	expect(t, pushReader, ":1\r\n")
+	expect(t, waitReader, "*2\r\n$1\r\nq\r\n$1\r\na\r\n")
+
+	// and the session reads commands again afterwards
+	if _, err := waitClient.Write([]byte("BLPOP q 0.01\r\nPING\r\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expect(t, waitReader, "*-1\r\n+PONG\r\n")
+}
+
+func TestSession_HangingUpWhileBlockedUnregisters(t *testing.T) {
+	d := newDispatcher()
+
+	waitConn, waitClient := net.Pipe()
+	sess := session.New(waitConn, d)
+	done := make(chan struct{})
+	go func() { defer close(done); sess.Run() }()
+
+	_ = waitClient.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := waitClient.Write([]byte("PING\r\nBLPOP q 0\r\n")); err != nil {
+		t.Fatalf("write BLPOP: %v", err)
+	}
+	expect(t, bufio.NewReader(waitClient), "+PONG\r\n")
+	waitClient.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("session kept waiting after its client hung up")
+	}
+
+	// the push isn't handed to the departed client
+	mustDispatch(t, d, ":1\r\n", "RPUSH", "q", "a")
+	mustDispatch(t, d, ":1\r\n", "LLEN", "q")
+}
+
+func TestSession_HangingUpWithPipelinedInputWhileBlocked(t *testing.T) {
+	d := newDispatcher()
+
+	waitConn, waitClient := net.Pipe()
+	sess := session.New(waitConn, d)
+	done := make(chan struct{})
+	go func() { defer close(done); sess.Run() }()
+
+	// the commands after BLPOP are already buffered when it blocks
+	_ = waitClient.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := waitClient.Write([]byte("PING\r\nBLPOP q 0\r\nPING\r\n")); err != nil {
+		t.Fatalf("write BLPOP: %v", err)
+	}
+	expect(t, bufio.NewReader(waitClient), "+PONG\r\n")
+	waitClient.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("session kept waiting after its client hung up")
+	}
+	mustDispatch(t, d, ":1\r\n", "RPUSH", "q", "a")
+	mustDispatch(t, d, ":1\r\n", "LLEN", "q")
+}
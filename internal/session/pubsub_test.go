@@ -0,0 +1,117 @@
+package session_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/command"
+	"github.com/amir-aharon/goliath/internal/pubsub"
+	"github.com/amir-aharon/goliath/internal/session"
+)
+
+func newPubSubDispatcher(hub *pubsub.Hub) *command.Dispatcher {
+	d := newDispatcher()
+	command.RegisterPubSub(d, hub)
+	return d
+}
+
+// expect reads exactly len(want) bytes and compares them
+func expect(t *testing.T, r io.Reader, want string) {
+	t.Helper()
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatalf("read %q: %v", want, err)
+	}
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSession_PublishDeliversToSubscriber(t *testing.T) {
+	d := newPubSubDispatcher(pubsub.NewHub())
+
+	subConn, subClient := net.Pipe()
+	defer subClient.Close()
+	go session.New(subConn, d).Run()
+
+	pubConn, pubClient := net.Pipe()
+	defer pubClient.Close()
+	go session.New(pubConn, d).Run()
+
+	_ = subClient.SetDeadline(time.Now().Add(2 * time.Second))
+	_ = pubClient.SetDeadline(time.Now().Add(2 * time.Second))
+	sub, pub := bufio.NewReader(subClient), bufio.NewReader(pubClient)
+
+	if _, err := subClient.Write([]byte("SUBSCRIBE news\r\n")); err != nil {
+		t.Fatalf("write SUBSCRIBE: %v", err)
+	}
+	expect(t, sub, "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n")
+
+	if _, err := pubClient.Write([]byte("PUBLISH news hello\r\n")); err != nil {
+		t.Fatalf("write PUBLISH: %v", err)
+	}
+	expect(t, pub, ":1\r\n")
+	expect(t, sub, "*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n")
+
+	if _, err := subClient.Write([]byte("GET k\r\n")); err != nil {
+		t.Fatalf("write GET: %v", err)
+	}
+	expect(t, sub, "-ERR Can't execute 'get': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context\r\n")
+}
+
+func TestSession_SlowSubscriberIsDisconnected(t *testing.T) {
+	hub := pubsub.NewHub()
+	hub.QueueLen = 4
+	d := newPubSubDispatcher(hub)
+
+	subConn, subClient := net.Pipe()
+	defer subClient.Close()
+	done := make(chan struct{})
+	go func() { defer close(done); session.New(subConn, d).Run() }()
+
+	_ = subClient.SetDeadline(time.Now().Add(2 * time.Second))
+	sub := bufio.NewReader(subClient)
+	if _, err := subClient.Write([]byte("SUBSCRIBE ch\r\n")); err != nil {
+		t.Fatalf("write SUBSCRIBE: %v", err)
+	}
+	expect(t, sub, "*3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n")
+
+	// the client stops reading: net.Pipe is unbuffered, so the first
+	// delivery blocks and the queue behind it fills up
+	for i := 0; i < 10; i++ {
+		hub.Publish("ch", "payload")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("slow subscriber was not disconnected")
+	}
+	if n := hub.Publish("ch", "payload"); n != 0 {
+		t.Fatalf("publish after disconnect: got %d receivers", n)
+	}
+}
+
+func TestSession_CloseUnsubscribes(t *testing.T) {
+	hub := pubsub.NewHub()
+	d := newPubSubDispatcher(hub)
+
+	subConn, subClient := net.Pipe()
+	done := make(chan struct{})
+	go func() { defer close(done); session.New(subConn, d).Run() }()
+
+	_ = subClient.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := subClient.Write([]byte("SUBSCRIBE ch\r\n")); err != nil {
+		t.Fatalf("write SUBSCRIBE: %v", err)
+	}
+	expect(t, subClient, "*3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n")
+
+	subClient.Close()
+	<-done
+	if n := hub.Publish("ch", "payload"); n != 0 {
+		t.Fatalf("publish after close: got %d receivers", n)
+	}
+}
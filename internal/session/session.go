@@ -3,45 +3,175 @@ package session
 import (
 	"bufio"
 	"errors"
+	"log"
 	"net"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/amir-aharon/goliath/internal/command"
+	"github.com/amir-aharon/goliath/internal/proto"
+	"github.com/amir-aharon/goliath/internal/pubsub"
 )
 
+// MaxPendingOutput bounds how many reply bytes are held back while a
+// pipeline is being processed; the writer flushes whenever it fills up.
+const MaxPendingOutput = 64 << 10
+
 // per-connection handler
 type Session struct {
 	Conn       net.Conn
+	Client     *command.Client
 	Dispatcher *command.Dispatcher
+
+	w *bufio.Writer
+	// guards w and Client between the command loop and pubsub delivery, so
+	// a message is never written into the middle of a reply
+	mu         sync.Mutex
+	delivering bool
 }
 
 func New(c net.Conn, d *command.Dispatcher) *Session {
+	w := bufio.NewWriterSize(c, MaxPendingOutput)
 	return &Session{
 		Conn:       c,
+		Client:     command.NewClient(w),
 		Dispatcher: d,
+		w:          w,
 	}
 }
 
+// flushReader flushes pending replies right before the session would block
+// on the network, so every command already buffered by the reader is
+// answered in a single write.
+type flushReader struct {
+	sess *Session
+}
+
+func (fr flushReader) Read(p []byte) (int, error) {
+	if err := fr.sess.flush(); err != nil {
+		return 0, err
+	}
+	return fr.sess.Conn.Read(p)
+}
+
+func (sess *Session) flush() error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.w.Flush()
+}
+
 func (sess *Session) Run() {
 	defer sess.Conn.Close()
+	defer sess.flush()
+	defer func() {
+		if sub := sess.Client.Sub; sub != nil {
+			sub.Close()
+		}
+	}()
 
-	r := bufio.NewReader(sess.Conn)
+	r := proto.NewReader(flushReader{sess})
 
 	for {
-		line, err := r.ReadString('\n')
+		args, err := r.ReadCommand()
 		if err != nil {
+			var perr *proto.ProtocolError
+			if errors.As(err, &perr) {
+				sess.mu.Lock()
+				_ = proto.Err(sess.w, perr.Error())
+				sess.mu.Unlock()
+			}
 			return
 		}
 
-		fields := strings.Fields(strings.TrimSpace(line))
-		if len(fields) == 0 {
+		if len(args) == 0 {
 			continue
 		}
 
-		if err := sess.Dispatcher.Dispatch(sess.Conn, fields[0], fields[1:]); err != nil {
-			if errors.Is(err, command.ErrQuit) {
+		sess.mu.Lock()
+		err = sess.Dispatcher.Dispatch(sess.Client, args[0], args[1:])
+		sub := sess.Client.Sub
+		sess.mu.Unlock()
+
+		if sub != nil && !sess.delivering {
+			sess.delivering = true
+			go sess.deliver(sub)
+		}
+		if errors.Is(err, command.ErrQuit) {
+			return
+		}
+		if sess.Client.Blocked() && !sess.block(r) {
+			return
+		}
+	}
+}
+
+// block waits out a blocking command like BLPOP, which parked the client
+// rather than answering it. The connection is watched meanwhile so a client
+// that hangs up gives up its place; block reports false if it did.
+func (sess *Session) block(r *proto.Reader) bool {
+	// answers to the commands before this one can't wait for it, even
+	// when more input is buffered and nothing would read the network
+	if err := sess.flush(); err != nil {
+		return false
+	}
+
+	gone := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		// returns once the client hangs up, the deadline below ends the
+		// wait, or it sends more than the reader can hold
+		err := r.Watch()
+		var nerr net.Error
+		if err != nil && !(errors.As(err, &nerr) && nerr.Timeout()) {
+			close(gone)
+		}
+	}()
+
+	_ = sess.Dispatcher.Wait(sess.Client, &sess.mu, gone)
+
+	_ = sess.Conn.SetReadDeadline(time.Now())
+	<-watched
+	_ = sess.Conn.SetReadDeadline(time.Time{})
+
+	select {
+	case <-gone:
+		return false
+	default:
+		return true
+	}
+}
+
+// deliver writes pubsub messages to the connection as they're published,
+// batching whatever is already queued into one flush. A subscriber dropped
+// for falling behind has its connection closed, which also ends Run.
+func (sess *Session) deliver(sub *pubsub.Subscriber) {
+	go func() {
+		<-sub.Done()
+		if sub.Dropped() {
+			log.Printf("session: closing %s, pubsub queue overflowed", sess.Conn.RemoteAddr())
+			sess.Conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case m := <-sub.Messages():
+			sess.mu.Lock()
+			err := m.Encode(sess.Client)
+			for err == nil && len(sub.Messages()) > 0 {
+				err = (<-sub.Messages()).Encode(sess.Client)
+			}
+			if err == nil {
+				err = sess.w.Flush()
+			}
+			sess.mu.Unlock()
+			if err != nil {
+				sess.Conn.Close()
 				return
 			}
+		case <-sub.Done():
+			return
 		}
 	}
 }
@@ -0,0 +1,75 @@
+package session_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/amir-aharon/goliath/internal/session"
+)
+
+const (
+	benchSET = "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n"
+	benchGET = "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"
+
+	benchSETReply = "+OK\r\n"
+	benchGETReply = "$5\r\nvalue\r\n"
+)
+
+// startBenchSession serves a single session over loopback TCP so the
+// numbers include real write syscalls.
+func startBenchSession(b *testing.B) net.Conn {
+	b.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatalf("listen: %v", err)
+	}
+	b.Cleanup(func() { _ = ln.Close() })
+
+	d := newDispatcher()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		session.New(c, d).Run()
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		b.Fatalf("dial: %v", err)
+	}
+	b.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func benchmarkSETGET(b *testing.B, depth int) {
+	conn := startBenchSession(b)
+	r := bufio.NewReader(conn)
+
+	var req, want []byte
+	for range depth {
+		req = append(req, benchSET+benchGET...)
+		want = append(want, benchSETReply+benchGETReply...)
+	}
+	got := make([]byte, len(want))
+
+	b.SetBytes(int64(len(req)))
+	b.ResetTimer()
+	for range b.N {
+		if _, err := conn.Write(req); err != nil {
+			b.Fatalf("write: %v", err)
+		}
+		if _, err := io.ReadFull(r, got); err != nil {
+			b.Fatalf("read: %v", err)
+		}
+	}
+	b.ReportMetric(float64(2*depth*b.N)/b.Elapsed().Seconds(), "cmds/s")
+}
+
+func BenchmarkSession_SETGET_Unpipelined(b *testing.B) { benchmarkSETGET(b, 1) }
+
+func BenchmarkSession_SETGET_Pipelined16(b *testing.B) { benchmarkSETGET(b, 16) }
+
+func BenchmarkSession_SETGET_Pipelined256(b *testing.B) { benchmarkSETGET(b, 256) }
@@ -2,7 +2,11 @@ package session_test
 
 import (
 	"bufio"
+	"fmt"
+	"io"
 	"net"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,9 +18,9 @@ import (
 func newDispatcher() *command.Dispatcher {
 	d := command.NewDispatcher()
 	command.RegisterBuiltins(d)
-	kv := store.NewMemory()
-	command.RegisterKV(d, kv)
-	command.RegisterTTL(d, kv)
+	dbs := store.NewDatabases()
+	command.RegisterKV(d, dbs)
+	command.RegisterTTL(d, dbs)
 	return d
 }
 
@@ -53,3 +57,152 @@ func TestSession_PINGAndQUIT(t *testing.T) {
 
 	<-done // session should exit after writing +OK and returning ErrQuit
 }
+
+func TestSession_MultibulkRequest(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	sess := session.New(serverConn, newDispatcher())
+	go sess.Run()
+
+	reader := bufio.NewReader(clientConn)
+	_ = clientConn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := clientConn.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n")); err != nil {
+		t.Fatalf("write SET: %v", err)
+	}
+	if resp, err := reader.ReadString('\n'); err != nil || resp != "+OK\r\n" {
+		t.Fatalf("SET resp: got %q, err=%v; want %q", resp, err, "+OK\r\n")
+	}
+}
+
+func TestSession_ProtocolErrorClosesConnection(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	sess := session.New(serverConn, newDispatcher())
+	done := make(chan struct{})
+	go func() { defer close(done); sess.Run() }()
+
+	reader := bufio.NewReader(clientConn)
+	_ = clientConn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := clientConn.Write([]byte("*1\r\n+PING\r\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	want := "-ERR Protocol error: expected '$', got '+'\r\n"
+	if resp, err := reader.ReadString('\n'); err != nil || resp != want {
+		t.Fatalf("got %q, err=%v; want %q", resp, err, want)
+	}
+
+	<-done
+}
+
+func TestSession_BinarySafeRoundTrip(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	sess := session.New(serverConn, newDispatcher())
+	go sess.Run()
+
+	reader := bufio.NewReader(clientConn)
+	_ = clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	key, val := "bin\x00key", string(all)
+
+	set := fmt.Sprintf("*3\r\n$3\r\nSET\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(key), key, len(val), val)
+	if _, err := clientConn.Write([]byte(set)); err != nil {
+		t.Fatalf("write SET: %v", err)
+	}
+	if resp, err := reader.ReadString('\n'); err != nil || resp != "+OK\r\n" {
+		t.Fatalf("SET resp: got %q, err=%v", resp, err)
+	}
+
+	get := fmt.Sprintf("*2\r\n$3\r\nGET\r\n$%d\r\n%s\r\n", len(key), key)
+	if _, err := clientConn.Write([]byte(get)); err != nil {
+		t.Fatalf("write GET: %v", err)
+	}
+	want := "$256\r\n" + val + "\r\n"
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(reader, got); err != nil {
+		t.Fatalf("read GET: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("GET resp: got %q, want %q", got, want)
+	}
+}
+
+func TestSession_HELLO3UsesRESP3Replies(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	sess := session.New(serverConn, newDispatcher())
+	go sess.Run()
+
+	reader := bufio.NewReader(clientConn)
+	_ = clientConn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := clientConn.Write([]byte("HELLO 3\r\n")); err != nil {
+		t.Fatalf("write HELLO: %v", err)
+	}
+	if resp, err := reader.ReadString('\n'); err != nil || resp != "%7\r\n" {
+		t.Fatalf("HELLO resp: got %q, err=%v; want %q", resp, err, "%7\r\n")
+	}
+	// skip the 7 key/value pairs; modules is an empty array and ends the reply
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read HELLO body: %v", err)
+		}
+		if line == "*0\r\n" {
+			break
+		}
+	}
+
+	if _, err := clientConn.Write([]byte("GET missing\r\n")); err != nil {
+		t.Fatalf("write GET: %v", err)
+	}
+	if resp, err := reader.ReadString('\n'); err != nil || resp != "_\r\n" {
+		t.Fatalf("GET resp: got %q, err=%v; want %q", resp, err, "_\r\n")
+	}
+}
+
+type countingConn struct {
+	net.Conn
+	writes atomic.Int32
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	c.writes.Add(1)
+	return c.Conn.Write(p)
+}
+
+func TestSession_PipelinedRepliesFlushOnce(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	cc := &countingConn{Conn: serverConn}
+	sess := session.New(cc, newDispatcher())
+	go sess.Run()
+
+	_ = clientConn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	const n = 100
+	go func() { _, _ = clientConn.Write([]byte(strings.Repeat("PING\r\n", n))) }()
+
+	want := strings.Repeat("+PONG\r\n", n)
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(clientConn, got); err != nil {
+		t.Fatalf("read replies: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("got %q, want %d PONGs", got, n)
+	}
+	if w := cc.writes.Load(); w != 1 {
+		t.Fatalf("replies took %d writes, want 1", w)
+	}
+}
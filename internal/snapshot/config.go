@@ -0,0 +1,59 @@
+package snapshot
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Rule triggers a background save once at least Changes writes happened
+// and Secs seconds passed since the last save.
+type Rule struct {
+	Secs    int
+	Changes int
+}
+
+type Config struct {
+	Path  string
+	Rules []Rule
+}
+
+var defaultRules = []Rule{{3600, 1}, {300, 100}, {60, 10000}}
+
+func LoadConfig() Config {
+	cfg := Config{
+		Path:  "dump.gsnap",
+		Rules: defaultRules,
+	}
+
+	if v := os.Getenv("DBFILENAME"); v != "" {
+		cfg.Path = v
+	}
+
+	// SAVE="<secs> <changes> [<secs> <changes> ...]", or SAVE="" to disable
+	if v, ok := os.LookupEnv("SAVE"); ok {
+		if rules, ok := ParseRules(v); ok {
+			cfg.Rules = rules
+		}
+	}
+
+	return cfg
+}
+
+func ParseRules(s string) ([]Rule, bool) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, false
+	}
+
+	rules := make([]Rule, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		secs, err1 := strconv.Atoi(fields[i])
+		changes, err2 := strconv.Atoi(fields[i+1])
+		if err1 != nil || err2 != nil || secs <= 0 || changes <= 0 {
+			return nil, false
+		}
+		rules = append(rules, Rule{Secs: secs, Changes: changes})
+	}
+	return rules, true
+}
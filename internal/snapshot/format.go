@@ -0,0 +1,345 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// File layout, all integers little endian:
+//
+//	"GOLIATH"  magic
+//	u8         format version
+//	uvarint    database count
+//	databases: uvarint index, uvarint entry count, entries
+//	entries:   uvarint key length, key, u8 value type, value,
+//	           varint absolute expiry in unix milliseconds (0 = no TTL)
+//	strings:   uvarint length, bytes
+//	lists:     uvarint element count, elements as strings
+//	hashes:    uvarint field count, then for each field its name and value
+//	           as strings and its varint absolute expiry (0 = no TTL)
+//	u64        CRC-64/ECMA of everything before it
+//
+// Older versions are still read:
+//
+//   - version 4 files have no field expiries
+//   - version 3 files have no hashes either
+//   - version 2 files have no value types: every value is a string
+//   - version 1 files have no databases: the entry count and entries
+//     follow the version directly, and all belong to database 0
+const (
+	magic   = "GOLIATH"
+	Version = 5
+
+	// sanity bound on lengths read from disk, same as the max bulk length
+	maxLen = 512 << 20
+)
+
+var (
+	ErrBadMagic    = errors.New("snapshot: not a goliath snapshot")
+	ErrBadVersion  = errors.New("snapshot: unsupported format version")
+	ErrBadChecksum = errors.New("snapshot: checksum mismatch")
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// value types
+const (
+	typeString = 0
+	typeList   = 1
+	typeHash   = 2
+)
+
+// Write encodes the entries of each database in the snapshot format.
+func Write(w io.Writer, dbs map[int][]store.Entry) error {
+	crc := crc64.New(crcTable)
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+
+	var buf [binary.MaxVarintLen64]byte
+	putUvarint := func(n uint64) { bw.Write(buf[:binary.PutUvarint(buf[:], n)]) }
+	putVarint := func(n int64) { bw.Write(buf[:binary.PutVarint(buf[:], n)]) }
+	putString := func(s string) { putUvarint(uint64(len(s))); bw.WriteString(s) }
+
+	bw.WriteString(magic)
+	bw.WriteByte(Version)
+	putUvarint(uint64(len(dbs)))
+	for _, db := range slices.Sorted(maps.Keys(dbs)) {
+		putUvarint(uint64(db))
+		putUvarint(uint64(len(dbs[db])))
+		for _, e := range dbs[db] {
+			putString(e.Key)
+			switch e.Kind() {
+			case store.KindList:
+				bw.WriteByte(typeList)
+				putUvarint(uint64(len(e.List)))
+				for _, el := range e.List {
+					putString(el)
+				}
+			case store.KindHash:
+				bw.WriteByte(typeHash)
+				putUvarint(uint64(len(e.Hash)))
+				for _, hf := range e.Hash {
+					putString(hf.Field)
+					putString(hf.Val)
+					putVarint(unixMilli(hf.ExpiresAt))
+				}
+			default:
+				bw.WriteByte(typeString)
+				putString(e.Val)
+			}
+			putVarint(unixMilli(e.ExpiresAt))
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.LittleEndian, crc.Sum64())
+}
+
+// unixMilli returns t in unix milliseconds, 0 for the zero time.
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// reader feeds everything it consumes into the checksum.
+type reader struct {
+	r   *bufio.Reader
+	crc hash.Hash64
+}
+
+func (r *reader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.crc.Write([]byte{b})
+	}
+	return b, err
+}
+
+func (r *reader) readFull(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return nil, err
+	}
+	r.crc.Write(buf)
+	return buf, nil
+}
+
+func (r *reader) readString() (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > maxLen {
+		return "", fmt.Errorf("snapshot: string length %d out of range", n)
+	}
+	b, err := r.readFull(int(n))
+	return string(b), err
+}
+
+// Read decodes a snapshot written by Write, or by an older version's
+// writer, verifying its checksum.
+func Read(rd io.Reader) (map[int][]store.Entry, error) {
+	r := &reader{r: bufio.NewReader(rd), crc: crc64.New(crcTable)}
+
+	hdr, err := r.readFull(len(magic) + 1)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	if string(hdr[:len(magic)]) != magic {
+		return nil, ErrBadMagic
+	}
+	dbs := make(map[int][]store.Entry)
+	switch ver := hdr[len(magic)]; ver {
+	case 1:
+		if dbs[0], err = r.readEntries(ver); err != nil {
+			return nil, err
+		}
+	case 2, 3, 4, Version:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		for range n {
+			db, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, unexpected(err)
+			}
+			if db > math.MaxInt32 {
+				return nil, fmt.Errorf("snapshot: database index %d out of range", db)
+			}
+			if dbs[int(db)], err = r.readEntries(ver); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w %d", ErrBadVersion, ver)
+	}
+
+	want := r.crc.Sum64()
+	var got uint64
+	if err := binary.Read(r.r, binary.LittleEndian, &got); err != nil {
+		return nil, unexpected(err)
+	}
+	if got != want {
+		return nil, ErrBadChecksum
+	}
+	return dbs, nil
+}
+
+// readEntries reads an entry count and that many entries in the given
+// format version.
+func (r *reader) readEntries(ver byte) ([]store.Entry, error) {
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	entries := make([]store.Entry, 0, min(count, 1<<16))
+	for range count {
+		var e store.Entry
+		if e.Key, err = r.readString(); err != nil {
+			return nil, unexpected(err)
+		}
+		if err := r.readValue(&e, ver); err != nil {
+			return nil, err
+		}
+		if e.ExpiresAt, err = r.readExpiry(); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// readValue reads the value of e, which files before version 3 store as a
+// bare string.
+func (r *reader) readValue(e *store.Entry, ver byte) error {
+	typ := byte(typeString)
+	if ver >= 3 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return unexpected(err)
+		}
+		typ = b
+	}
+
+	var err error
+	switch typ {
+	case typeString:
+		e.Val, err = r.readString()
+	case typeList:
+		var n uint64
+		if n, err = binary.ReadUvarint(r); err != nil {
+			break
+		}
+		e.List = make([]string, 0, min(n, 1<<16))
+		for range n {
+			var el string
+			if el, err = r.readString(); err != nil {
+				break
+			}
+			e.List = append(e.List, el)
+		}
+	case typeHash:
+		var n uint64
+		if n, err = binary.ReadUvarint(r); err != nil {
+			break
+		}
+		e.Hash = make([]store.HashField, 0, min(n, 1<<16))
+		for range n {
+			var hf store.HashField
+			if hf.Field, err = r.readString(); err != nil {
+				break
+			}
+			if hf.Val, err = r.readString(); err != nil {
+				break
+			}
+			if ver >= 5 {
+				if hf.ExpiresAt, err = r.readExpiry(); err != nil {
+					break
+				}
+			}
+			e.Hash = append(e.Hash, hf)
+		}
+	default:
+		return fmt.Errorf("snapshot: unknown value type %d", typ)
+	}
+	return unexpected(err)
+}
+
+// readExpiry reads an absolute expiry, the zero time for none.
+func (r *reader) readExpiry() (time.Time, error) {
+	exp, err := binary.ReadVarint(r)
+	if err != nil {
+		return time.Time{}, unexpected(err)
+	}
+	if exp == 0 {
+		return time.Time{}, nil
+	}
+	return time.UnixMilli(exp), nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// SaveFile writes dbs to path atomically: a temporary file is written and
+// fsynced, then renamed over path.
+func SaveFile(path string, dbs map[int][]store.Entry) error {
+	tmp := fmt.Sprintf("%s.tmp-%d", path, os.Getpid())
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = Write(f, dbs)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// LoadFile reads the snapshot at path. A missing file yields an error
+// satisfying errors.Is(err, os.ErrNotExist).
+func LoadFile(path string) (map[int][]store.Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
@@ -0,0 +1,194 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc64"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/snapshot"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func sampleDBs() map[int][]store.Entry {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	return map[int][]store.Entry{
+		0: {
+			{Key: "plain", Val: "value"},
+			{Key: "ttl", Val: "v", ExpiresAt: time.UnixMilli(1_700_000_123_456)},
+			{Key: "bin\x00", Val: string(all)},
+			{Key: "", Val: ""},
+			{Key: "list", List: []string{"a", "", "c"}, ExpiresAt: time.UnixMilli(1_700_000_000_000)},
+			{Key: "hash", Hash: []store.HashField{
+				{Field: "f", Val: "v"},
+				{Field: "", Val: ""},
+				{Field: "ttl", Val: "v", ExpiresAt: time.UnixMilli(1_700_000_000_500)},
+			}},
+		},
+		9: {{Key: "other", Val: "db"}},
+	}
+}
+
+func TestWriteRead_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := snapshot.Write(&buf, sampleDBs()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("GOLIATH\x05")) {
+		t.Fatalf("missing header: %q", buf.Bytes()[:8])
+	}
+
+	got, err := snapshot.Read(&buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !reflect.DeepEqual(got, sampleDBs()) {
+		t.Fatalf("got %+v, want %+v", got, sampleDBs())
+	}
+}
+
+func TestRead_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := snapshot.Write(&buf, nil); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := snapshot.Read(&buf)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("got (%v, %v), want no entries", got, err)
+	}
+}
+
+func TestRead_Version1(t *testing.T) {
+	// a version 1 file holding SET k v, which predates databases
+	body := []byte("GOLIATH\x01\x01\x01k\x01v\x00")
+	var buf bytes.Buffer
+	buf.Write(body)
+	_ = binary.Write(&buf, binary.LittleEndian, crc64.Checksum(body, crc64.MakeTable(crc64.ECMA)))
+
+	got, err := snapshot.Read(&buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := map[int][]store.Entry{0: {{Key: "k", Val: "v"}}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_Version2(t *testing.T) {
+	// a version 2 file holding SET k v in database 1, from before values
+	// had types
+	body := []byte("GOLIATH\x02\x01\x01\x01\x01k\x01v\x00")
+	var buf bytes.Buffer
+	buf.Write(body)
+	_ = binary.Write(&buf, binary.LittleEndian, crc64.Checksum(body, crc64.MakeTable(crc64.ECMA)))
+
+	got, err := snapshot.Read(&buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := map[int][]store.Entry{1: {{Key: "k", Val: "v"}}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_Version3(t *testing.T) {
+	// a version 3 file holding RPUSH l a in database 0, from before hashes
+	body := []byte("GOLIATH\x03\x01\x00\x01\x01l\x01\x01\x01a\x00")
+	var buf bytes.Buffer
+	buf.Write(body)
+	_ = binary.Write(&buf, binary.LittleEndian, crc64.Checksum(body, crc64.MakeTable(crc64.ECMA)))
+
+	got, err := snapshot.Read(&buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := map[int][]store.Entry{0: {{Key: "l", List: []string{"a"}}}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_Version4(t *testing.T) {
+	// a version 4 file holding HSET h f v in database 0, from before
+	// fields had expiries
+	body := []byte("GOLIATH\x04\x01\x00\x01\x01h\x02\x01\x01f\x01v\x00")
+	var buf bytes.Buffer
+	buf.Write(body)
+	_ = binary.Write(&buf, binary.LittleEndian, crc64.Checksum(body, crc64.MakeTable(crc64.ECMA)))
+
+	got, err := snapshot.Read(&buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	want := map[int][]store.Entry{0: {{Key: "h", Hash: []store.HashField{{Field: "f", Val: "v"}}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_Corruption(t *testing.T) {
+	var buf bytes.Buffer
+	if err := snapshot.Write(&buf, sampleDBs()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	good := buf.Bytes()
+
+	flipped := bytes.Clone(good)
+	flipped[len(flipped)/2] ^= 0xff
+	if _, err := snapshot.Read(bytes.NewReader(flipped)); err == nil {
+		t.Fatalf("flipped byte: expected an error")
+	}
+
+	if _, err := snapshot.Read(bytes.NewReader(good[:len(good)-3])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncated: got %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	badMagic := append([]byte("REDIS"), good[5:]...)
+	if _, err := snapshot.Read(bytes.NewReader(badMagic)); !errors.Is(err, snapshot.ErrBadMagic) {
+		t.Fatalf("bad magic: got %v", err)
+	}
+
+	badVersion := bytes.Clone(good)
+	badVersion[7] = 99
+	if _, err := snapshot.Read(bytes.NewReader(badVersion)); !errors.Is(err, snapshot.ErrBadVersion) {
+		t.Fatalf("bad version: got %v", err)
+	}
+
+	badCRC := bytes.Clone(good)
+	badCRC[len(badCRC)-1] ^= 0x01
+	if _, err := snapshot.Read(bytes.NewReader(badCRC)); !errors.Is(err, snapshot.ErrBadChecksum) {
+		t.Fatalf("bad checksum: got %v", err)
+	}
+}
+
+func TestSaveFileLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.gsnap")
+
+	if _, err := snapshot.LoadFile(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing file: got %v, want os.ErrNotExist", err)
+	}
+
+	if err := snapshot.SaveFile(path, sampleDBs()); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := snapshot.LoadFile(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !reflect.DeepEqual(got, sampleDBs()) {
+		t.Fatalf("got %+v, want %+v", got, sampleDBs())
+	}
+
+	files, _ := os.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Fatalf("temporary files left behind: %v", files)
+	}
+}
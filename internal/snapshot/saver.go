@@ -0,0 +1,159 @@
+package snapshot
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+var ErrSaveInProgress = errors.New("background save already in progress")
+
+// Source is the dataset a save dumps, by database.
+type Source interface {
+	Snapshot() map[int][]store.Entry
+}
+
+// Counter reports how many changes have been made to the dataset so far,
+// like the dispatcher's count of mutating commands.
+type Counter interface {
+	Changes() int64
+}
+
+// Saver writes snapshots on demand (SAVE/BGSAVE) and automatically when one
+// of its rules is met, going by how far dirty's count got past the one the
+// last save captured.
+type Saver struct {
+	mu       sync.Mutex
+	cfg      Config
+	src      Source
+	dirty    Counter
+	saving   bool
+	saved    int64
+	lastSave time.Time
+
+	stop chan struct{}
+}
+
+// NewSaver returns a Saver for src. Changes dirty counted before now, e.g.
+// while loading the dataset, count as saved.
+func NewSaver(cfg Config, src Source, dirty Counter) *Saver {
+	s := &Saver{
+		cfg:      cfg,
+		src:      src,
+		dirty:    dirty,
+		saved:    dirty.Changes(),
+		lastSave: time.Now(),
+		stop:     make(chan struct{}),
+	}
+	if len(cfg.Rules) > 0 {
+		go s.ruleLoop()
+	}
+	return s
+}
+
+// Save writes a snapshot and returns once it's on disk.
+func (s *Saver) Save() error {
+	s.mu.Lock()
+	if s.saving {
+		s.mu.Unlock()
+		return ErrSaveInProgress
+	}
+	s.saving = true
+	// counted first, so a change racing the snapshot is saved again
+	// rather than not at all
+	changes := s.dirty.Changes()
+	dbs := s.src.Snapshot()
+	s.mu.Unlock()
+
+	return s.finish(SaveFile(s.cfg.Path, dbs), changes)
+}
+
+// BGSave starts writing a snapshot in the background. The dataset is copied
+// up front (see store.Snapshot), so clients keep being served while the file
+// is encoded and written.
+func (s *Saver) BGSave() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.saving {
+		return ErrSaveInProgress
+	}
+	s.saving = true
+	changes := s.dirty.Changes()
+	dbs := s.src.Snapshot()
+
+	go func() {
+		if err := s.finish(SaveFile(s.cfg.Path, dbs), changes); err != nil {
+			log.Printf("snapshot: background save: %v", err)
+		}
+	}()
+	return nil
+}
+
+// finish records the outcome of a save that captured the dataset when
+// dirty had counted changes.
+func (s *Saver) finish(err error, changes int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saving = false
+	if err != nil {
+		return err
+	}
+	s.saved = changes
+	s.lastSave = time.Now()
+	return nil
+}
+
+func (s *Saver) LastSave() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSave
+}
+
+// Saving reports whether a save is in progress.
+func (s *Saver) Saving() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saving
+}
+
+func (s *Saver) ruleLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.due() {
+				if err := s.BGSave(); err != nil && !errors.Is(err, ErrSaveInProgress) {
+					log.Printf("snapshot: %v", err)
+				}
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Saver) due() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Now().Sub(s.lastSave)
+	for _, r := range s.cfg.Rules {
+		if s.dirty.Changes()-s.saved >= int64(r.Changes) && elapsed >= time.Duration(r.Secs)*time.Second {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops the automatic save rules. It doesn't wait for a background
+// save already running.
+func (s *Saver) Close() {
+	close(s.stop)
+}
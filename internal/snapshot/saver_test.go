@@ -0,0 +1,136 @@
+package snapshot_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/snapshot"
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+// counter stands in for the dispatcher's count of changes.
+type counter struct{ atomic.Int64 }
+
+func (c *counter) Changes() int64 { return c.Load() }
+
+func waitSaved(t *testing.T, s *snapshot.Saver, since time.Time) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Saving() || !s.LastSave().After(since) {
+		if time.Now().After(deadline) {
+			t.Fatalf("save did not finish in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSaver_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.gsnap")
+	dbs := store.NewDatabases()
+	dbs.DB(0).Set("a", "1")
+
+	s := snapshot.NewSaver(snapshot.Config{Path: path}, dbs, &counter{})
+	defer s.Close()
+
+	before := s.LastSave()
+	time.Sleep(time.Millisecond)
+	if err := s.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if !s.LastSave().After(before) {
+		t.Fatalf("LastSave not updated")
+	}
+
+	restored := store.NewDatabases()
+	dump, err := snapshot.LoadFile(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	restored.Restore(dump)
+	if v, ok, _ := restored.DB(0).Get("a"); !ok || v != "1" {
+		t.Fatalf("restored: got (%q,%v), want (\"1\",true)", v, ok)
+	}
+}
+
+func TestSaver_BGSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.gsnap")
+	dbs := store.NewDatabases()
+	mem := dbs.DB(0)
+	mem.Set("a", "1")
+
+	s := snapshot.NewSaver(snapshot.Config{Path: path}, dbs, &counter{})
+	defer s.Close()
+
+	before := s.LastSave()
+	if err := s.BGSave(); err != nil {
+		t.Fatalf("bgsave: %v", err)
+	}
+	mem.Set("a", "2") // after the snapshot point, must not be in the file
+	waitSaved(t, s, before)
+
+	dump, err := snapshot.LoadFile(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if want := map[int][]store.Entry{0: {{Key: "a", Val: "1"}}}; !reflect.DeepEqual(dump, want) {
+		t.Fatalf("got %+v, want %+v", dump, want)
+	}
+}
+
+func TestSaver_SaveErrorKeepsLastSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing-dir", "dump.gsnap")
+	s := snapshot.NewSaver(snapshot.Config{Path: path}, store.NewDatabases(), &counter{})
+	defer s.Close()
+
+	before := s.LastSave()
+	if err := s.Save(); err == nil {
+		t.Fatalf("expected an error saving into a missing directory")
+	}
+	if !s.LastSave().Equal(before) || s.Saving() {
+		t.Fatalf("failed save changed state: last=%v saving=%v", s.LastSave(), s.Saving())
+	}
+}
+
+func TestSaver_RulesTriggerBackgroundSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.gsnap")
+	dbs := store.NewDatabases()
+	dbs.DB(0).Set("k", "v")
+
+	// changes counted before the saver started, e.g. by a replay, don't
+	// count towards its rules
+	dirty := &counter{}
+	dirty.Store(5)
+	s := snapshot.NewSaver(snapshot.Config{Path: path, Rules: []snapshot.Rule{{Secs: 1, Changes: 2}}}, dbs, dirty)
+	defer s.Close()
+	before := s.LastSave()
+
+	dirty.Add(1)
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := snapshot.LoadFile(path); err == nil {
+		t.Fatalf("saved after 1 change, the rule wants 2")
+	}
+	dirty.Add(1)
+	waitSaved(t, s, before)
+
+	if _, err := snapshot.LoadFile(path); err != nil {
+		t.Fatalf("automatic save didn't produce a snapshot: %v", err)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, ok := snapshot.ParseRules("900 1 300 10")
+	if want := []snapshot.Rule{{900, 1}, {300, 10}}; !ok || !reflect.DeepEqual(rules, want) {
+		t.Fatalf("got (%v,%v), want (%v,true)", rules, ok, want)
+	}
+	if rules, ok := snapshot.ParseRules(""); !ok || len(rules) != 0 {
+		t.Fatalf("empty: got (%v,%v), want no rules", rules, ok)
+	}
+	for _, bad := range []string{"900", "x 1", "0 1", "900 -1"} {
+		if _, ok := snapshot.ParseRules(bad); ok {
+			t.Fatalf("ParseRules(%q): expected failure", bad)
+		}
+	}
+}
@@ -8,12 +8,14 @@ import (
 type MemoryConfig struct {
 	SweepIntervalSec int
 	SweepSampleSize  int
+	Databases        int
 }
 
 func LoadMemoryConfig() MemoryConfig {
 	cfg := MemoryConfig{
 		SweepIntervalSec: 60,
 		SweepSampleSize:  20,
+		Databases:        16,
 	}
 
 	if v := os.Getenv("EXPIRED_SWEEP_INTERVAL"); v != "" {
@@ -28,5 +30,11 @@ func LoadMemoryConfig() MemoryConfig {
 		}
 	}
 
+	if v := os.Getenv("DATABASES"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			cfg.Databases = parsed
+		}
+	}
+
 	return cfg
 }
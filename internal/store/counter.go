@@ -0,0 +1,104 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// errors carry redis' wording so handlers can reply with them as is
+var (
+	ErrNotInteger = errors.New("value is not an integer or out of range")
+	ErrOverflow   = errors.New("increment or decrement would overflow")
+	ErrNotFloat   = errors.New("value is not a valid float")
+	ErrNaN        = errors.New("increment would produce NaN or Infinity")
+)
+
+// ParseInt parses s the way redis decides a string holds an integer: no
+// sign other than a leading '-', no leading zeros, no spaces.
+func ParseInt(s string) (int64, bool) {
+	if s == "" || len(s) > 20 || s[0] == '+' || s == "-0" {
+		return 0, false
+	}
+	digits := s
+	if digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// ParseFloat parses s as a finite float.
+func ParseFloat(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// FormatFloat formats f in plain notation with as few digits as round trip,
+// the way redis stores INCRBYFLOAT results.
+func FormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// IncrBy adds delta to the integer stored at k, treating a missing key as 0,
+// and returns the new value. The key keeps its TTL.
+func (mem *memory) IncrBy(k string, delta int64) (int64, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	e, ok := mem.m[k]
+	if !ok || e.expired(mem.clock.Now()) {
+		e = entry{val: stringValue("0")}
+	}
+
+	cur, err := e.str()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := ParseInt(cur)
+	if !ok {
+		return 0, ErrNotInteger
+	}
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+
+	n += delta
+	e.val = stringValue(strconv.FormatInt(n, 10))
+	mem.put(k, e)
+	return n, nil
+}
+
+// IncrByFloat is IncrBy for floats.
+func (mem *memory) IncrByFloat(k string, delta float64) (float64, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	e, ok := mem.m[k]
+	if !ok || e.expired(mem.clock.Now()) {
+		e = entry{val: stringValue("0")}
+	}
+
+	cur, err := e.str()
+	if err != nil {
+		return 0, err
+	}
+	f, ok := ParseFloat(cur)
+	if !ok {
+		return 0, ErrNotFloat
+	}
+	f += delta
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, ErrNaN
+	}
+
+	e.val = stringValue(FormatFloat(f))
+	mem.put(k, e)
+	return f, nil
+}
@@ -0,0 +1,127 @@
+package store_test
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestParseInt(t *testing.T) {
+	valid := map[string]int64{
+		"0": 0, "7": 7, "-7": -7,
+		"9223372036854775807":  math.MaxInt64,
+		"-9223372036854775808": math.MinInt64,
+	}
+	for s, want := range valid {
+		if n, ok := store.ParseInt(s); !ok || n != want {
+			t.Errorf("ParseInt(%q) = %d, %v; want %d", s, n, ok, want)
+		}
+	}
+	for _, s := range []string{"", "+1", "01", "-0", " 1", "1 ", "1.0", "9223372036854775808", "abc"} {
+		if _, ok := store.ParseInt(s); ok {
+			t.Errorf("ParseInt(%q) accepted", s)
+		}
+	}
+}
+
+func TestIncrBy(t *testing.T) {
+	mem := store.NewMemory()
+
+	if n, err := mem.IncrBy("k", 5); err != nil || n != 5 {
+		t.Fatalf("IncrBy on missing key: got %d, %v", n, err)
+	}
+	if n, err := mem.IncrBy("k", -7); err != nil || n != -2 {
+		t.Fatalf("IncrBy: got %d, %v", n, err)
+	}
+	if v, _, _ := mem.Get("k"); v != "-2" {
+		t.Fatalf("stored value: got %q", v)
+	}
+
+	mem.Set("k", strconv.FormatInt(math.MaxInt64, 10))
+	if _, err := mem.IncrBy("k", 1); !errors.Is(err, store.ErrOverflow) {
+		t.Fatalf("overflow: got %v", err)
+	}
+	mem.Set("k", strconv.FormatInt(math.MinInt64, 10))
+	if _, err := mem.IncrBy("k", -1); !errors.Is(err, store.ErrOverflow) {
+		t.Fatalf("underflow: got %v", err)
+	}
+
+	mem.Set("k", "12 ")
+	if _, err := mem.IncrBy("k", 1); !errors.Is(err, store.ErrNotInteger) {
+		t.Fatalf("non-integer: got %v", err)
+	}
+}
+
+func TestIncrBy_KeepsTTL(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+	mem.SetEx("k", "1", 10*time.Second)
+
+	mem.IncrBy("k", 1)
+	mem.IncrByFloat("k", 0.5)
+	if secs, _, hasExp := mem.TTL("k"); !hasExp || secs != 10 {
+		t.Fatalf("TTL after increments: got %d (hasExp=%v), want 10", secs, hasExp)
+	}
+
+	// an expired counter starts over from zero
+	fc.Advance(time.Minute)
+	if n, err := mem.IncrBy("k", 1); err != nil || n != 1 {
+		t.Fatalf("IncrBy on expired key: got %d, %v", n, err)
+	}
+	if _, _, hasExp := mem.TTL("k"); hasExp {
+		t.Fatalf("restarted counter kept the old expiry")
+	}
+}
+
+func TestIncrBy_Concurrent(t *testing.T) {
+	mem := store.NewMemory()
+	const workers, each = 8, 1000
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range each {
+				mem.IncrBy("k", 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _, _ := mem.Get("k"); v != strconv.Itoa(workers*each) {
+		t.Fatalf("got %s, want %d", v, workers*each)
+	}
+}
+
+func TestIncrByFloat(t *testing.T) {
+	mem := store.NewMemory()
+	mem.Set("k", "10.50")
+
+	if f, err := mem.IncrByFloat("k", 0.1); err != nil || f != 10.6 {
+		t.Fatalf("IncrByFloat: got %v, %v", f, err)
+	}
+	if v, _, _ := mem.Get("k"); v != "10.6" {
+		t.Fatalf("stored value: got %q", v)
+	}
+
+	mem.Set("k", "5.0e3")
+	mem.IncrByFloat("k", 2e3)
+	if v, _, _ := mem.Get("k"); v != "7000" {
+		t.Fatalf("stored value: got %q, want plain notation", v)
+	}
+
+	mem.Set("k", "abc")
+	if _, err := mem.IncrByFloat("k", 1); !errors.Is(err, store.ErrNotFloat) {
+		t.Fatalf("non-float: got %v", err)
+	}
+	mem.Set("k", "1e308")
+	if _, err := mem.IncrByFloat("k", 1e308); !errors.Is(err, store.ErrNaN) {
+		t.Fatalf("infinite result: got %v", err)
+	}
+}
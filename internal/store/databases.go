@@ -0,0 +1,182 @@
+package store
+
+import (
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Databases is a fixed set of numbered keyspaces, each its own KV, like
+// redis's logical databases. Clients pick one by index.
+type Databases struct {
+	// guards the order of dbs, which SWAPDB changes; held exclusively by
+	// the operations that lock two databases so they can't deadlock
+	mu  sync.RWMutex
+	dbs []*memory
+}
+
+// NewDatabases creates the number of databases set in the environment,
+// 16 by default.
+func NewDatabases() *Databases {
+	cfg := LoadMemoryConfig()
+	return newDatabases(realClock{}, cfg)
+}
+
+func NewDatabasesWithClock(c Clock) *Databases { // <-- for tests
+	cfg := LoadMemoryConfig()
+	return newDatabases(c, cfg)
+}
+
+func newDatabases(c Clock, cfg MemoryConfig) *Databases {
+	s := &Databases{dbs: make([]*memory, cfg.Databases)}
+	for i := range s.dbs {
+		s.dbs[i] = newMemory(c, cfg)
+	}
+	return s
+}
+
+// Len returns the number of databases.
+func (s *Databases) Len() int {
+	return len(s.dbs)
+}
+
+// DB returns database i, which must be in range.
+func (s *Databases) DB(i int) KV {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dbs[i]
+}
+
+// OnExpire registers fn to be called for keys expiring in any database,
+// with the index the database has at the time.
+func (s *Databases) OnExpire(fn func(db int, k string)) {
+	for _, mem := range s.dbs {
+		mem.OnExpire(func(k string) { fn(s.index(mem), k) })
+	}
+}
+
+// OnFieldExpire registers fn to be called for hash fields expiring in any
+// database, like OnExpire.
+func (s *Databases) OnFieldExpire(fn func(db int, k string, fields []string)) {
+	for _, mem := range s.dbs {
+		mem.OnFieldExpire(func(k string, fields []string) { fn(s.index(mem), k, fields) })
+	}
+}
+
+func (s *Databases) index(mem *memory) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i, m := range s.dbs {
+		if m == mem {
+			return i
+		}
+	}
+	return -1
+}
+
+// Swap exchanges the contents of databases i and j, so clients using one
+// see the other's keys from then on.
+func (s *Databases) Swap(i, j int) {
+	s.mu.Lock()
+	s.dbs[i], s.dbs[j] = s.dbs[j], s.dbs[i]
+	s.mu.Unlock()
+}
+
+// Move moves k, with its TTL, from database from to database to. It does
+// nothing if k doesn't exist in from or already exists in to, and reports
+// whether it moved.
+func (s *Databases) Move(k string, from, to int) bool {
+	if from == to {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, dst := s.dbs[from], s.dbs[to]
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	e, ok := src.m[k]
+	if !ok || e.expired(src.clock.Now()) {
+		return false
+	}
+	if d, ok := dst.m[k]; ok && !d.expired(dst.clock.Now()) {
+		return false
+	}
+	src.del(k)
+	dst.del(k)
+	dst.put(k, e)
+	return true
+}
+
+// Copy copies src in database from to dst in database to, like KV.Copy
+// across databases.
+func (s *Databases) Copy(src string, from int, dst string, to int, replace bool) bool {
+	if from == to {
+		return s.DB(from).Copy(src, dst, replace)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sm, dm := s.dbs[from], s.dbs[to]
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	e, ok := sm.m[src]
+	if !ok || e.expired(sm.clock.Now()) {
+		return false
+	}
+	if d, ok := dm.m[dst]; ok && !d.expired(dm.clock.Now()) && !replace {
+		return false
+	}
+	dm.del(dst)
+	dm.put(dst, entry{val: e.val.clone(), expiresAt: e.expiresAt})
+	return true
+}
+
+// FlushAll empties every database.
+func (s *Databases) FlushAll() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, mem := range s.dbs {
+		mem.Flush()
+	}
+}
+
+// Snapshot copies the live keys of every non-empty database, by index.
+// Like memory.Snapshot it doesn't stop writers, SWAPDB included, so it's
+// only a point in time if the caller holds them off.
+func (s *Databases) Snapshot() map[int][]Entry {
+	s.mu.RLock()
+	dbs := slices.Clone(s.dbs)
+	s.mu.RUnlock()
+
+	out := make(map[int][]Entry)
+	for i, mem := range dbs {
+		if entries := mem.Snapshot(); len(entries) > 0 {
+			out[i] = entries
+		}
+	}
+	return out
+}
+
+// Restore loads a snapshot taken with Snapshot. It returns the indexes of
+// databases that were skipped because they're out of range.
+func (s *Databases) Restore(dbs map[int][]Entry) []int {
+	var skipped []int
+	for i, entries := range dbs {
+		if i < 0 || i >= len(s.dbs) {
+			skipped = append(skipped, i)
+			continue
+		}
+		s.DB(i).Restore(entries)
+	}
+	sort.Ints(skipped)
+	return skipped
+}
@@ -0,0 +1,114 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestDatabases_Count(t *testing.T) {
+	if n := store.NewDatabases().Len(); n != 16 {
+		t.Fatalf("default: got %d databases, want 16", n)
+	}
+	t.Setenv("DATABASES", "4")
+	if n := store.NewDatabases().Len(); n != 4 {
+		t.Fatalf("DATABASES=4: got %d databases", n)
+	}
+}
+
+func TestDatabases_SwapAndExpiry(t *testing.T) {
+	clk := newFakeClock(time.Unix(1_700_000_000, 0))
+	dbs := store.NewDatabasesWithClock(clk)
+
+	var expired []int
+	dbs.OnExpire(func(db int, k string) { expired = append(expired, db) })
+
+	dbs.DB(1).SetEx("k", "v", time.Second)
+	dbs.Swap(1, 2)
+	if _, ok, _ := dbs.DB(1).Get("k"); ok {
+		t.Fatalf("k still in db 1 after the swap")
+	}
+
+	clk.Advance(2 * time.Second)
+	if _, ok, _ := dbs.DB(2).Get("k"); ok {
+		t.Fatalf("expected k to be expired")
+	}
+	// reported under the index the keyspace has now
+	if !reflect.DeepEqual(expired, []int{2}) {
+		t.Fatalf("expired in dbs %v, want [2]", expired)
+	}
+}
+
+func TestDatabases_Move(t *testing.T) {
+	clk := newFakeClock(time.Unix(1_700_000_000, 0))
+	dbs := store.NewDatabasesWithClock(clk)
+
+	dbs.DB(0).SetEx("k", "v", time.Minute)
+	if !dbs.Move("k", 0, 1) {
+		t.Fatalf("Move failed")
+	}
+	if _, ok, _ := dbs.DB(0).Get("k"); ok {
+		t.Fatalf("k left behind in db 0")
+	}
+	if ttl, _, hasExp := dbs.DB(1).TTL("k"); !hasExp || ttl != 60 {
+		t.Fatalf("TTL not moved: %d, %v", ttl, hasExp)
+	}
+
+	dbs.DB(0).Set("k", "other")
+	if dbs.Move("k", 0, 1) {
+		t.Fatalf("Move overwrote an existing key")
+	}
+	if dbs.Move("missing", 0, 1) || dbs.Move("k", 0, 0) {
+		t.Fatalf("Move of a missing key or to the same db succeeded")
+	}
+}
+
+func TestDatabases_CopyAcross(t *testing.T) {
+	dbs := store.NewDatabases()
+	dbs.DB(0).Set("a", "1")
+	dbs.DB(3).Set("b", "old")
+
+	if dbs.Copy("a", 0, "b", 3, false) {
+		t.Fatalf("Copy overwrote without replace")
+	}
+	if !dbs.Copy("a", 0, "b", 3, true) {
+		t.Fatalf("Copy with replace failed")
+	}
+	if v, _, _ := dbs.DB(3).Get("b"); v != "1" {
+		t.Fatalf("b in db 3: got %q, want 1", v)
+	}
+	if v, _, _ := dbs.DB(0).Get("a"); v != "1" {
+		t.Fatalf("source changed: %q", v)
+	}
+}
+
+func TestDatabases_FlushSnapshotRestore(t *testing.T) {
+	dbs := store.NewDatabases()
+	dbs.DB(0).Set("a", "1")
+	dbs.DB(5).Set("b", "2")
+
+	snap := dbs.Snapshot()
+	want := map[int][]store.Entry{0: {{Key: "a", Val: "1"}}, 5: {{Key: "b", Val: "2"}}}
+	if !reflect.DeepEqual(snap, want) {
+		t.Fatalf("Snapshot: got %+v, want %+v", snap, want)
+	}
+
+	dbs.DB(5).Flush()
+	if n := dbs.DB(5).Len(); n != 0 {
+		t.Fatalf("Flush left %d keys", n)
+	}
+	dbs.FlushAll()
+	if n := dbs.DB(0).Len(); n != 0 {
+		t.Fatalf("FlushAll left %d keys in db 0", n)
+	}
+
+	snap[99] = []store.Entry{{Key: "c", Val: "3"}}
+	if skipped := dbs.Restore(snap); !reflect.DeepEqual(skipped, []int{99}) {
+		t.Fatalf("Restore skipped %v, want [99]", skipped)
+	}
+	if v, _, _ := dbs.DB(5).Get("b"); v != "2" {
+		t.Fatalf("restored b: got %q", v)
+	}
+}
@@ -0,0 +1,397 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrHashNotInteger = errors.New("hash value is not an integer")
+	ErrHashNotFloat   = errors.New("hash value is not a float")
+)
+
+// hashAt returns the hash at k, nil if k doesn't exist, recording the
+// access. Must be called with mu held.
+func (mem *memory) hashAt(k string, now time.Time) (*hashValue, error) {
+	e, ok := mem.m[k]
+	if !ok || e.expired(now) {
+		return nil, nil
+	}
+	h, ok := e.val.(*hashValue)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	e.meta.touch(now)
+	return h, nil
+}
+
+// liveHash is hashAt for writers: fields that have expired are removed
+// first, to be reported once mu is released with unlock. Must be called
+// with mu held for writing. A hash whose fields have all expired is
+// already missing to hashAt, so this never empties one.
+func (mem *memory) liveHash(k string, now time.Time) (*hashValue, error) {
+	h, err := mem.hashAt(k, now)
+	if h != nil && h.stale(now) {
+		mem.purge(k, h, now)
+	}
+	return h, err
+}
+
+// expiredFields are fields of the hash at key removed because their TTL
+// passed.
+type expiredFields struct {
+	key    string
+	fields []string
+}
+
+// OnFieldExpire registers fn to be called with the fields removed from the
+// hash at k because their TTL passed, whether the sweeper found them or a
+// write did, but not when the last of them takes the whole key, which
+// OnExpire reports. Like OnExpire's, fn runs outside the store's lock.
+func (mem *memory) OnFieldExpire(fn func(k string, fields []string)) {
+	mem.mu.Lock()
+	mem.onFieldExpire = fn
+	mem.mu.Unlock()
+}
+
+// purge removes the fields of h, the hash at k, that have expired by now,
+// queueing them for unlock to report. Must be called with mu held for
+// writing.
+func (mem *memory) purge(k string, h *hashValue, now time.Time) {
+	if gone := h.purge(now); len(gone) > 0 {
+		mem.expiredFields = append(mem.expiredFields, expiredFields{k, gone})
+	}
+}
+
+// unlock releases mu, held for writing, then reports the hash fields purge
+// removed meanwhile.
+func (mem *memory) unlock() {
+	expired, fn := mem.expiredFields, mem.onFieldExpire
+	mem.expiredFields = nil
+	mem.mu.Unlock()
+
+	if fn != nil {
+		for _, x := range expired {
+			fn(x.key, x.fields)
+		}
+	}
+}
+
+// readHash calls fn with the hash at k, nil if k doesn't exist, unless k
+// holds another type. fn runs under the read lock, or under the write lock
+// when the hash has fields to expire, which are removed before it runs.
+func (mem *memory) readHash(k string, fn func(h *hashValue)) error {
+	now := mem.clock.Now()
+
+	mem.mu.RLock()
+	h, err := mem.hashAt(k, now)
+	if err != nil || h == nil || !h.stale(now) {
+		defer mem.mu.RUnlock()
+		if err == nil {
+			fn(h)
+		}
+		return err
+	}
+	mem.mu.RUnlock()
+
+	mem.mu.Lock()
+	defer mem.unlock()
+	if h, err = mem.liveHash(k, now); err == nil {
+		fn(h)
+	}
+	return err
+}
+
+// hashFor returns the hash at k, creating it if missing. Must be called
+// with mu held.
+func (mem *memory) hashFor(k string, now time.Time) (*hashValue, error) {
+	h, err := mem.liveHash(k, now)
+	if h == nil && err == nil {
+		h = &hashValue{}
+		mem.put(k, entry{val: h})
+	}
+	return h, err
+}
+
+// HSet sets fields of the hash at k from alternating fields and values,
+// creating it if missing, and returns how many fields are new.
+func (mem *memory) HSet(k string, pairs ...string) (int, error) {
+	mem.mu.Lock()
+	defer mem.unlock()
+
+	h, err := mem.hashFor(k, mem.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	var added int
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if h.set(pairs[i], pairs[i+1]) {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// HSetNX sets field f of the hash at k only if f doesn't exist yet, and
+// reports whether it did.
+func (mem *memory) HSetNX(k, f, v string) (bool, error) {
+	mem.mu.Lock()
+	defer mem.unlock()
+
+	h, err := mem.hashFor(k, mem.clock.Now())
+	if err != nil {
+		return false, err
+	}
+	if _, ok := h.get(f); ok {
+		return false, nil
+	}
+	h.set(f, v)
+	return true, nil
+}
+
+// HGet returns field f of the hash at k.
+func (mem *memory) HGet(k, f string) (string, bool, error) {
+	var v string
+	var ok bool
+	err := mem.readHash(k, func(h *hashValue) {
+		if h != nil {
+			v, ok = h.get(f)
+		}
+	})
+	return v, ok, err
+}
+
+// HMGet returns each of fields of the hash at k and whether it exists.
+func (mem *memory) HMGet(k string, fields ...string) ([]string, []bool, error) {
+	vals, found := make([]string, len(fields)), make([]bool, len(fields))
+	err := mem.readHash(k, func(h *hashValue) {
+		if h == nil {
+			return
+		}
+		for i, f := range fields {
+			vals[i], found[i] = h.get(f)
+		}
+	})
+	return vals, found, err
+}
+
+// HDel removes fields from the hash at k and returns how many existed, and
+// whether that emptied and so deleted the hash.
+func (mem *memory) HDel(k string, fields ...string) (int, bool, error) {
+	mem.mu.Lock()
+	defer mem.unlock()
+
+	h, err := mem.liveHash(k, mem.clock.Now())
+	if h == nil {
+		return 0, false, err
+	}
+	var n int
+	for _, f := range fields {
+		if h.del(f) {
+			n++
+		}
+	}
+	if h.len() == 0 {
+		mem.del(k)
+	}
+	return n, h.len() == 0, nil
+}
+
+// HLen returns the number of fields in the hash at k.
+func (mem *memory) HLen(k string) (int, error) {
+	var n int
+	err := mem.readHash(k, func(h *hashValue) {
+		if h != nil {
+			n = h.len()
+		}
+	})
+	return n, err
+}
+
+// HStrLen returns the length of field f of the hash at k, 0 if missing.
+func (mem *memory) HStrLen(k, f string) (int, error) {
+	v, _, err := mem.HGet(k, f)
+	return len(v), err
+}
+
+// HExists reports whether the hash at k has field f.
+func (mem *memory) HExists(k, f string) (bool, error) {
+	_, ok, err := mem.HGet(k, f)
+	return ok, err
+}
+
+// HGetAll returns the fields and values of the hash at k, alternating.
+func (mem *memory) HGetAll(k string) ([]string, error) {
+	return mem.hashFields(k, true, true)
+}
+
+// HKeys returns the fields of the hash at k.
+func (mem *memory) HKeys(k string) ([]string, error) {
+	return mem.hashFields(k, true, false)
+}
+
+// HVals returns the values of the hash at k.
+func (mem *memory) HVals(k string) ([]string, error) {
+	return mem.hashFields(k, false, true)
+}
+
+func (mem *memory) hashFields(k string, names, vals bool) ([]string, error) {
+	var out []string
+	err := mem.readHash(k, func(h *hashValue) {
+		if h == nil {
+			return
+		}
+		out = make([]string, 0, h.len()*2)
+		h.each(func(hf hashField) bool {
+			if names {
+				out = append(out, hf.name)
+			}
+			if vals {
+				out = append(out, hf.val)
+			}
+			return true
+		})
+	})
+	return out, err
+}
+
+// HIncrBy adds delta to the integer in field f of the hash at k, treating
+// a missing key or field as 0, and returns the new value.
+func (mem *memory) HIncrBy(k, f string, delta int64) (int64, error) {
+	mem.mu.Lock()
+	defer mem.unlock()
+
+	h, err := mem.liveHash(k, mem.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	var cur hashField
+	if h != nil {
+		var ok bool
+		if cur, ok = h.field(f); ok {
+			if n, ok = ParseInt(cur.val); !ok {
+				return 0, ErrHashNotInteger
+			}
+		}
+	}
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+
+	n += delta
+	if h == nil {
+		h = &hashValue{}
+		mem.put(k, entry{val: h})
+	}
+	h.set(f, strconv.FormatInt(n, 10))
+	if !cur.expiresAt.IsZero() {
+		h.expire(f, cur.expiresAt) // the field keeps its TTL
+	}
+	return n, nil
+}
+
+// HIncrByFloat is HIncrBy for floats. It also returns the expiry the field
+// keeps, zero if it has none, since the increment is journaled as an HSET,
+// which would drop it.
+func (mem *memory) HIncrByFloat(k, f string, delta float64) (float64, time.Time, error) {
+	mem.mu.Lock()
+	defer mem.unlock()
+
+	h, err := mem.liveHash(k, mem.clock.Now())
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	var n float64
+	var cur hashField
+	if h != nil {
+		var ok bool
+		if cur, ok = h.field(f); ok {
+			if n, ok = ParseFloat(cur.val); !ok {
+				return 0, time.Time{}, ErrHashNotFloat
+			}
+		}
+	}
+	n += delta
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, time.Time{}, ErrNaN
+	}
+
+	if h == nil {
+		h = &hashValue{}
+		mem.put(k, entry{val: h})
+	}
+	h.set(f, FormatFloat(n))
+	if !cur.expiresAt.IsZero() {
+		h.expire(f, cur.expiresAt) // the field keeps its TTL
+	}
+	return n, cur.expiresAt, nil
+}
+
+// HScan returns about count fields and values of the hash at k,
+// alternating, starting from cursor, along with the cursor to pass next;
+// 0 starts an iteration and is returned at its end. A field that exists
+// for the whole iteration is returned exactly once.
+func (mem *memory) HScan(k string, cursor uint64, count int) ([]string, uint64, error) {
+	var out []string
+	var next uint64
+	err := mem.readHash(k, func(h *hashValue) {
+		if h == nil {
+			return
+		}
+		var fields []hashField
+		fields, next = h.scan(cursor, max(count, 1))
+		out = make([]string, 0, 2*len(fields))
+		for _, hf := range fields {
+			out = append(out, hf.name, hf.val)
+		}
+	})
+	return out, next, err
+}
+
+// HRandField returns fields of the hash at k picked at random, with their
+// values, alternating. A positive count picks that many distinct fields,
+// or all of them if there are fewer; a negative one picks -count fields
+// that may repeat, all of them under the lock, so callers keep it small.
+func (mem *memory) HRandField(k string, count int) ([]string, error) {
+	var out []string
+	err := mem.readHash(k, func(h *hashValue) {
+		if h != nil && count != 0 {
+			out = randFields(h, count)
+		}
+	})
+	return out, err
+}
+
+func randFields(h *hashValue, count int) []string {
+	if count < 0 {
+		var out []string
+		for range -count {
+			hf := h.random()
+			out = append(out, hf.name, hf.val)
+		}
+		return out
+	}
+
+	all := make([]hashField, 0, h.len())
+	h.each(func(hf hashField) bool {
+		all = append(all, hf)
+		return true
+	})
+	if count < len(all) {
+		// a partial shuffle leaves count random fields at the front
+		for i := range count {
+			j := i + rand.IntN(len(all)-i)
+			all[i], all[j] = all[j], all[i]
+		}
+		all = all[:count]
+	}
+	out := make([]string, 0, 2*len(all))
+	for _, hf := range all {
+		out = append(out, hf.name, hf.val)
+	}
+	return out
+}
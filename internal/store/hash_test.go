@@ -0,0 +1,183 @@
+package store_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestHSetHGetHDel(t *testing.T) {
+	mem := store.NewMemory()
+
+	if n, err := mem.HSet("h", "a", "1", "b", "2", "a", "3"); n != 2 || err != nil {
+		t.Fatalf("HSet: got %d, %v", n, err)
+	}
+	if v, ok, _ := mem.HGet("h", "a"); !ok || v != "3" {
+		t.Fatalf("HGet: got %q, %v", v, ok)
+	}
+	if ok, _ := mem.HSetNX("h", "a", "x"); ok {
+		t.Fatalf("HSetNX on an existing field succeeded")
+	}
+	if ok, _ := mem.HSetNX("h", "c", "x"); !ok {
+		t.Fatalf("HSetNX on a new field failed")
+	}
+	vals, found, _ := mem.HMGet("h", "c", "nope", "b")
+	if !reflect.DeepEqual(vals, []string{"x", "", "2"}) || !reflect.DeepEqual(found, []bool{true, false, true}) {
+		t.Fatalf("HMGet: got %q, %v", vals, found)
+	}
+	if all, _ := mem.HGetAll("h"); !reflect.DeepEqual(all, []string{"a", "3", "b", "2", "c", "x"}) {
+		t.Fatalf("HGetAll: got %q", all)
+	}
+	if n, _ := mem.HStrLen("h", "c"); n != 1 {
+		t.Fatalf("HStrLen: got %d", n)
+	}
+
+	n, emptied, _ := mem.HDel("h", "a", "nope")
+	if n != 1 || emptied {
+		t.Fatalf("HDel: got %d, %v", n, emptied)
+	}
+	n, emptied, _ = mem.HDel("h", "b", "c")
+	if n != 2 || !emptied || mem.Exists("h") != 0 {
+		t.Fatalf("HDel of the last fields: got %d, %v", n, emptied)
+	}
+}
+
+func TestHash_Encoding(t *testing.T) {
+	mem := store.NewMemory()
+	mem.HSet("small", "f", "v")
+	mem.HSet("long", "f", strings.Repeat("x", 65))
+	for i := range 129 {
+		mem.HSet("many", strconv.Itoa(i), "v")
+	}
+
+	for k, want := range map[string]string{"small": "listpack", "long": "hashtable", "many": "hashtable"} {
+		if info, _ := mem.Object(k); info.Encoding != want {
+			t.Errorf("%s: encoding %q, want %q", k, info.Encoding, want)
+		}
+	}
+	// the map keeps the order fields were added in
+	keys, _ := mem.HKeys("many")
+	if len(keys) != 129 || keys[0] != "0" || keys[128] != "128" {
+		t.Fatalf("HKeys: got %d fields", len(keys))
+	}
+}
+
+func TestHIncrBy(t *testing.T) {
+	mem := store.NewMemory()
+
+	if n, _ := mem.HIncrBy("h", "n", 5); n != 5 {
+		t.Fatalf("HIncrBy on a missing key: got %d", n)
+	}
+	if n, _ := mem.HIncrBy("h", "n", -7); n != -2 {
+		t.Fatalf("HIncrBy: got %d", n)
+	}
+	mem.HSet("h", "s", "abc", "big", "9223372036854775807")
+	if _, err := mem.HIncrBy("h", "s", 1); !errors.Is(err, store.ErrHashNotInteger) {
+		t.Fatalf("HIncrBy on a non-integer: got %v", err)
+	}
+	if _, err := mem.HIncrBy("h", "big", 1); !errors.Is(err, store.ErrOverflow) {
+		t.Fatalf("HIncrBy overflow: got %v", err)
+	}
+
+	if f, _, _ := mem.HIncrByFloat("h", "n", 0.5); f != -1.5 {
+		t.Fatalf("HIncrByFloat: got %v", f)
+	}
+	if v, _, _ := mem.HGet("h", "n"); v != "-1.5" {
+		t.Fatalf("stored float: got %q", v)
+	}
+	if _, _, err := mem.HIncrByFloat("h", "s", 1); !errors.Is(err, store.ErrHashNotFloat) {
+		t.Fatalf("HIncrByFloat on a non-float: got %v", err)
+	}
+}
+
+func TestHScan(t *testing.T) {
+	mem := store.NewMemory()
+	mem.HSet("small", "a", "1", "b", "2")
+	if got, next, _ := mem.HScan("small", 0, 1); next != 0 || len(got) != 4 {
+		t.Fatalf("small hash: got %q, next %d", got, next)
+	}
+
+	for i := range 300 {
+		mem.HSet("big", strconv.Itoa(i), "v", "x"+strconv.Itoa(i), "v")
+	}
+	seen := make(map[string]int)
+	var cursor uint64
+	for step := 0; ; step++ {
+		pairs, next, err := mem.HScan("big", cursor, 20)
+		if err != nil {
+			t.Fatalf("HScan: %v", err)
+		}
+		for i := 0; i < len(pairs); i += 2 {
+			seen[pairs[i]]++
+		}
+		// fields deleted and added mid-iteration don't disturb the rest
+		mem.HDel("big", "x"+strconv.Itoa(step*7))
+		mem.HSet("big", "new"+strconv.Itoa(step), "v")
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	for i := range 300 {
+		if n := seen[strconv.Itoa(i)]; n != 1 {
+			t.Fatalf("field %d returned %d times", i, n)
+		}
+	}
+}
+
+func TestHRandField(t *testing.T) {
+	mem := store.NewMemory()
+	if got, _ := mem.HRandField("h", 1); got != nil {
+		t.Fatalf("missing key: got %q", got)
+	}
+	mem.HSet("h", "a", "1", "b", "2", "c", "3")
+
+	got, _ := mem.HRandField("h", 2)
+	if len(got) != 4 || got[0] == got[2] {
+		t.Fatalf("distinct: got %q", got)
+	}
+	all, _ := mem.HRandField("h", 10)
+	var fields []string
+	for i := 0; i < len(all); i += 2 {
+		fields = append(fields, all[i])
+	}
+	slices.Sort(fields)
+	if !reflect.DeepEqual(fields, []string{"a", "b", "c"}) {
+		t.Fatalf("count past the size: got %q", all)
+	}
+	if got, _ := mem.HRandField("h", -7); len(got) != 14 {
+		t.Fatalf("repeating: got %d elements", len(got))
+	}
+}
+
+func TestHash_SnapshotCopy(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+	for i := range 200 {
+		mem.HSet("big", strconv.Itoa(i), strconv.Itoa(i*i))
+	}
+	mem.HSet("small", "a", "1")
+
+	mem.Copy("big", "copy", false)
+	mem.HSet("copy", "0", "changed")
+	if v, _, _ := mem.HGet("big", "0"); v != "0" {
+		t.Fatalf("Copy shares fields: %q", v)
+	}
+
+	other := store.NewMemoryWithClock(fc)
+	other.Restore(mem.Snapshot())
+	for _, k := range []string{"big", "small", "copy"} {
+		want, _ := mem.HGetAll(k)
+		if got, _ := other.HGetAll(k); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s after restore: got %d elements, want %d", k, len(got), len(want))
+		}
+	}
+	if typ := other.Type("small"); typ != store.KindHash {
+		t.Fatalf("Type after restore: %s", typ)
+	}
+}
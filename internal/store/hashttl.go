@@ -0,0 +1,84 @@
+package store
+
+import "time"
+
+// HExpire changes the expiry of each of fields of the hash at k under
+// opt's conditions, the way ExpireWith does for a key, and returns what
+// it did to each, ExpireNoField for a field that doesn't exist. A time
+// that has already passed deletes the field; the bool reports whether
+// that emptied and so deleted the hash. Like ExpireWith, it also returns
+// the expiry it went by.
+func (mem *memory) HExpire(k string, opt ExpireOptions, fields ...string) ([]ExpireResult, time.Time, bool, error) {
+	mem.mu.Lock()
+	defer mem.unlock()
+
+	out := make([]ExpireResult, len(fields))
+	for i := range out {
+		out[i] = ExpireNoField
+	}
+	now := mem.clock.Now()
+	h, err := mem.liveHash(k, now)
+	if h == nil {
+		return out, time.Time{}, false, err
+	}
+
+	at := opt.At
+	if at.IsZero() && !opt.Persist {
+		at = now.Add(opt.TTL)
+	}
+	for i, f := range fields {
+		hf, ok := h.field(f)
+		switch {
+		case !ok:
+		case opt.Persist:
+			if out[i] = ExpireSkipped; !hf.expiresAt.IsZero() {
+				h.expire(f, time.Time{})
+				out[i] = ExpireSet
+			}
+		case !opt.Cond.allows(hf.expiresAt, at):
+			out[i] = ExpireSkipped
+		case !at.After(now):
+			h.del(f)
+			out[i] = ExpireDeleted
+		default:
+			h.expire(f, at)
+			out[i] = ExpireSet
+		}
+	}
+	if h.len() == 0 {
+		mem.del(k)
+	}
+	return out, at, h.len() == 0, nil
+}
+
+// HExpireTime returns when each of fields of the hash at k expires, the
+// zero time if it doesn't, and whether it exists.
+func (mem *memory) HExpireTime(k string, fields ...string) ([]time.Time, []bool, error) {
+	ats, found := make([]time.Time, len(fields)), make([]bool, len(fields))
+	err := mem.readHash(k, func(h *hashValue) {
+		if h == nil {
+			return
+		}
+		for i, f := range fields {
+			var hf hashField
+			hf, found[i] = h.field(f)
+			ats[i] = hf.expiresAt
+		}
+	})
+	return ats, found, err
+}
+
+// HTTL returns the time left before each of fields of the hash at k
+// expires, -1 for a field without a TTL as PTTL replies, and whether it
+// exists.
+func (mem *memory) HTTL(k string, fields ...string) ([]time.Duration, []bool, error) {
+	ats, found, err := mem.HExpireTime(k, fields...)
+	now := mem.clock.Now()
+	ttls := make([]time.Duration, len(fields))
+	for i, at := range ats {
+		if ttls[i] = -1; !at.IsZero() {
+			ttls[i] = max(at.Sub(now), 0)
+		}
+	}
+	return ttls, found, err
+}
@@ -0,0 +1,207 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestHExpire(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fc := newFakeClock(start)
+	mem := store.NewMemoryWithClock(fc)
+	mem.HSet("h", "a", "1", "b", "2", "c", "3")
+
+	res, _, _, _ := mem.HExpire("h", store.ExpireOptions{TTL: 10 * time.Second}, "a", "nope")
+	if want := []store.ExpireResult{store.ExpireSet, store.ExpireNoField}; !reflect.DeepEqual(res, want) {
+		t.Fatalf("HExpire: got %v, want %v", res, want)
+	}
+	cases := []struct {
+		opt  store.ExpireOptions
+		want store.ExpireResult
+	}{
+		{store.ExpireOptions{Cond: store.ExpireNX, TTL: time.Second}, store.ExpireSkipped},
+		{store.ExpireOptions{Cond: store.ExpireGT, TTL: time.Second}, store.ExpireSkipped},
+		{store.ExpireOptions{Cond: store.ExpireLT, TTL: 5 * time.Second}, store.ExpireSet},
+	}
+	for _, c := range cases {
+		if res, _, _, _ := mem.HExpire("h", c.opt, "a"); res[0] != c.want {
+			t.Fatalf("HExpire %+v: got %v, want %v", c.opt, res[0], c.want)
+		}
+	}
+	if res, _, _, _ := mem.HExpire("h", store.ExpireOptions{Cond: store.ExpireXX, TTL: time.Second}, "b"); res[0] != store.ExpireSkipped {
+		t.Fatalf("HExpire XX on a field without a TTL: got %v", res[0])
+	}
+
+	ttls, found, _ := mem.HTTL("h", "a", "b", "nope")
+	if !reflect.DeepEqual(ttls, []time.Duration{5 * time.Second, -1, -1}) || !reflect.DeepEqual(found, []bool{true, true, false}) {
+		t.Fatalf("HTTL: got %v, %v", ttls, found)
+	}
+	if ats, _, _ := mem.HExpireTime("h", "a"); !ats[0].Equal(start.Add(5 * time.Second)) {
+		t.Fatalf("HExpireTime: got %v", ats[0])
+	}
+
+	// an expiry in the past deletes the field
+	if res, _, emptied, _ := mem.HExpire("h", store.ExpireOptions{At: start.Add(-time.Second)}, "b"); res[0] != store.ExpireDeleted || emptied {
+		t.Fatalf("HExpire in the past: got %v, %v", res[0], emptied)
+	}
+
+	fc.Advance(6 * time.Second)
+	if _, ok, _ := mem.HGet("h", "a"); ok {
+		t.Fatalf("expired field still readable")
+	}
+	if keys, _ := mem.HKeys("h"); !reflect.DeepEqual(keys, []string{"c"}) {
+		t.Fatalf("HKeys: got %q", keys)
+	}
+	if n, _ := mem.HLen("h"); n != 1 {
+		t.Fatalf("HLen: got %d", n)
+	}
+}
+
+func TestHExpire_LastFieldTakesTheKey(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+	mem.HSet("h", "a", "1", "b", "2")
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Second}, "a")
+	mem.HExpire("h", store.ExpireOptions{TTL: 2 * time.Second}, "b")
+
+	fc.Advance(1500 * time.Millisecond)
+	if mem.Exists("h") != 1 {
+		t.Fatalf("hash gone while a field is left")
+	}
+	fc.Advance(time.Second)
+	if mem.Exists("h") != 0 || mem.Type("h") != store.KindNone {
+		t.Fatalf("hash outlived its last field")
+	}
+
+	// a new hash under the name starts empty
+	mem.HSet("h", "c", "3")
+	if all, _ := mem.HGetAll("h"); !reflect.DeepEqual(all, []string{"c", "3"}) {
+		t.Fatalf("HGetAll: got %q", all)
+	}
+
+	// removing the field that expires last leaves the others to decide
+	mem.HSet("h", "d", "4")
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Second}, "c")
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Hour}, "d")
+	mem.HDel("h", "d")
+	fc.Advance(2 * time.Second)
+	if mem.Exists("h") != 0 {
+		t.Fatalf("hash outlived its last field after HDel")
+	}
+}
+
+func TestHExpire_Writes(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+	mem.HSet("h", "n", "1", "s", "x")
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Minute}, "n", "s")
+
+	// an increment keeps the field's TTL, overwriting it drops it
+	mem.HIncrBy("h", "n", 1)
+	mem.HSet("h", "s", "y")
+	if ttls, _, _ := mem.HTTL("h", "n", "s"); ttls[0] != time.Minute || ttls[1] != -1 {
+		t.Fatalf("HTTL: got %v", ttls)
+	}
+
+	res, _, _, _ := mem.HExpire("h", store.ExpireOptions{Persist: true}, "n", "s", "nope")
+	if want := []store.ExpireResult{store.ExpireSet, store.ExpireSkipped, store.ExpireNoField}; !reflect.DeepEqual(res, want) {
+		t.Fatalf("persist: got %v, want %v", res, want)
+	}
+	fc.Advance(2 * time.Minute)
+	if n, _ := mem.HLen("h"); n != 2 {
+		t.Fatalf("persisted field expired: HLen %d", n)
+	}
+}
+
+func TestHExpire_SnapshotRestore(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fc := newFakeClock(start)
+	mem := store.NewMemoryWithClock(fc)
+	mem.HSet("h", "a", "1", "b", "2", "c", "3")
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Second}, "a")
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Hour}, "b")
+	fc.Advance(2 * time.Second)
+
+	snap := mem.Snapshot()
+	want := []store.HashField{{Field: "b", Val: "2", ExpiresAt: start.Add(time.Hour)}, {Field: "c", Val: "3"}}
+	if len(snap) != 1 || !reflect.DeepEqual(snap[0].Hash, want) {
+		t.Fatalf("Snapshot: got %+v", snap)
+	}
+
+	other := store.NewMemoryWithClock(fc)
+	other.Restore([]store.Entry{
+		{Key: "h", Hash: snap[0].Hash},
+		{Key: "gone", Hash: []store.HashField{{Field: "a", Val: "1", ExpiresAt: start}}},
+	})
+	if ttls, _, _ := other.HTTL("h", "b", "c"); ttls[0] != time.Hour-2*time.Second || ttls[1] != -1 {
+		t.Fatalf("HTTL after restore: got %v", ttls)
+	}
+	if other.Exists("gone") != 0 {
+		t.Fatalf("restored a hash whose fields had all expired")
+	}
+}
+
+func TestHExpire_Sweeper(t *testing.T) {
+	t.Setenv("EXPIRED_SWEEP_INTERVAL", "1")
+	mem := store.NewMemory()
+
+	expired := make(chan string, 1)
+	mem.OnExpire(func(k string) { expired <- k })
+	mem.HSet("h", "f", "v")
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Millisecond}, "f")
+
+	select {
+	case k := <-expired:
+		if k != "h" {
+			t.Fatalf("got %q, want h", k)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("sweeper never collected the hash")
+	}
+}
+
+func TestHExpire_OnFieldExpire(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	var got [][]string
+	mem.OnFieldExpire(func(k string, fields []string) { got = append(got, append([]string{k}, fields...)) })
+	mem.HSet("h", "a", "1", "b", "2", "c", "3")
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Second}, "a", "b")
+
+	fc.Advance(2 * time.Second)
+	mem.HGet("h", "a")
+	mem.HSet("h", "d", "4") // nothing left to purge
+	if want := [][]string{{"h", "a", "b"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("OnFieldExpire calls: got %q, want %q", got, want)
+	}
+
+	// the last field going takes the key, which OnExpire reports instead
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Second}, "c", "d")
+	fc.Advance(2 * time.Second)
+	if mem.Exists("h") != 0 || len(got) != 1 {
+		t.Fatalf("after the last field: %q", got)
+	}
+}
+
+func TestHExpire_SweeperReportsFields(t *testing.T) {
+	t.Setenv("EXPIRED_SWEEP_INTERVAL", "1")
+	mem := store.NewMemory()
+
+	expired := make(chan []string, 1)
+	mem.OnFieldExpire(func(k string, fields []string) { expired <- append([]string{k}, fields...) })
+	mem.HSet("h", "f", "v", "g", "w")
+	mem.HExpire("h", store.ExpireOptions{TTL: time.Millisecond}, "f")
+
+	select {
+	case got := <-expired:
+		if !reflect.DeepEqual(got, []string{"h", "f"}) {
+			t.Fatalf("got %q, want [h f]", got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("sweeper never reported the field")
+	}
+}
@@ -0,0 +1,290 @@
+package store
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// Small hashes are stored the way redis's listpack encoding stores them: a
+// flat run of fields in insertion order, searched linearly, which costs far
+// less memory than a map for a handful of fields. A hash that grows past
+// either limit moves to a map for good.
+const (
+	hashMaxListpackEntries = 128
+	hashMaxListpackValue   = 64
+)
+
+type hashField struct {
+	name, val string
+	expiresAt time.Time // zero if the field has no TTL
+	seq       uint64    // the field's position in idx, once in a map
+}
+
+func (hf hashField) expired(now time.Time) bool {
+	return !hf.expiresAt.IsZero() && now.After(hf.expiresAt)
+}
+
+// hashValue is a hash: pairs while it's small, m once it isn't, with idx
+// giving its fields a stable order for HSCAN cursors the way mem.keys
+// does for SCAN.
+//
+// ttls counts the fields with a TTL. minAt is no later than the earliest
+// of their expiries, so a hash can tell cheaply that none has passed, and
+// maxAt is the latest, so it can tell that all of them have.
+type hashValue struct {
+	pairs []hashField
+	m     map[string]hashField
+	idx   keyIndex
+
+	ttls         int
+	minAt, maxAt time.Time
+}
+
+func (h *hashValue) kind() Kind { return KindHash }
+
+func (h *hashValue) encoding() string {
+	if h.m == nil {
+		return "listpack"
+	}
+	return "hashtable"
+}
+
+func (h *hashValue) clone() value {
+	c := &hashValue{pairs: slices.Clone(h.pairs), ttls: h.ttls, minAt: h.minAt, maxAt: h.maxAt}
+	if h.m != nil {
+		c.m = maps.Clone(h.m)
+		c.idx = keyIndex{slots: slices.Clone(h.idx.slots), next: h.idx.next, dead: h.idx.dead}
+	}
+	return c
+}
+
+func (h *hashValue) len() int {
+	if h.m == nil {
+		return len(h.pairs)
+	}
+	return len(h.m)
+}
+
+func (h *hashValue) find(f string) int {
+	return slices.IndexFunc(h.pairs, func(hf hashField) bool { return hf.name == f })
+}
+
+func (h *hashValue) field(f string) (hashField, bool) {
+	if h.m == nil {
+		if i := h.find(f); i >= 0 {
+			return h.pairs[i], true
+		}
+		return hashField{}, false
+	}
+	hf, ok := h.m[f]
+	return hf, ok
+}
+
+func (h *hashValue) get(f string) (string, bool) {
+	hf, ok := h.field(f)
+	return hf.val, ok
+}
+
+// set sets field f to v, dropping its TTL, and reports whether f is new.
+func (h *hashValue) set(f, v string) bool {
+	if h.m == nil {
+		if i := h.find(f); i >= 0 {
+			h.forget(h.pairs[i])
+			h.pairs[i].val = v
+			h.pairs[i].expiresAt = time.Time{}
+			if len(v) > hashMaxListpackValue {
+				h.grow()
+			}
+			return false
+		}
+		h.pairs = append(h.pairs, hashField{name: f, val: v})
+		if len(h.pairs) > hashMaxListpackEntries || len(f) > hashMaxListpackValue || len(v) > hashMaxListpackValue {
+			h.grow()
+		}
+		return true
+	}
+
+	hf, ok := h.m[f]
+	if !ok {
+		hf = hashField{name: f, seq: h.idx.add(f)}
+	}
+	h.forget(hf)
+	hf.val, hf.expiresAt = v, time.Time{}
+	h.m[f] = hf
+	return !ok
+}
+
+// grow moves a small hash into a map, keeping the order of its fields.
+func (h *hashValue) grow() {
+	h.m = make(map[string]hashField, len(h.pairs))
+	for _, hf := range h.pairs {
+		hf.seq = h.idx.add(hf.name)
+		h.m[hf.name] = hf
+	}
+	h.pairs = nil
+}
+
+// del removes field f and reports whether it existed.
+func (h *hashValue) del(f string) bool {
+	hf, ok := h.field(f)
+	if ok {
+		h.forget(hf)
+		h.remove(hf)
+	}
+	return ok
+}
+
+// remove takes hf out of the hash, leaving the TTL counts to the caller.
+func (h *hashValue) remove(hf hashField) {
+	if h.m == nil {
+		i := h.find(hf.name)
+		h.pairs = slices.Delete(h.pairs, i, i+1)
+		return
+	}
+	h.idx.remove(hf.seq)
+	delete(h.m, hf.name)
+}
+
+// expire sets the expiry of field f, which must exist; a zero at removes
+// its TTL.
+func (h *hashValue) expire(f string, at time.Time) {
+	var hf hashField
+	if h.m == nil {
+		i := h.find(f)
+		hf = h.pairs[i]
+		h.forget(hf)
+		h.pairs[i].expiresAt = at
+	} else {
+		hf = h.m[f]
+		h.forget(hf)
+		hf.expiresAt = at
+		h.m[f] = hf
+	}
+	if at.IsZero() {
+		return
+	}
+	if h.ttls++; h.ttls == 1 || at.Before(h.minAt) {
+		h.minAt = at
+	}
+	if h.ttls == 1 || at.After(h.maxAt) {
+		h.maxAt = at
+	}
+}
+
+// forget drops hf's TTL, if any, from the counts, before hf is removed or
+// its TTL changed. minAt stays a lower bound as it is, but losing the
+// latest expiry means finding the next one.
+func (h *hashValue) forget(hf hashField) {
+	if hf.expiresAt.IsZero() {
+		return
+	}
+	h.ttls--
+	if h.ttls > 0 && hf.expiresAt.Equal(h.maxAt) {
+		h.maxAt = time.Time{}
+		h.each(func(o hashField) bool {
+			if o.name != hf.name && o.expiresAt.After(h.maxAt) {
+				h.maxAt = o.expiresAt
+			}
+			return true
+		})
+	}
+}
+
+// stale reports whether some field may have expired by now, which purge
+// would remove.
+func (h *hashValue) stale(now time.Time) bool {
+	return h.ttls > 0 && now.After(h.minAt)
+}
+
+// expired reports whether every field has expired by now, leaving the
+// hash empty.
+func (h *hashValue) expired(now time.Time) bool {
+	return h.ttls > 0 && h.ttls == h.len() && now.After(h.maxAt)
+}
+
+// purge removes the fields that have expired by now and returns their
+// names, and recomputes minAt and maxAt from the fields left.
+func (h *hashValue) purge(now time.Time) []string {
+	var gone []hashField
+	h.each(func(hf hashField) bool {
+		if hf.expired(now) {
+			gone = append(gone, hf)
+		}
+		return true
+	})
+	names := make([]string, len(gone))
+	for i, hf := range gone {
+		h.remove(hf)
+		names[i] = hf.name
+	}
+	h.ttls -= len(gone)
+
+	h.minAt, h.maxAt = time.Time{}, time.Time{}
+	h.each(func(hf hashField) bool {
+		if at := hf.expiresAt; !at.IsZero() {
+			if h.minAt.IsZero() || at.Before(h.minAt) {
+				h.minAt = at
+			}
+			if at.After(h.maxAt) {
+				h.maxAt = at
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// each calls fn for every field in the order they were added, until fn
+// returns false.
+func (h *hashValue) each(fn func(hf hashField) bool) {
+	if h.m == nil {
+		for _, hf := range h.pairs {
+			if !fn(hf) {
+				return
+			}
+		}
+		return
+	}
+	for _, s := range h.idx.slots {
+		if s.live && !fn(h.m[s.key]) {
+			return
+		}
+	}
+}
+
+// scan returns about count fields starting from cursor and the cursor to
+// resume from, 0 once done, with the same guarantees as Scan. Like redis,
+// a small hash is returned whole.
+func (h *hashValue) scan(cursor uint64, count int) ([]hashField, uint64) {
+	if h.m == nil {
+		return slices.Clone(h.pairs), 0
+	}
+	slots := h.idx.slots
+	i := h.idx.search(cursor)
+	out := make([]hashField, 0, min(count, len(slots)-i))
+	for visits := 0; i < len(slots) && len(out) < count && visits < count*scanVisits; i, visits = i+1, visits+1 {
+		if s := slots[i]; s.live {
+			out = append(out, h.m[s.key])
+		}
+	}
+	if i == len(slots) {
+		return out, 0
+	}
+	return out, slots[i].seq
+}
+
+// random returns a field picked at random; h must not be empty.
+func (h *hashValue) random() hashField {
+	if h.m == nil {
+		return h.pairs[rand.IntN(len(h.pairs))]
+	}
+	// as in RandomKey, compaction keeps most slots live
+	slots := h.idx.slots
+	for {
+		if s := slots[rand.IntN(len(slots))]; s.live {
+			return h.m[s.key]
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package store
+
+import "sort"
+
+// keyIndex lists the keys in the order they were created. Each key gets a
+// sequence number that never changes while it exists and that is larger
+// than any handed out before, so a SCAN cursor can simply be the sequence
+// number to resume from: a key that lives through the whole iteration is
+// returned exactly once no matter how the keyspace grows or shrinks in
+// between.
+type keyIndex struct {
+	slots []slot // ordered by seq
+	next  uint64
+	dead  int
+}
+
+type slot struct {
+	seq  uint64
+	key  string
+	live bool
+}
+
+// compactMin keeps small indexes from being compacted over and over.
+const compactMin = 64
+
+func (ix *keyIndex) add(k string) uint64 {
+	ix.next++
+	ix.slots = append(ix.slots, slot{seq: ix.next, key: k, live: true})
+	return ix.next
+}
+
+func (ix *keyIndex) remove(seq uint64) {
+	i := ix.search(seq)
+	if i == len(ix.slots) || ix.slots[i].seq != seq || !ix.slots[i].live {
+		return
+	}
+	ix.slots[i] = slot{seq: seq}
+	ix.dead++
+
+	// deleted slots are dropped once they make up half of the index, which
+	// keeps both memory and the slots a scan skips over bounded
+	if ix.dead > compactMin && ix.dead > len(ix.slots)/2 {
+		live := ix.slots[:0]
+		for _, s := range ix.slots {
+			if s.live {
+				live = append(live, s)
+			}
+		}
+		clear(ix.slots[len(live):])
+		ix.slots, ix.dead = live, 0
+	}
+}
+
+// search returns the index of the first slot whose seq is at least seq.
+func (ix *keyIndex) search(seq uint64) int {
+	return sort.Search(len(ix.slots), func(i int) bool { return ix.slots[i].seq >= seq })
+}
+
+// put and del are the only ways keys enter and leave mem.m, so the index
+// stays in step with it. Both must be called with mu held. put counts as an
+// access; an overwritten key keeps its access history unless e brings its
+// own.
+
+func (mem *memory) put(k string, e entry) {
+	old, ok := mem.m[k]
+	if ok {
+		e.seq = old.seq
+	} else {
+		e.seq = mem.keys.add(k)
+	}
+
+	now := mem.clock.Now()
+	switch {
+	case e.meta == nil && ok:
+		e.meta = old.meta
+		e.meta.touch(now)
+	case e.meta == nil:
+		e.meta = newKeyMeta(now)
+	default:
+		e.meta.touch(now)
+	}
+	mem.m[k] = e
+}
+
+func (mem *memory) del(k string) {
+	if e, ok := mem.m[k]; ok {
+		mem.keys.remove(e.seq)
+		delete(mem.m, k)
+	}
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"errors"
+	"math/rand/v2"
+)
+
+var ErrNoSuchKey = errors.New("no such key")
+
+// scanVisits bounds the slots one Scan call looks at per key it was asked
+// for, so a run of deleted or expired keys can't hold the lock for long.
+const scanVisits = 10
+
+// Scan returns up to count live keys starting from cursor, along with the
+// cursor to pass next; 0 starts an iteration and is returned at its end.
+// Each call holds the lock only for its own slice of the keyspace. A key
+// that exists for the whole iteration is returned exactly once; keys
+// created or deleted meanwhile may or may not be.
+func (mem *memory) Scan(cursor uint64, count int) ([]string, uint64) {
+	count = max(count, 1)
+	now := mem.clock.Now()
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	slots := mem.keys.slots
+	i := mem.keys.search(cursor)
+	keys := make([]string, 0, min(count, len(slots)-i))
+	for visits := 0; i < len(slots) && len(keys) < count && visits < count*scanVisits; i, visits = i+1, visits+1 {
+		s := slots[i]
+		if !s.live {
+			continue
+		}
+		if e := mem.m[s.key]; !e.expired(now) {
+			keys = append(keys, s.key)
+		}
+	}
+
+	if i == len(slots) {
+		return keys, 0
+	}
+	return keys, slots[i].seq
+}
+
+// RandomKey returns a live key picked at random, or false if there are
+// none.
+func (mem *memory) RandomKey() (string, bool) {
+	now := mem.clock.Now()
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	slots := mem.keys.slots
+	if len(mem.m) == 0 {
+		return "", false
+	}
+
+	// compaction keeps most slots live, so a few tries almost always land
+	// on one; the pass afterwards covers a keyspace of mostly expired keys
+	for range 16 {
+		s := slots[rand.IntN(len(slots))]
+		if s.live && !mem.m[s.key].expired(now) {
+			return s.key, true
+		}
+	}
+	start := rand.IntN(len(slots))
+	for j := range slots {
+		s := slots[(start+j)%len(slots)]
+		if s.live && !mem.m[s.key].expired(now) {
+			return s.key, true
+		}
+	}
+	return "", false
+}
+
+// Len returns the number of keys, including expired ones that haven't been
+// removed yet, as redis's DBSIZE does.
+func (mem *memory) Len() int {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	return len(mem.m)
+}
+
+// Flush deletes every key.
+func (mem *memory) Flush() {
+	mem.mu.Lock()
+	mem.m = make(map[string]entry)
+	mem.keys = keyIndex{}
+	mem.mu.Unlock()
+}
+
+// Type returns the type of the value at k, KindNone if k doesn't exist.
+func (mem *memory) Type(k string) Kind {
+	now := mem.clock.Now()
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	if e, ok := mem.m[k]; ok && !e.expired(now) {
+		return e.val.kind()
+	}
+	return KindNone
+}
+
+// Rename moves the value at src, with its TTL, to dst, replacing whatever
+// dst held; with nx it does nothing if dst exists. It reports whether the
+// value moved, and ErrNoSuchKey if src doesn't exist. Renaming a key to
+// itself leaves it alone but counts as a move unless nx is set.
+func (mem *memory) Rename(src, dst string, nx bool) (bool, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	now := mem.clock.Now()
+	e, ok := mem.m[src]
+	if !ok || e.expired(now) {
+		return false, ErrNoSuchKey
+	}
+	if src == dst {
+		return !nx, nil
+	}
+	if d, ok := mem.m[dst]; ok && !d.expired(now) && nx {
+		return false, nil
+	}
+
+	mem.del(src)
+	mem.del(dst)
+	mem.put(dst, e)
+	return true, nil
+}
+
+// Copy copies the value at src, with its TTL, to dst. Unless replace is
+// set it does nothing if dst exists. It reports whether it copied.
+func (mem *memory) Copy(src, dst string, replace bool) bool {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	now := mem.clock.Now()
+	e, ok := mem.m[src]
+	if !ok || e.expired(now) {
+		return false
+	}
+	if d, ok := mem.m[dst]; ok && !d.expired(now) && !replace {
+		return false
+	}
+
+	mem.del(dst)
+	mem.put(dst, entry{val: e.val.clone(), expiresAt: e.expiresAt})
+	return true
+}
@@ -0,0 +1,101 @@
+package store_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestScan_CoversKeysWhileGrowing(t *testing.T) {
+	mem := store.NewMemory()
+	for i := range 1000 {
+		mem.Set("old"+strconv.Itoa(i), "v")
+	}
+
+	seen := map[string]int{}
+	cursor, calls := uint64(0), 0
+	for {
+		var keys []string
+		keys, cursor = mem.Scan(cursor, 10)
+		for _, k := range keys {
+			seen[k]++
+		}
+		// grow and churn the keyspace between calls
+		for j := range 5 {
+			mem.Set("new"+strconv.Itoa(calls*5+j), "v")
+		}
+		mem.Del("new" + strconv.Itoa(calls))
+		calls++
+		if cursor == 0 {
+			break
+		}
+	}
+
+	for i := range 1000 {
+		if n := seen["old"+strconv.Itoa(i)]; n != 1 {
+			t.Fatalf("old%d returned %d times, want 1", i, n)
+		}
+	}
+}
+
+func TestScan_SurvivesCompaction(t *testing.T) {
+	mem := store.NewMemory()
+	for i := range 1000 {
+		mem.Set(strconv.Itoa(i), "v")
+	}
+
+	keys, cursor := mem.Scan(0, 100)
+	if len(keys) != 100 || cursor == 0 {
+		t.Fatalf("first page: %d keys, cursor %d", len(keys), cursor)
+	}
+	// delete most of what's left so the index gets compacted
+	for i := 100; i < 900; i++ {
+		mem.Del(strconv.Itoa(i))
+	}
+
+	var rest []string
+	for cursor != 0 {
+		keys, cursor = mem.Scan(cursor, 100)
+		rest = append(rest, keys...)
+	}
+	if len(rest) != 100 || rest[0] != "900" || rest[99] != "999" {
+		t.Fatalf("after compaction: got %d keys %q..., want 900..999", len(rest), rest[:min(len(rest), 3)])
+	}
+}
+
+func TestScan_SkipsExpired(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	mem.Set("a", "1")
+	mem.SetEx("b", "2", time.Second)
+	fc.Advance(2 * time.Second)
+
+	keys, cursor := mem.Scan(0, 10)
+	if len(keys) != 1 || keys[0] != "a" || cursor != 0 {
+		t.Fatalf("Scan: got %q, cursor %d", keys, cursor)
+	}
+	// DBSIZE counts keys the sweeper hasn't removed yet
+	if n := mem.Len(); n != 2 {
+		t.Fatalf("Len: got %d, want 2", n)
+	}
+	for range 20 {
+		if k, ok := mem.RandomKey(); !ok || k != "a" {
+			t.Fatalf("RandomKey: got %q, %v", k, ok)
+		}
+	}
+}
+
+func TestRandomKey_Empty(t *testing.T) {
+	mem := store.NewMemory()
+	if k, ok := mem.RandomKey(); ok {
+		t.Fatalf("RandomKey on empty store: got %q", k)
+	}
+	mem.Set("a", "1")
+	mem.Del("a")
+	if k, ok := mem.RandomKey(); ok {
+		t.Fatalf("RandomKey after delete: got %q", k)
+	}
+}
@@ -0,0 +1,309 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// Side is an end of a list.
+type Side int
+
+const (
+	Left Side = iota
+	Right
+)
+
+// listAt returns the list at k, nil if k doesn't exist, recording the
+// access. Must be called with mu held.
+func (mem *memory) listAt(k string, now time.Time) (*quicklist, error) {
+	e, ok := mem.m[k]
+	if !ok || e.expired(now) {
+		return nil, nil
+	}
+	l, ok := e.val.(*quicklist)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	e.meta.touch(now)
+	return l, nil
+}
+
+// Push adds vals one at a time to side of the list at k, creating it if
+// missing unless existing is set, and returns the new length.
+func (mem *memory) Push(k string, side Side, existing bool, vals ...string) (int, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	if l == nil {
+		if existing {
+			return 0, nil
+		}
+		l = &quicklist{}
+		mem.put(k, entry{val: l})
+	}
+	for _, v := range vals {
+		l.push(side, v)
+	}
+	return l.len(), nil
+}
+
+// Pop removes up to count elements from side of the list at k and returns
+// them, nil if k doesn't exist, and whether that emptied and so deleted
+// the list.
+func (mem *memory) Pop(k string, side Side, count int) ([]string, bool, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	if l == nil {
+		return nil, false, err
+	}
+	return mem.pop(k, l, side, count), l.len() == 0, nil
+}
+
+// must be called with mu held
+func (mem *memory) pop(k string, l *quicklist, side Side, count int) []string {
+	vals := make([]string, 0, min(count, l.len()))
+	for len(vals) < count && l.len() > 0 {
+		vals = append(vals, l.pop(side))
+	}
+	if l.len() == 0 {
+		mem.del(k)
+	}
+	return vals
+}
+
+// MPop pops like Pop from the first of keys holding a list, and returns
+// which key that was. It stops at the first key of another type.
+func (mem *memory) MPop(keys []string, side Side, count int) (string, []string, bool, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	now := mem.clock.Now()
+	for _, k := range keys {
+		l, err := mem.listAt(k, now)
+		if err != nil {
+			return "", nil, false, err
+		}
+		if l != nil {
+			return k, mem.pop(k, l, side, count), l.len() == 0, nil
+		}
+	}
+	return "", nil, false, nil
+}
+
+// Move pops an element from side from of the list at src and pushes it to
+// side to of the list at dst, creating dst if missing. It returns the
+// element, false if src doesn't exist, and whether src was emptied. A list
+// moved onto itself is rotated.
+func (mem *memory) Move(src, dst string, from, to Side) (string, bool, bool, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	now := mem.clock.Now()
+	sl, err := mem.listAt(src, now)
+	if sl == nil {
+		return "", false, false, err
+	}
+	dl, err := mem.listAt(dst, now)
+	if err != nil {
+		return "", false, false, err
+	}
+
+	v := sl.pop(from)
+	if dl == nil {
+		dl = &quicklist{}
+		mem.put(dst, entry{val: dl})
+	}
+	dl.push(to, v)
+	if sl.len() == 0 {
+		mem.del(src)
+	}
+	return v, true, sl.len() == 0, nil
+}
+
+// LLen returns the length of the list at k, 0 if k doesn't exist.
+func (mem *memory) LLen(k string) (int, error) {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	if l == nil {
+		return 0, err
+	}
+	return l.len(), nil
+}
+
+// listRange clamps redis's inclusive, possibly negative, start and stop to
+// a list of n elements, reporting false if nothing is left.
+func listRange(start, stop, n int) (int, int, bool) {
+	if start < 0 {
+		start = max(start+n, 0)
+	}
+	if stop < 0 {
+		stop += n
+	}
+	stop = min(stop, n-1)
+	return start, stop, start <= stop && start < n
+}
+
+// LRange returns the elements of the list at k from start to stop, both
+// inclusive and counting from the tail if negative.
+func (mem *memory) LRange(k string, start, stop int) ([]string, error) {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	if l == nil {
+		return nil, err
+	}
+	start, stop, ok := listRange(start, stop, l.len())
+	if !ok {
+		return nil, nil
+	}
+	return l.slice(start, stop), nil
+}
+
+// LIndex returns the element at index i of the list at k.
+func (mem *memory) LIndex(k string, i int) (string, bool, error) {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	if l == nil {
+		return "", false, err
+	}
+	i, ok := l.index(i)
+	if !ok {
+		return "", false, nil
+	}
+	return l.at(i), true, nil
+}
+
+// LSet replaces the element at index i of the list at k.
+func (mem *memory) LSet(k string, i int, v string) error {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	switch {
+	case err != nil:
+		return err
+	case l == nil:
+		return ErrNoSuchKey
+	}
+	i, ok := l.index(i)
+	if !ok {
+		return ErrIndexOutOfRange
+	}
+	l.set(i, v)
+	return nil
+}
+
+// LRem removes up to count elements equal to v from the list at k, from
+// the tail if count is negative and all of them if it's 0. It returns how
+// many it removed and whether that emptied the list.
+func (mem *memory) LRem(k string, count int, v string) (int, bool, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	if l == nil {
+		return 0, false, err
+	}
+	var n int
+	if count < 0 {
+		n = l.remove(v, -count, true)
+	} else {
+		n = l.remove(v, count, false)
+	}
+	if l.len() == 0 {
+		mem.del(k)
+	}
+	return n, l.len() == 0, nil
+}
+
+// LTrim keeps only the elements from start to stop of the list at k,
+// indexed like LRange. It reports whether k existed and whether the trim
+// emptied it.
+func (mem *memory) LTrim(k string, start, stop int) (bool, bool, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	if l == nil {
+		return false, false, err
+	}
+	n := l.len()
+	if start, stop, ok := listRange(start, stop, n); ok {
+		l.trim(start, n-1-stop)
+		return true, false, nil
+	}
+	mem.del(k)
+	return true, true, nil
+}
+
+// LInsert inserts v before or after the first element equal to pivot in
+// the list at k. It returns the new length, 0 if k doesn't exist and -1 if
+// pivot wasn't found.
+func (mem *memory) LInsert(k string, before bool, pivot, v string) (int, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	if l == nil {
+		return 0, err
+	}
+	at := -1
+	l.each(false, func(i int, e string) bool {
+		if e == pivot {
+			at = i
+		}
+		return at < 0
+	})
+	if at < 0 {
+		return -1, nil
+	}
+	if !before {
+		at++
+	}
+	l.insert(at, v)
+	return l.len(), nil
+}
+
+// LPos returns the indexes of elements equal to v in the list at k. It
+// skips the first rank-1 matches, searching from the tail if rank is
+// negative, returns at most count indexes (all of them if 0), and compares
+// at most maxlen elements (all of them if 0).
+func (mem *memory) LPos(k, v string, rank, count, maxlen int) ([]int, error) {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	l, err := mem.listAt(k, mem.clock.Now())
+	if l == nil {
+		return nil, err
+	}
+	reverse := rank < 0
+	skip := max(rank, -rank) - 1
+
+	var out []int
+	var compared int
+	l.each(reverse, func(i int, e string) bool {
+		if e == v {
+			if skip > 0 {
+				skip--
+			} else {
+				out = append(out, i)
+			}
+		}
+		compared++
+		return (count == 0 || len(out) < count) && (maxlen == 0 || compared < maxlen)
+	})
+	return out, nil
+}
@@ -0,0 +1,308 @@
+package store_test
+
+import (
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestPushPop(t *testing.T) {
+	mem := store.NewMemory()
+
+	if n, err := mem.Push("k", store.Right, true, "a"); n != 0 || err != nil {
+		t.Fatalf("Push existing-only on missing key: got %d, %v", n, err)
+	}
+	mem.Push("k", store.Right, false, "b", "c")
+	if n, _ := mem.Push("k", store.Left, false, "a", "z"); n != 4 {
+		t.Fatalf("Push: got length %d, want 4", n)
+	}
+	if got, _ := mem.LRange("k", 0, -1); !reflect.DeepEqual(got, []string{"z", "a", "b", "c"}) {
+		t.Fatalf("LRange: got %q", got)
+	}
+
+	vals, emptied, _ := mem.Pop("k", store.Left, 1)
+	if !reflect.DeepEqual(vals, []string{"z"}) || emptied {
+		t.Fatalf("Pop left: got %q, %v", vals, emptied)
+	}
+	vals, emptied, _ = mem.Pop("k", store.Right, 10)
+	if !reflect.DeepEqual(vals, []string{"c", "b", "a"}) || !emptied {
+		t.Fatalf("Pop right: got %q, %v", vals, emptied)
+	}
+	if mem.Exists("k") != 0 {
+		t.Fatalf("empty list wasn't deleted")
+	}
+	if vals, _, _ := mem.Pop("k", store.Left, 1); vals != nil {
+		t.Fatalf("Pop on missing key: got %q", vals)
+	}
+}
+
+// TestList_Model checks the quicklist against a plain slice through runs
+// of random operations long enough to span many nodes.
+func TestList_Model(t *testing.T) {
+	mem := store.NewMemory()
+	var model []string
+	r := rand.New(rand.NewPCG(1, 2))
+
+	for step := range 20000 {
+		v := strconv.Itoa(r.IntN(50))
+		n := len(model)
+		switch op := r.IntN(10); {
+		case op < 3:
+			mem.Push("k", store.Right, false, v)
+			model = append(model, v)
+		case op < 5:
+			mem.Push("k", store.Left, false, v)
+			model = slices.Insert(model, 0, v)
+		case op == 5 && n > 0:
+			mem.Pop("k", store.Left, 1)
+			model = model[1:]
+		case op == 6 && n > 0:
+			mem.Pop("k", store.Right, 1)
+			model = model[:n-1]
+		case op == 7 && n > 0:
+			pivot := model[r.IntN(n)]
+			mem.LInsert("k", true, pivot, v)
+			model = slices.Insert(model, slices.Index(model, pivot), v)
+		case op == 8 && n > 0:
+			i := r.IntN(n)
+			mem.LSet("k", i, v)
+			model[i] = v
+		case op == 9 && n > 0 && step%7 == 0:
+			count := r.IntN(5) - 2
+			got, _, _ := mem.LRem("k", count, v)
+			want := removeModel(&model, v, count)
+			if got != want {
+				t.Fatalf("step %d: LRem %d %s removed %d, want %d", step, count, v, got, want)
+			}
+		}
+
+		if step%97 == 0 || step == 19999 {
+			got, _ := mem.LRange("k", 0, -1)
+			if len(got) != len(model) || (len(model) > 0 && !reflect.DeepEqual(got, model)) {
+				t.Fatalf("step %d: list diverged from model", step)
+			}
+			if n, _ := mem.LLen("k"); n != len(model) {
+				t.Fatalf("step %d: LLen %d, want %d", step, n, len(model))
+			}
+			if len(model) > 0 {
+				i := r.IntN(len(model))
+				if v, _, _ := mem.LIndex("k", i); v != model[i] {
+					t.Fatalf("step %d: LIndex %d: got %q, want %q", step, i, v, model[i])
+				}
+			}
+		}
+	}
+}
+
+func removeModel(model *[]string, v string, count int) int {
+	var removed int
+	limit := max(count, -count)
+	if count >= 0 {
+		for i := 0; i < len(*model) && (limit == 0 || removed < limit); {
+			if (*model)[i] == v {
+				*model = slices.Delete(*model, i, i+1)
+				removed++
+				continue
+			}
+			i++
+		}
+		return removed
+	}
+	for i := len(*model) - 1; i >= 0 && removed < limit; i-- {
+		if (*model)[i] == v {
+			*model = slices.Delete(*model, i, i+1)
+			removed++
+		}
+	}
+	return removed
+}
+
+func TestLRange_LTrim(t *testing.T) {
+	mem := store.NewMemory()
+	for i := range 300 {
+		mem.Push("k", store.Right, false, strconv.Itoa(i))
+	}
+
+	cases := []struct {
+		start, stop int
+		first, n    int
+	}{
+		{0, 2, 0, 3},
+		{-3, -1, 297, 3},
+		{-1000, 1, 0, 2},
+		{298, 1000, 298, 2},
+		{5, 4, 0, 0},
+		{300, 400, 0, 0},
+	}
+	for _, c := range cases {
+		got, _ := mem.LRange("k", c.start, c.stop)
+		if len(got) != c.n || (c.n > 0 && got[0] != strconv.Itoa(c.first)) {
+			t.Errorf("LRange %d %d: got %d elements starting %v", c.start, c.stop, len(got), got)
+		}
+	}
+
+	if existed, emptied, _ := mem.LTrim("k", 100, -101); !existed || emptied {
+		t.Fatalf("LTrim: got %v, %v", existed, emptied)
+	}
+	got, _ := mem.LRange("k", 0, -1)
+	if len(got) != 100 || got[0] != "100" || got[99] != "199" {
+		t.Fatalf("after LTrim: %d elements, %q..%q", len(got), got[0], got[len(got)-1])
+	}
+	if existed, emptied, _ := mem.LTrim("k", 1, 0); !existed || !emptied || mem.Exists("k") != 0 {
+		t.Fatalf("LTrim to nothing: got %v, %v", existed, emptied)
+	}
+	if existed, _, _ := mem.LTrim("k", 0, -1); existed {
+		t.Fatalf("LTrim on missing key reported it existed")
+	}
+}
+
+func TestLSet_LIndex(t *testing.T) {
+	mem := store.NewMemory()
+	if err := mem.LSet("k", 0, "v"); !errors.Is(err, store.ErrNoSuchKey) {
+		t.Fatalf("LSet on missing key: got %v", err)
+	}
+	mem.Push("k", store.Right, false, "a", "b", "c")
+	if err := mem.LSet("k", -1, "z"); err != nil {
+		t.Fatalf("LSet: %v", err)
+	}
+	if err := mem.LSet("k", 3, "z"); !errors.Is(err, store.ErrIndexOutOfRange) {
+		t.Fatalf("LSet out of range: got %v", err)
+	}
+	if v, ok, _ := mem.LIndex("k", 2); !ok || v != "z" {
+		t.Fatalf("LIndex: got %q, %v", v, ok)
+	}
+	if _, ok, _ := mem.LIndex("k", -4); ok {
+		t.Fatalf("LIndex out of range succeeded")
+	}
+}
+
+func TestLInsert_LPos(t *testing.T) {
+	mem := store.NewMemory()
+	if n, _ := mem.LInsert("k", true, "a", "x"); n != 0 {
+		t.Fatalf("LInsert on missing key: got %d", n)
+	}
+	mem.Push("k", store.Right, false, "a", "b", "c", "b", "a", "b")
+	if n, _ := mem.LInsert("k", false, "nope", "x"); n != -1 {
+		t.Fatalf("LInsert without pivot: got %d", n)
+	}
+	if n, _ := mem.LInsert("k", false, "c", "x"); n != 7 {
+		t.Fatalf("LInsert after: got %d", n)
+	}
+	// a b c x b a b
+	cases := []struct {
+		rank, count, maxlen int
+		want                []int
+	}{
+		{1, 1, 0, []int{1}},
+		{2, 1, 0, []int{4}},
+		{1, 0, 0, []int{1, 4, 6}},
+		{-1, 2, 0, []int{6, 4}},
+		{1, 0, 3, []int{1}},
+		{-2, 0, 2, nil},
+		{4, 0, 0, nil},
+	}
+	for _, c := range cases {
+		if got, _ := mem.LPos("k", "b", c.rank, c.count, c.maxlen); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("LPos rank %d count %d maxlen %d: got %v, want %v", c.rank, c.count, c.maxlen, got, c.want)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	mem := store.NewMemory()
+	mem.Push("src", store.Right, false, "a", "b")
+
+	if v, ok, emptied, _ := mem.Move("src", "dst", store.Left, store.Right); v != "a" || !ok || emptied {
+		t.Fatalf("Move: got %q, %v, %v", v, ok, emptied)
+	}
+	if v, _, emptied, _ := mem.Move("src", "dst", store.Right, store.Left); v != "b" || !emptied {
+		t.Fatalf("Move last element: got %q, %v", v, emptied)
+	}
+	if got, _ := mem.LRange("dst", 0, -1); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Fatalf("dst: got %q", got)
+	}
+	if mem.Exists("src") != 0 {
+		t.Fatalf("empty source wasn't deleted")
+	}
+
+	// onto itself, including a list of one
+	if v, _, emptied, _ := mem.Move("dst", "dst", store.Left, store.Right); v != "b" || emptied {
+		t.Fatalf("rotate: got %q, %v", v, emptied)
+	}
+	if got, _ := mem.LRange("dst", 0, -1); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("rotated: got %q", got)
+	}
+	mem.Push("one", store.Left, false, "x")
+	if _, _, emptied, _ := mem.Move("one", "one", store.Right, store.Left); emptied || mem.Exists("one") != 1 {
+		t.Fatalf("rotating a list of one deleted it")
+	}
+
+	mem.Set("str", "v")
+	if _, _, _, err := mem.Move("dst", "str", store.Left, store.Left); !errors.Is(err, store.ErrWrongType) {
+		t.Fatalf("Move onto a string: got %v", err)
+	}
+	if n, _ := mem.LLen("dst"); n != 2 {
+		t.Fatalf("failed Move popped anyway")
+	}
+	if _, ok, _, _ := mem.Move("missing", "dst", store.Left, store.Left); ok {
+		t.Fatalf("Move from a missing key succeeded")
+	}
+}
+
+func TestMPop(t *testing.T) {
+	mem := store.NewMemory()
+	mem.Push("b", store.Right, false, "1", "2", "3")
+
+	k, vals, emptied, err := mem.MPop([]string{"a", "b"}, store.Right, 2)
+	if k != "b" || !reflect.DeepEqual(vals, []string{"3", "2"}) || emptied || err != nil {
+		t.Fatalf("MPop: got %q, %q, %v, %v", k, vals, emptied, err)
+	}
+	if k, _, _, _ := mem.MPop([]string{"a", "c"}, store.Left, 1); k != "" {
+		t.Fatalf("MPop on missing keys: got %q", k)
+	}
+	mem.Set("s", "v")
+	if _, _, _, err := mem.MPop([]string{"s", "b"}, store.Left, 1); !errors.Is(err, store.ErrWrongType) {
+		t.Fatalf("MPop over a string: got %v", err)
+	}
+}
+
+func TestList_SnapshotCopyObject(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+	for i := range 200 {
+		mem.Push("big", store.Right, false, strconv.Itoa(i))
+	}
+	mem.Push("small", store.Right, false, "a", "b")
+
+	if info, _ := mem.Object("small"); info.Encoding != "listpack" {
+		t.Fatalf("small list encoding: %q", info.Encoding)
+	}
+	if info, _ := mem.Object("big"); info.Encoding != "quicklist" {
+		t.Fatalf("big list encoding: %q", info.Encoding)
+	}
+
+	// a copy doesn't share elements with the original
+	mem.Copy("small", "copy", false)
+	mem.LSet("copy", 0, "z")
+	if v, _, _ := mem.LIndex("small", 0); v != "a" {
+		t.Fatalf("Copy shares elements: %q", v)
+	}
+
+	other := store.NewMemoryWithClock(fc)
+	other.Restore(mem.Snapshot())
+	for _, k := range []string{"big", "small", "copy"} {
+		want, _ := mem.LRange(k, 0, -1)
+		if got, _ := other.LRange(k, 0, -1); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s after restore: got %d elements, want %d", k, len(got), len(want))
+		}
+	}
+	if typ := other.Type("big"); typ != store.KindList {
+		t.Fatalf("Type after restore: %s", typ)
+	}
+}
@@ -6,8 +6,10 @@ import (
 )
 
 type entry struct {
-	val       string
+	val       value
 	expiresAt time.Time
+	seq       uint64 // the key's position in mem.keys
+	meta      *keyMeta
 }
 
 type memory struct {
@@ -15,57 +17,120 @@ type memory struct {
 	m                map[string]entry
 	cfg MemoryConfig
 	clock Clock
+
+	// guarded by mu
+	keys          keyIndex
+	onExpire      func(k string)
+	onFieldExpire func(k string, fields []string)
+	// hash fields removed for having expired, reported by unlock
+	expiredFields []expiredFields
+}
+
+// OnExpire registers fn to be called with every key removed because its
+// TTL passed, whether the sweeper found it or it was hit on access. fn runs
+// outside the store's lock.
+func (mem *memory) OnExpire(fn func(k string)) {
+	mem.mu.Lock()
+	mem.onExpire = fn
+	mem.mu.Unlock()
 }
 
 func (mem *memory) getEntry(k string) (entry, bool) {
 	now := mem.clock.Now()
 
+	// a hash's expiry depends on its fields, so check it under the lock
 	mem.mu.RLock()
 	e, ok := mem.m[k]
+	expired := ok && e.expired(now)
 	mem.mu.RUnlock()
 
 	if !ok {
 		return entry{}, false
 	}
 
-	if e.expired(now) {
+	if expired {
 		mem.mu.Lock()
 		if e2, ok2 := mem.m[k]; ok2 && e2.expired(mem.clock.Now()) {
-			delete(mem.m, k)
+			mem.del(k)
+			onExpire := mem.onExpire
 			mem.mu.Unlock()
+			if onExpire != nil {
+				onExpire(k)
+			}
 			return entry{}, false
 		}
 		e = mem.m[k]
 		mem.mu.Unlock()
 	}
 
+	e.meta.touch(now)
 	return e, true
 }
 
-func (mem *memory) Get(k string) (string, bool) {
+func (mem *memory) Get(k string) (string, bool, error) {
 	e, ok := mem.getEntry(k)
 	if !ok {
-		return "", false
+		return "", false, nil
 	}
-	return e.val, true
+	v, err := e.str()
+	return v, err == nil, err
 }
 
 func (mem *memory) Set(k, v string) {
 	mem.mu.Lock()
-	mem.m[k] = entry{val: v}
+	mem.put(k, entry{val: stringValue(v)})
 	mem.mu.Unlock()
 }
 
+// SetWith writes v under k as one step, honouring opt. It returns the value
+// k held before, whether it existed, whether the write happened, and the
+// expiry k was written with, zero for none. With opt.Get it fails with
+// ErrWrongType, writing nothing, if k holds another type.
+func (mem *memory) SetWith(k, v string, opt SetOptions) (string, bool, bool, time.Time, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	now := mem.clock.Now()
+	old, existed := mem.m[k]
+	if existed && old.expired(now) {
+		old, existed = entry{}, false
+	}
+
+	prev, err := old.str()
+	if err != nil && opt.Get {
+		return "", true, false, time.Time{}, err
+	}
+
+	if (opt.Cond == SetNX && existed) || (opt.Cond == SetXX && !existed) {
+		return prev, existed, false, time.Time{}, nil
+	}
+
+	e := entry{val: stringValue(v), expiresAt: opt.ExpiresAt}
+	switch {
+	case opt.KeepTTL:
+		e.expiresAt = old.expiresAt
+	case opt.TTL > 0:
+		e.expiresAt = now.Add(opt.TTL)
+	}
+	if !e.expiresAt.IsZero() && !e.expiresAt.After(now) {
+		// an absolute expiry already in the past, e.g. from EXAT
+		mem.del(k)
+	} else {
+		mem.put(k, e)
+	}
+	return prev, existed, true, e.expiresAt, nil
+}
+
 func (mem *memory) SetEx(k, v string, ttl time.Duration) {
 	mem.mu.Lock()
-	mem.m[k] = entry{val: v, expiresAt: mem.clock.Now().Add(ttl)}
+	mem.put(k, entry{val: stringValue(v), expiresAt: mem.clock.Now().Add(ttl)})
 	mem.mu.Unlock()
 }
 
 func (mem *memory) Del(k string) bool {
 	mem.mu.Lock()
 	_, existed := mem.m[k]
-	delete(mem.m, k)
+	mem.del(k)
 	mem.mu.Unlock()
 	return existed
 }
@@ -2,8 +2,11 @@ package store_test
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/amir-aharon/goliath/internal/store"
 )
@@ -16,7 +19,7 @@ func TestStoreSetGet(t *testing.T) {
 
 	mem.Set(k, v)
 
-	got, ok := mem.Get(k)
+	got, ok, _ := mem.Get(k)
 	if !ok {
 		t.Fatalf("expected key to exist")
 	}
@@ -42,14 +45,14 @@ func TestStoreDel(t *testing.T) {
 	}
 
 	// after delete, key is gone
-	if _, ok := mem.Get(k); ok {
+	if _, ok, _ := mem.Get(k); ok {
 		t.Fatalf("expected key %q to be gone after Del", k)
 	}
 }
 
 func TestStoreGetMissing(t *testing.T) {
 	mem := store.NewMemory()
-	if _, ok := mem.Get("missing"); ok {
+	if _, ok, _ := mem.Get("missing"); ok {
 		t.Fatalf("expected ok=false for missing key")
 	}
 }
@@ -59,12 +62,12 @@ func TestStoreOverwrite(t *testing.T) {
 	k := "a"
 
 	mem.Set(k, "1")
-	if v, _ := mem.Get(k); v != "1" {
+	if v, _, _ := mem.Get(k); v != "1" {
 		t.Fatalf("got %q, want %q after first set", v, "1")
 	}
 
 	mem.Set(k, "2")
-	if v, _ := mem.Get(k); v != "2" {
+	if v, _, _ := mem.Get(k); v != "2" {
 		t.Fatalf("got %q, want %q after overwrite", v, "2")
 	}
 }
@@ -101,7 +104,7 @@ func TestStore_NoDataRaces(t *testing.T) {
 			for i := range iters {
 				k := keys[(id+i)%len(keys)]
 				mem.Set(k, fmt.Sprintf("w%d-%d", id, i))
-				_, _ = mem.Get(k)
+				_, _, _ = mem.Get(k)
 
 				if i%delEvery == 0 {
 					_ = mem.Del(k)
@@ -112,3 +115,152 @@ func TestStore_NoDataRaces(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestStoreBinarySafe(t *testing.T) {
+	mem := store.NewMemory()
+	for b := range 256 {
+		k := string([]byte{'k', byte(b)})
+		v := string([]byte{byte(b), 0x00, byte(b), '\r', '\n'})
+		mem.Set(k, v)
+	}
+	for b := range 256 {
+		k := string([]byte{'k', byte(b)})
+		want := string([]byte{byte(b), 0x00, byte(b), '\r', '\n'})
+		if got, ok, _ := mem.Get(k); !ok || got != want {
+			t.Fatalf("byte %#02x: got (%q,%v), want (%q,true)", b, got, ok, want)
+		}
+	}
+}
+
+func TestStoreSnapshot(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	mem.Set("a", "1")
+	mem.SetEx("b", "2", 10*time.Second)
+	mem.SetEx("gone", "3", time.Second)
+	fc.Advance(2 * time.Second)
+
+	snap := mem.Snapshot()
+	sort.Slice(snap, func(i, j int) bool { return snap[i].Key < snap[j].Key })
+
+	want := []store.Entry{
+		{Key: "a", Val: "1"},
+		{Key: "b", Val: "2", ExpiresAt: time.Unix(1_700_000_010, 0)},
+	}
+	if !reflect.DeepEqual(snap, want) {
+		t.Fatalf("Snapshot: got %+v, want %+v", snap, want)
+	}
+
+	// later writes don't leak into an existing snapshot
+	mem.Set("a", "changed")
+	if snap[0].Val != "1" {
+		t.Fatalf("snapshot changed after write: %+v", snap[0])
+	}
+}
+
+func TestStoreSnapshot_Batches(t *testing.T) {
+	mem := store.NewMemory()
+	const n = 5000
+	for i := range n {
+		mem.Set(fmt.Sprint("k", i), "v")
+	}
+	for i := 0; i < n; i += 3 {
+		mem.Del(fmt.Sprint("k", i))
+	}
+	mem.Set("k0", "back") // recreated, so it comes last
+	big := make([]string, 3000)
+	mem.Push("list", store.Right, false, big...)
+
+	// writes to existing keys don't stop meanwhile
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				mem.Set(fmt.Sprint("k", 1+i%(n-1)), "w")
+			}
+		}
+	}()
+	snap := mem.Snapshot()
+	close(stop)
+	<-done
+
+	// every key that lived through the snapshot is copied exactly once
+	seen := make(map[string]int)
+	for _, e := range snap {
+		seen[e.Key]++
+	}
+	for i := range n {
+		want := 1
+		if i%3 == 0 && i != 0 {
+			want = 0
+		}
+		if k := fmt.Sprint("k", i); seen[k] != want {
+			t.Fatalf("%s copied %d times, want %d", k, seen[k], want)
+		}
+	}
+	if seen["list"] != 1 || len(seen) != n-(n+2)/3+2 {
+		t.Fatalf("Snapshot: got %d keys", len(snap))
+	}
+}
+
+func TestStoreRestore(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fc := newFakeClock(start)
+	mem := store.NewMemoryWithClock(fc)
+
+	mem.Restore([]store.Entry{
+		{Key: "a", Val: "1"},
+		{Key: "b", Val: "2", ExpiresAt: start.Add(5 * time.Second)},
+		{Key: "stale", Val: "3", ExpiresAt: start.Add(-time.Second)},
+	})
+
+	if v, ok, _ := mem.Get("a"); !ok || v != "1" {
+		t.Fatalf("a: got (%q,%v)", v, ok)
+	}
+	if secs, exists, hasExp := mem.TTL("b"); !exists || !hasExp || secs != 5 {
+		t.Fatalf("b TTL: got (secs=%d, exists=%v, hasExp=%v), want (5,true,true)", secs, exists, hasExp)
+	}
+	if _, ok, _ := mem.Get("stale"); ok {
+		t.Fatalf("expired entry was restored")
+	}
+}
+
+func TestStoreSetWith(t *testing.T) {
+	clk := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(clk)
+
+	if _, existed, written, _, _ := mem.SetWith("k", "a", store.SetOptions{Cond: store.SetXX}); existed || written {
+		t.Fatalf("XX on missing key: existed=%v written=%v", existed, written)
+	}
+	if _, _, written, _, _ := mem.SetWith("k", "a", store.SetOptions{Cond: store.SetNX, TTL: time.Minute}); !written {
+		t.Fatalf("NX on missing key was not written")
+	}
+	old, existed, written, _, _ := mem.SetWith("k", "b", store.SetOptions{Cond: store.SetNX})
+	if old != "a" || !existed || written {
+		t.Fatalf("NX on existing key: got (%q, %v, %v)", old, existed, written)
+	}
+
+	if _, _, written, _, _ := mem.SetWith("k", "c", store.SetOptions{KeepTTL: true}); !written {
+		t.Fatalf("KeepTTL write failed")
+	}
+	if secs, _, hasExp := mem.TTL("k"); !hasExp || secs != 60 {
+		t.Fatalf("TTL after KeepTTL: got %d (hasExp=%v), want 60", secs, hasExp)
+	}
+
+	// an expired key counts as missing
+	clk.Advance(2 * time.Minute)
+	if old, existed, written, _, _ := mem.SetWith("k", "d", store.SetOptions{Cond: store.SetNX}); old != "" || existed || !written {
+		t.Fatalf("NX over expired key: got (%q, %v, %v)", old, existed, written)
+	}
+
+	mem.SetWith("k", "e", store.SetOptions{ExpiresAt: clk.Now().Add(-time.Second)})
+	if _, ok, _ := mem.Get("k"); ok {
+		t.Fatalf("past ExpiresAt should leave no key behind")
+	}
+}
@@ -0,0 +1,88 @@
+package store
+
+// Multi-key operations run under a single lock acquisition, so other
+// clients see all of their effects or none.
+
+// MGet returns the values at keys, with ok[i] false for missing ones and
+// ones that don't hold strings.
+func (mem *memory) MGet(keys ...string) (vals []string, ok []bool) {
+	vals, ok = make([]string, len(keys)), make([]bool, len(keys))
+	now := mem.clock.Now()
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	for i, k := range keys {
+		if e, found := mem.m[k]; found && !e.expired(now) {
+			e.meta.touch(now)
+			if v, err := e.str(); err == nil {
+				vals[i], ok[i] = v, true
+			}
+		}
+	}
+	return vals, ok
+}
+
+// MSet sets alternating keys and values, clearing any TTLs.
+func (mem *memory) MSet(kvs ...string) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	for i := 0; i+1 < len(kvs); i += 2 {
+		mem.put(kvs[i], entry{val: stringValue(kvs[i+1])})
+	}
+}
+
+// MSetNX is MSet, but only if none of the keys exist. It reports whether
+// the keys were set.
+func (mem *memory) MSetNX(kvs ...string) bool {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	now := mem.clock.Now()
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if e, ok := mem.m[kvs[i]]; ok && !e.expired(now) {
+			return false
+		}
+	}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		mem.put(kvs[i], entry{val: stringValue(kvs[i+1])})
+	}
+	return true
+}
+
+// DelKeys deletes keys and returns the ones that existed, each once.
+func (mem *memory) DelKeys(keys ...string) []string {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	now := mem.clock.Now()
+	var deleted []string
+	for _, k := range keys {
+		e, ok := mem.m[k]
+		if !ok {
+			continue
+		}
+		mem.del(k)
+		if !e.expired(now) {
+			deleted = append(deleted, k)
+		}
+	}
+	return deleted
+}
+
+// Exists counts how many of keys exist; a key named twice counts twice.
+func (mem *memory) Exists(keys ...string) int {
+	now := mem.clock.Now()
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	var n int
+	for _, k := range keys {
+		if e, ok := mem.m[k]; ok && !e.expired(now) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,66 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestMSet_ClearsTTL(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	mem.Set("a", "old")
+	mem.ExpireAt("a", fc.Now().Add(time.Minute))
+	mem.MSet("a", "1", "b", "2")
+
+	vals, ok := mem.MGet("a", "b", "c")
+	if !reflect.DeepEqual(vals, []string{"1", "2", ""}) || !reflect.DeepEqual(ok, []bool{true, true, false}) {
+		t.Fatalf("MGet: got %q %v", vals, ok)
+	}
+	if _, _, hasExp := mem.TTL("a"); hasExp {
+		t.Fatalf("MSet kept the TTL")
+	}
+}
+
+func TestMSetNX_AllOrNothing(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	mem.Set("b", "x")
+	if mem.MSetNX("a", "1", "b", "2") {
+		t.Fatalf("MSetNX set keys while one existed")
+	}
+	if _, ok, _ := mem.Get("a"); ok {
+		t.Fatalf("MSetNX partially applied")
+	}
+
+	// an expired key doesn't count as existing
+	mem.ExpireAt("b", fc.Now().Add(time.Second))
+	fc.Advance(2 * time.Second)
+	if !mem.MSetNX("a", "1", "b", "2") {
+		t.Fatalf("MSetNX refused over an expired key")
+	}
+}
+
+func TestDelKeys_Exists(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	mem.MSet("a", "1", "b", "2", "gone", "3")
+	mem.ExpireAt("gone", fc.Now().Add(time.Second))
+	fc.Advance(2 * time.Second)
+
+	if n := mem.Exists("a", "a", "b", "gone", "missing"); n != 3 {
+		t.Fatalf("Exists: got %d, want 3", n)
+	}
+	got := mem.DelKeys("a", "a", "gone", "missing")
+	if !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("DelKeys: got %q, want [a]", got)
+	}
+	if n := mem.Exists("a", "b"); n != 1 {
+		t.Fatalf("Exists after DelKeys: got %d, want 1", n)
+	}
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// keyMeta records when a key was last accessed and how often, for OBJECT
+// IDLETIME and FREQ. Every copy of an entry shares it and it's updated
+// atomically, so a read can record an access under the read lock.
+type keyMeta struct {
+	// the last access as unix milliseconds << 8 | the LFU counter
+	state atomic.Uint64
+}
+
+// The access frequency is redis's logarithmic LFU counter with its default
+// lfu-log-factor and lfu-decay-time: it starts at lfuInit, grows ever more
+// slowly with each access and loses one per idle minute.
+const (
+	lfuInit      = 5
+	lfuLogFactor = 10
+	lfuDecay     = time.Minute
+)
+
+func newKeyMeta(now time.Time) *keyMeta {
+	m := &keyMeta{}
+	m.state.Store(uint64(now.UnixMilli())<<8 | lfuInit)
+	return m
+}
+
+// touch records an access at now.
+func (m *keyMeta) touch(now time.Time) {
+	counter := m.freq(now)
+	if counter < 255 {
+		base := max(counter-lfuInit, 0)
+		if rand.Float64() < 1/float64(base*lfuLogFactor+1) {
+			counter++
+		}
+	}
+	m.state.Store(uint64(now.UnixMilli())<<8 | uint64(counter))
+}
+
+func (m *keyMeta) lastAccess() time.Time {
+	return time.UnixMilli(int64(m.state.Load() >> 8))
+}
+
+// freq returns the LFU counter as of now, decayed for the time since the
+// last access.
+func (m *keyMeta) freq(now time.Time) int {
+	st := m.state.Load()
+	counter := int(st & 0xff)
+	idle := now.Sub(time.UnixMilli(int64(st >> 8)))
+	return max(counter-int(idle/lfuDecay), 0)
+}
+
+// ObjectInfo describes how a key is stored, for OBJECT.
+type ObjectInfo struct {
+	Encoding string
+	Idle     time.Duration
+	Freq     int
+}
+
+// Object returns k's ObjectInfo. Looking at a key this way doesn't count as
+// accessing it.
+func (mem *memory) Object(k string) (ObjectInfo, bool) {
+	now := mem.clock.Now()
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	e, ok := mem.m[k]
+	if !ok || e.expired(now) {
+		return ObjectInfo{}, false
+	}
+	return ObjectInfo{
+		Encoding: e.val.encoding(),
+		Idle:     max(now.Sub(e.meta.lastAccess()), 0),
+		Freq:     e.meta.freq(now),
+	}, true
+}
+
+// stringEncoding names the representation redis would pick for v: integers
+// are stored as such, short strings inline with their header.
+func stringEncoding(v string) string {
+	if _, ok := ParseInt(v); ok {
+		return "int"
+	}
+	if len(v) <= 44 {
+		return "embstr"
+	}
+	return "raw"
+}
+
+// Touch records an access to each of keys and returns how many exist.
+func (mem *memory) Touch(keys ...string) int {
+	now := mem.clock.Now()
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	var n int
+	for _, k := range keys {
+		if e, ok := mem.m[k]; ok && !e.expired(now) {
+			e.meta.touch(now)
+			n++
+		}
+	}
+	return n
+}
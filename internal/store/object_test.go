@@ -0,0 +1,147 @@
+package store_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestObject_IdleTime(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	mem.Set("k", "v")
+	fc.Advance(10 * time.Second)
+	if info, _ := mem.Object("k"); info.Idle != 10*time.Second {
+		t.Fatalf("idle after set: got %v, want 10s", info.Idle)
+	}
+	// looking at it with Object doesn't reset it, reading it does
+	if info, _ := mem.Object("k"); info.Idle != 10*time.Second {
+		t.Fatalf("Object reset the idle time: %v", info.Idle)
+	}
+	mem.Get("k")
+	if info, _ := mem.Object("k"); info.Idle != 0 {
+		t.Fatalf("idle after get: got %v, want 0", info.Idle)
+	}
+
+	fc.Advance(5 * time.Second)
+	if n := mem.Touch("k", "missing"); n != 1 {
+		t.Fatalf("Touch: got %d, want 1", n)
+	}
+	if info, _ := mem.Object("k"); info.Idle != 0 {
+		t.Fatalf("idle after touch: got %v, want 0", info.Idle)
+	}
+	if _, ok := mem.Object("missing"); ok {
+		t.Fatalf("Object found a missing key")
+	}
+}
+
+func TestObject_Freq(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	mem.Set("k", "v")
+	if info, _ := mem.Object("k"); info.Freq != 5 {
+		t.Fatalf("initial freq: got %d, want 5", info.Freq)
+	}
+	for range 1000 {
+		mem.Get("k")
+	}
+	hot, _ := mem.Object("k")
+	if hot.Freq <= 5 || hot.Freq > 255 {
+		t.Fatalf("freq after 1000 reads: got %d", hot.Freq)
+	}
+
+	// one point is lost per idle minute
+	fc.Advance(3 * time.Minute)
+	if info, _ := mem.Object("k"); info.Freq != hot.Freq-3 {
+		t.Fatalf("decayed freq: got %d, want %d", info.Freq, hot.Freq-3)
+	}
+}
+
+func TestObject_Encoding(t *testing.T) {
+	mem := store.NewMemory()
+	cases := map[string]string{
+		"12345":                 "int",
+		"-7":                    "int",
+		"012":                   "embstr",
+		"hello":                 "embstr",
+		strings.Repeat("x", 44): "embstr",
+		strings.Repeat("x", 45): "raw",
+	}
+	for v, want := range cases {
+		mem.Set("k", v)
+		if info, _ := mem.Object("k"); info.Encoding != want {
+			t.Errorf("encoding of %q: got %s, want %s", v, info.Encoding, want)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	if _, err := mem.Rename("missing", "b", false); !errors.Is(err, store.ErrNoSuchKey) {
+		t.Fatalf("Rename missing: got %v", err)
+	}
+
+	mem.SetEx("a", "1", time.Minute)
+	mem.Set("b", "2")
+	if ok, _ := mem.Rename("a", "b", true); ok {
+		t.Fatalf("Rename nx overwrote an existing key")
+	}
+	if ok, err := mem.Rename("a", "b", false); !ok || err != nil {
+		t.Fatalf("Rename: got %v, %v", ok, err)
+	}
+	if v, _, _ := mem.Get("b"); v != "1" {
+		t.Fatalf("b: got %q, want 1", v)
+	}
+	if _, ok, _ := mem.Get("a"); ok {
+		t.Fatalf("a still exists")
+	}
+	if ttl, _, hasExp := mem.TTL("b"); !hasExp || ttl != 60 {
+		t.Fatalf("TTL not carried over: %d, %v", ttl, hasExp)
+	}
+
+	if ok, _ := mem.Rename("b", "b", false); !ok {
+		t.Fatalf("Rename to itself failed")
+	}
+	if ok, _ := mem.Rename("b", "b", true); ok {
+		t.Fatalf("Rename nx to itself reported a move")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	if mem.Copy("missing", "b", false) {
+		t.Fatalf("Copy of a missing key succeeded")
+	}
+	mem.SetEx("a", "1", time.Minute)
+	mem.Set("b", "2")
+	if mem.Copy("a", "b", false) {
+		t.Fatalf("Copy overwrote without replace")
+	}
+	if !mem.Copy("a", "b", true) {
+		t.Fatalf("Copy with replace failed")
+	}
+	if v, _, _ := mem.Get("b"); v != "1" {
+		t.Fatalf("b: got %q, want 1", v)
+	}
+	if v, _, _ := mem.Get("a"); v != "1" {
+		t.Fatalf("source changed: %q", v)
+	}
+	if ttl, _, hasExp := mem.TTL("b"); !hasExp || ttl != 60 {
+		t.Fatalf("TTL not copied: %d, %v", ttl, hasExp)
+	}
+	if typ := mem.Type("b"); typ != store.KindString {
+		t.Fatalf("Type: got %s", typ)
+	}
+	if typ := mem.Type("missing"); typ != store.KindNone {
+		t.Fatalf("Type of missing key: got %s", typ)
+	}
+}
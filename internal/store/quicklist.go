@@ -0,0 +1,296 @@
+package store
+
+import "slices"
+
+// listNodeMax is how many elements a quicklist node holds, redis's
+// list-max-listpack-size counted in entries.
+const listNodeMax = 128
+
+// quicklist is a list stored as a doubly linked list of small slices, like
+// redis's quicklist: a push or pop at either end touches a single node,
+// and finding an index walks nodes rather than elements.
+type quicklist struct {
+	head, tail *listNode
+	n          int
+}
+
+type listNode struct {
+	prev, next *listNode
+	elems      []string
+}
+
+func (l *quicklist) kind() Kind { return KindList }
+
+// a list that fits one node is what redis keeps as a single listpack
+func (l *quicklist) encoding() string {
+	if l.head == l.tail {
+		return "listpack"
+	}
+	return "quicklist"
+}
+
+func (l *quicklist) clone() value {
+	c := &quicklist{}
+	for nd := l.head; nd != nil; nd = nd.next {
+		c.link(c.tail, &listNode{elems: slices.Clone(nd.elems)})
+	}
+	c.n = l.n
+	return c
+}
+
+func (l *quicklist) len() int {
+	return l.n
+}
+
+// link inserts nd after at, or at the front if at is nil.
+func (l *quicklist) link(at, nd *listNode) {
+	nd.prev = at
+	if at == nil {
+		nd.next, l.head = l.head, nd
+	} else {
+		nd.next, at.next = at.next, nd
+	}
+	if nd.next == nil {
+		l.tail = nd
+	} else {
+		nd.next.prev = nd
+	}
+}
+
+func (l *quicklist) unlink(nd *listNode) {
+	if nd.prev == nil {
+		l.head = nd.next
+	} else {
+		nd.prev.next = nd.next
+	}
+	if nd.next == nil {
+		l.tail = nd.prev
+	} else {
+		nd.next.prev = nd.prev
+	}
+}
+
+func (l *quicklist) push(side Side, v string) {
+	if side == Left {
+		if l.head == nil || len(l.head.elems) >= listNodeMax {
+			l.link(nil, &listNode{})
+		}
+		l.head.elems = slices.Insert(l.head.elems, 0, v)
+	} else {
+		if l.tail == nil || len(l.tail.elems) >= listNodeMax {
+			l.link(l.tail, &listNode{})
+		}
+		l.tail.elems = append(l.tail.elems, v)
+	}
+	l.n++
+}
+
+// pop removes and returns the element at side; the list must not be empty.
+func (l *quicklist) pop(side Side) string {
+	var v string
+	if side == Left {
+		nd := l.head
+		v, nd.elems[0] = nd.elems[0], ""
+		nd.elems = nd.elems[1:]
+		if len(nd.elems) == 0 {
+			l.unlink(nd)
+		}
+	} else {
+		nd := l.tail
+		last := len(nd.elems) - 1
+		v, nd.elems[last] = nd.elems[last], ""
+		nd.elems = nd.elems[:last]
+		if len(nd.elems) == 0 {
+			l.unlink(nd)
+		}
+	}
+	l.n--
+	return v
+}
+
+// locate returns the node holding element i, 0 <= i < l.n, and i's offset
+// in it, walking from whichever end is closer.
+func (l *quicklist) locate(i int) (*listNode, int) {
+	if i < l.n/2 {
+		for nd := l.head; ; nd = nd.next {
+			if i < len(nd.elems) {
+				return nd, i
+			}
+			i -= len(nd.elems)
+		}
+	}
+	i = l.n - 1 - i // counted from the tail
+	for nd := l.tail; ; nd = nd.prev {
+		if i < len(nd.elems) {
+			return nd, len(nd.elems) - 1 - i
+		}
+		i -= len(nd.elems)
+	}
+}
+
+// index normalizes a redis index, negative ones counting from the tail,
+// and reports whether it's in range.
+func (l *quicklist) index(i int) (int, bool) {
+	if i < 0 {
+		i += l.n
+	}
+	return i, i >= 0 && i < l.n
+}
+
+func (l *quicklist) at(i int) string {
+	nd, j := l.locate(i)
+	return nd.elems[j]
+}
+
+func (l *quicklist) set(i int, v string) {
+	nd, j := l.locate(i)
+	nd.elems[j] = v
+}
+
+// insert puts v at index i, 0 <= i <= l.n, splitting a full node in two.
+func (l *quicklist) insert(i int, v string) {
+	switch {
+	case i == 0:
+		l.push(Left, v)
+		return
+	case i == l.n:
+		l.push(Right, v)
+		return
+	}
+
+	nd, j := l.locate(i)
+	if len(nd.elems) >= listNodeMax {
+		half := len(nd.elems) / 2
+		next := &listNode{elems: slices.Clone(nd.elems[half:])}
+		clear(nd.elems[half:])
+		nd.elems = nd.elems[:half]
+		l.link(nd, next)
+		if j >= half {
+			nd, j = next, j-half
+		}
+	}
+	nd.elems = slices.Insert(nd.elems, j, v)
+	l.n++
+}
+
+// slice returns the elements from start to stop, inclusive and in range.
+func (l *quicklist) slice(start, stop int) []string {
+	out := make([]string, 0, stop-start+1)
+	nd, j := l.locate(start)
+	for len(out) < cap(out) {
+		if j == len(nd.elems) {
+			nd, j = nd.next, 0
+		}
+		out = append(out, nd.elems[j])
+		j++
+	}
+	return out
+}
+
+// values returns every element.
+func (l *quicklist) values() []string {
+	out := make([]string, 0, l.n)
+	for nd := l.head; nd != nil; nd = nd.next {
+		out = append(out, nd.elems...)
+	}
+	return out
+}
+
+// each calls fn with every element and its index until fn returns false,
+// going from the tail if reverse is set.
+func (l *quicklist) each(reverse bool, fn func(i int, v string) bool) {
+	if !reverse {
+		i := 0
+		for nd := l.head; nd != nil; nd = nd.next {
+			for _, v := range nd.elems {
+				if !fn(i, v) {
+					return
+				}
+				i++
+			}
+		}
+		return
+	}
+	i := l.n - 1
+	for nd := l.tail; nd != nil; nd = nd.prev {
+		for j := len(nd.elems) - 1; j >= 0; j-- {
+			if !fn(i, nd.elems[j]) {
+				return
+			}
+			i--
+		}
+	}
+}
+
+// trim drops front elements from the head and back elements from the
+// tail, a whole node at a time where it can.
+func (l *quicklist) trim(front, back int) {
+	for front > 0 {
+		nd := l.head
+		if len(nd.elems) <= front {
+			front -= len(nd.elems)
+			l.n -= len(nd.elems)
+			l.unlink(nd)
+			continue
+		}
+		clear(nd.elems[:front])
+		nd.elems = nd.elems[front:]
+		l.n -= front
+		front = 0
+	}
+	for back > 0 {
+		nd := l.tail
+		if len(nd.elems) <= back {
+			back -= len(nd.elems)
+			l.n -= len(nd.elems)
+			l.unlink(nd)
+			continue
+		}
+		keep := len(nd.elems) - back
+		clear(nd.elems[keep:])
+		nd.elems = nd.elems[:keep]
+		l.n -= back
+		back = 0
+	}
+}
+
+// remove deletes up to count elements equal to v, all of them if count is
+// 0, going from the tail if reverse is set. It returns how many it removed.
+func (l *quicklist) remove(v string, count int, reverse bool) int {
+	var removed int
+	nd := l.head
+	if reverse {
+		nd = l.tail
+	}
+	for nd != nil && (count == 0 || removed < count) {
+		next := nd.next
+		if reverse {
+			next = nd.prev
+		}
+
+		n := len(nd.elems)
+		if reverse {
+			for k := n - 1; k >= 0 && (count == 0 || removed < count); k-- {
+				if nd.elems[k] == v {
+					nd.elems = slices.Delete(nd.elems, k, k+1)
+					removed++
+				}
+			}
+		} else {
+			for k := 0; k < len(nd.elems) && (count == 0 || removed < count); {
+				if nd.elems[k] == v {
+					nd.elems = slices.Delete(nd.elems, k, k+1)
+					removed++
+				} else {
+					k++
+				}
+			}
+		}
+		if len(nd.elems) == 0 {
+			l.unlink(nd)
+		}
+		l.n -= n - len(nd.elems)
+		nd = next
+	}
+	return removed
+}
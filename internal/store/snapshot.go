@@ -0,0 +1,148 @@
+package store
+
+import "time"
+
+// Entry is a point-in-time copy of one live key. Exactly one of the value
+// fields is set, according to the key's type.
+type Entry struct {
+	Key       string
+	Val       string      // a string
+	List      []string    // a list's elements, head first
+	Hash      []HashField // a hash's fields, in the order they were added
+	ExpiresAt time.Time   // zero if the key has no TTL
+}
+
+// HashField is one field of a hash in an Entry.
+type HashField struct {
+	Field, Val string
+	ExpiresAt  time.Time // zero if the field has no TTL
+}
+
+// Kind returns the type of the value e holds.
+func (e Entry) Kind() Kind {
+	switch {
+	case e.List != nil:
+		return KindList
+	case e.Hash != nil:
+		return KindHash
+	}
+	return KindString
+}
+
+// snapshotBatch bounds how many keys, and elements of their values,
+// Snapshot copies per hold of the lock.
+const snapshotBatch = 1024
+
+// Snapshot copies every live key. String values are immutable, so only
+// their headers are copied, and collections are copied element by element.
+// Keys are copied in batches in the order mem.keys gives them, releasing
+// the lock in between so writers aren't held up by a large keyspace, and
+// the (much slower) serialization happens afterwards without it.
+//
+// Each key is copied whole, but the copy as a whole is only a point in
+// time if nothing writes meanwhile: a key renamed during the snapshot may
+// be missed or copied twice, and keys created after it started are left
+// out. Saves and rewrites take it with no command running for that reason.
+func (mem *memory) Snapshot() []Entry {
+	mem.mu.RLock()
+	end := mem.keys.next
+	out := make([]Entry, 0, len(mem.m))
+	mem.mu.RUnlock()
+
+	for seq := uint64(0); seq <= end; {
+		out, seq = mem.snapshotBatch(out, seq, end)
+	}
+	return out
+}
+
+// snapshotBatch appends copies of the live keys from sequence number seq up
+// to end to out, stopping after about snapshotBatch keys and elements. It
+// returns the sequence number to continue from, past end once it's done.
+func (mem *memory) snapshotBatch(out []Entry, seq, end uint64) ([]Entry, uint64) {
+	now := mem.clock.Now()
+
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	slots := mem.keys.slots
+	copied := 0
+	for i := mem.keys.search(seq); i < len(slots) && slots[i].seq <= end; i++ {
+		s := slots[i]
+		if copied >= snapshotBatch {
+			return out, s.seq
+		}
+		copied++
+		if e := mem.m[s.key]; s.live && !e.expired(now) {
+			ent := e.export(s.key, now)
+			copied += len(ent.List) + len(ent.Hash)
+			out = append(out, ent)
+		}
+	}
+	return out, end + 1
+}
+
+// export copies e, stored under k, into an Entry, leaving out hash fields
+// that expired by now.
+func (e entry) export(k string, now time.Time) Entry {
+	ent := Entry{Key: k, ExpiresAt: e.expiresAt}
+	switch v := e.val.(type) {
+	case stringValue:
+		ent.Val = string(v)
+	case *quicklist:
+		ent.List = v.values()
+	case *hashValue:
+		ent.Hash = make([]HashField, 0, v.len())
+		v.each(func(hf hashField) bool {
+			if !hf.expired(now) {
+				ent.Hash = append(ent.Hash, HashField{hf.name, hf.val, hf.expiresAt})
+			}
+			return true
+		})
+	}
+	return ent
+}
+
+// Restore loads entries into the store, e.g. from a snapshot file on boot.
+// Entries and hash fields whose expiry already passed are skipped.
+func (mem *memory) Restore(entries []Entry) {
+	now := mem.clock.Now()
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	for _, e := range entries {
+		if !e.ExpiresAt.IsZero() && !e.ExpiresAt.After(now) {
+			continue
+		}
+		if len(e.List) == 0 && len(e.Hash) == 0 && e.Kind() != KindString {
+			continue // collections don't exist empty
+		}
+		var v value
+		switch e.Kind() {
+		case KindList:
+			l := &quicklist{}
+			for _, el := range e.List {
+				l.push(Right, el)
+			}
+			v = l
+		case KindHash:
+			h := &hashValue{}
+			for _, hf := range e.Hash {
+				if !hf.ExpiresAt.IsZero() && !hf.ExpiresAt.After(now) {
+					continue
+				}
+				h.set(hf.Field, hf.Val)
+				if !hf.ExpiresAt.IsZero() {
+					h.expire(hf.Field, hf.ExpiresAt)
+				}
+			}
+			if h.len() == 0 {
+				continue
+			}
+			v = h
+		default:
+			v = stringValue(e.Val)
+		}
+		mem.put(e.Key, entry{val: v, expiresAt: e.ExpiresAt})
+	}
+}
@@ -4,13 +4,143 @@ import (
 	"time"
 )
 
-type KV interface {
-	Get(k string) (string, bool)
-	Set(k, v string)
-	SetEx(k, v string, ttl time.Duration)
+// SetCond restricts when SetWith writes.
+type SetCond int
+
+const (
+	SetAlways SetCond = iota
+	SetNX             // only if the key doesn't exist
+	SetXX             // only if it does
+)
+
+type SetOptions struct {
+	Cond SetCond
+	// the new expiry, either relative to the store's clock or absolute;
+	// with neither set the key doesn't expire
+	TTL       time.Duration
+	ExpiresAt time.Time
+	// KeepTTL keeps the key's current expiry instead
+	KeepTTL bool
+	// Get asks for the old value, which requires it to be a string
+	Get bool
+}
+
+// ExpireCond restricts when ExpireWith changes an expiry; conditions can be
+// combined.
+type ExpireCond int
+
+const (
+	ExpireNX ExpireCond = 1 << iota // only if the key has no expiry
+	ExpireXX                        // only if it has one
+	ExpireGT                        // only if later than the current one
+	ExpireLT                        // only if earlier
+)
+
+type ExpireOptions struct {
+	Cond ExpireCond
+	// the new expiry: At if set, otherwise TTL from now
+	At  time.Time
+	TTL time.Duration
+	// Persist removes the expiry instead
+	Persist bool
+}
+
+// ExpireResult says what ExpireWith did.
+type ExpireResult int
+
+const (
+	ExpireSkipped ExpireResult = iota // missing key or condition not met
+	ExpireSet
+	ExpireDeleted // the new expiry had already passed
+	ExpireNoField // HExpire only: the hash has no such field
+)
+
+// Keyspace holds the operations that work on keys of any type.
+type Keyspace interface {
 	Del(k string) bool
 	TTL(k string) (int64, bool, bool)
-	Persist(k string) bool
+	PTTL(k string) (int64, bool, bool)
+	ExpireTime(k string) (time.Time, bool)
+	ExpireAt(k string, at time.Time) bool
+	ExpireWith(k string, opt ExpireOptions) (ExpireResult, time.Time)
+	DelKeys(keys ...string) []string
+	Exists(keys ...string) int
+	Scan(cursor uint64, count int) ([]string, uint64)
+	RandomKey() (string, bool)
+	Len() int
+	Type(k string) Kind
+	Rename(src, dst string, nx bool) (bool, error)
+	Copy(src, dst string, replace bool) bool
+	Touch(keys ...string) int
+	Object(k string) (ObjectInfo, bool)
+	Flush()
+	Snapshot() []Entry
+	Restore(entries []Entry)
+}
+
+// Strings holds the operations on string values.
+type Strings interface {
+	Get(k string) (string, bool, error)
+	Set(k, v string)
+	SetWith(k, v string, opt SetOptions) (string, bool, bool, time.Time, error)
+	SetEx(k, v string, ttl time.Duration)
+	GetEx(k string, opt ExpireOptions) (string, bool, ExpireResult, time.Time, error)
+	IncrBy(k string, delta int64) (int64, error)
+	IncrByFloat(k string, delta float64) (float64, error)
+	Append(k, v string) (int, error)
+	SetRange(k string, offset int, v string) (int, error)
+	GetDel(k string) (string, bool, error)
+	MGet(keys ...string) ([]string, []bool)
+	MSet(kvs ...string)
+	MSetNX(kvs ...string) bool
+}
+
+// Lists holds the operations on list values. A list is deleted when its
+// last element is removed.
+type Lists interface {
+	Push(k string, side Side, existing bool, vals ...string) (int, error)
+	Pop(k string, side Side, count int) ([]string, bool, error)
+	MPop(keys []string, side Side, count int) (string, []string, bool, error)
+	Move(src, dst string, from, to Side) (string, bool, bool, error)
+	LLen(k string) (int, error)
+	LRange(k string, start, stop int) ([]string, error)
+	LIndex(k string, i int) (string, bool, error)
+	LSet(k string, i int, v string) error
+	LRem(k string, count int, v string) (int, bool, error)
+	LTrim(k string, start, stop int) (bool, bool, error)
+	LInsert(k string, before bool, pivot, v string) (int, error)
+	LPos(k, v string, rank, count, maxlen int) ([]int, error)
+}
+
+// Hashes holds the operations on hash values. Like a list, a hash is
+// deleted when its last field is removed, or expires.
+type Hashes interface {
+	HSet(k string, pairs ...string) (int, error)
+	HSetNX(k, f, v string) (bool, error)
+	HGet(k, f string) (string, bool, error)
+	HMGet(k string, fields ...string) ([]string, []bool, error)
+	HDel(k string, fields ...string) (int, bool, error)
+	HLen(k string) (int, error)
+	HStrLen(k, f string) (int, error)
+	HExists(k, f string) (bool, error)
+	HGetAll(k string) ([]string, error)
+	HKeys(k string) ([]string, error)
+	HVals(k string) ([]string, error)
+	HIncrBy(k, f string, delta int64) (int64, error)
+	HIncrByFloat(k, f string, delta float64) (float64, time.Time, error)
+	HScan(k string, cursor uint64, count int) ([]string, uint64, error)
+	HRandField(k string, count int) ([]string, error)
+	HExpire(k string, opt ExpireOptions, fields ...string) ([]ExpireResult, time.Time, bool, error)
+	HExpireTime(k string, fields ...string) ([]time.Time, []bool, error)
+	HTTL(k string, fields ...string) ([]time.Duration, []bool, error)
+}
+
+// KV is one database: its keyspace and the operations of every type.
+type KV interface {
+	Keyspace
+	Strings
+	Lists
+	Hashes
 }
 
 func NewMemory() *memory {
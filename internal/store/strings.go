@@ -0,0 +1,93 @@
+package store
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxStringLen caps values grown by APPEND and SETRANGE, matching redis'
+// default proto-max-bulk-len.
+const MaxStringLen = 512 << 20
+
+var ErrTooLarge = errors.New("string exceeds maximum allowed size (proto-max-bulk-len)")
+
+// Append appends v to the value at k, creating it if missing, and returns
+// the new length. The key keeps its TTL.
+func (mem *memory) Append(k, v string) (int, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	e, ok := mem.m[k]
+	if !ok || e.expired(mem.clock.Now()) {
+		e = entry{}
+	}
+	cur, err := e.str()
+	if err != nil {
+		return 0, err
+	}
+	if len(cur)+len(v) > MaxStringLen {
+		return 0, ErrTooLarge
+	}
+
+	e.val = stringValue(cur + v)
+	mem.put(k, e)
+	return len(cur) + len(v), nil
+}
+
+// SetRange overwrites the value at k starting at offset, zero-padding it
+// if it's shorter, and returns the new length. An empty v changes nothing,
+// so it doesn't create a missing key either.
+func (mem *memory) SetRange(k string, offset int, v string) (int, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	e, ok := mem.m[k]
+	if !ok || e.expired(mem.clock.Now()) {
+		e = entry{}
+	}
+	cur, err := e.str()
+	if err != nil {
+		return 0, err
+	}
+	if v == "" {
+		return len(cur), nil
+	}
+	if offset+len(v) > MaxStringLen {
+		return 0, ErrTooLarge
+	}
+
+	var b strings.Builder
+	b.Grow(max(len(cur), offset+len(v)))
+	if offset > len(cur) {
+		b.WriteString(cur)
+		b.WriteString(strings.Repeat("\x00", offset-len(cur)))
+	} else {
+		b.WriteString(cur[:offset])
+	}
+	b.WriteString(v)
+	if end := offset + len(v); end < len(cur) {
+		b.WriteString(cur[end:])
+	}
+
+	e.val = stringValue(b.String())
+	mem.put(k, e)
+	return b.Len(), nil
+}
+
+// GetDel deletes k and returns the value it held, leaving k alone if it
+// isn't a string.
+func (mem *memory) GetDel(k string) (string, bool, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	e, ok := mem.m[k]
+	if !ok || e.expired(mem.clock.Now()) {
+		return "", false, nil
+	}
+	v, err := e.str()
+	if err != nil {
+		return "", false, err
+	}
+	mem.del(k)
+	return v, true, nil
+}
@@ -0,0 +1,92 @@
+package store_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestAppend(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	if n, err := mem.Append("k", "ab"); err != nil || n != 2 {
+		t.Fatalf("Append on missing key: got %d, %v", n, err)
+	}
+	mem.ExpireAt("k", fc.Now().Add(time.Minute))
+	if n, _ := mem.Append("k", "cd"); n != 4 {
+		t.Fatalf("Append: got %d, want 4", n)
+	}
+	if v, _, _ := mem.Get("k"); v != "abcd" {
+		t.Fatalf("value: got %q", v)
+	}
+	if _, _, hasExp := mem.TTL("k"); !hasExp {
+		t.Fatalf("Append dropped the TTL")
+	}
+}
+
+func TestAppend_Concurrent(t *testing.T) {
+	mem := store.NewMemory()
+	const workers, each = 8, 500
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range each {
+				mem.Append("k", "x")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _, _ := mem.Get("k"); len(v) != workers*each {
+		t.Fatalf("got length %d, want %d", len(v), workers*each)
+	}
+}
+
+func TestSetRange(t *testing.T) {
+	mem := store.NewMemory()
+
+	if n, _ := mem.SetRange("k", 3, ""); n != 0 {
+		t.Fatalf("empty SetRange on missing key: got %d", n)
+	}
+	if _, ok, _ := mem.Get("k"); ok {
+		t.Fatalf("empty SetRange created the key")
+	}
+
+	mem.Set("k", "Hello World")
+	if n, _ := mem.SetRange("k", 6, "Redis"); n != 11 {
+		t.Fatalf("SetRange: got %d, want 11", n)
+	}
+	if v, _, _ := mem.Get("k"); v != "Hello Redis" {
+		t.Fatalf("value: got %q", v)
+	}
+
+	if n, _ := mem.SetRange("pad", 3, "x"); n != 4 {
+		t.Fatalf("SetRange past the end: got %d, want 4", n)
+	}
+	if v, _, _ := mem.Get("pad"); v != "\x00\x00\x00x" {
+		t.Fatalf("padded value: got %q", v)
+	}
+
+	if _, err := mem.SetRange("k", store.MaxStringLen, "x"); !errors.Is(err, store.ErrTooLarge) {
+		t.Fatalf("too large: got %v", err)
+	}
+}
+
+func TestGetDel(t *testing.T) {
+	mem := store.NewMemory()
+	mem.Set("k", "v")
+
+	if v, ok, _ := mem.GetDel("k"); !ok || v != "v" {
+		t.Fatalf("GetDel: got %q, %v", v, ok)
+	}
+	if _, ok, _ := mem.GetDel("k"); ok {
+		t.Fatalf("second GetDel found the key")
+	}
+}
@@ -33,11 +33,28 @@ func (mem *memory) sweepExpired() {
 	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
 	sampled := keys[:N]
 
+	var expired []string
 	mem.mu.Lock()
 	for _, k := range sampled {
-		if e, ok := mem.m[k]; ok && e.expired(now) {
-			delete(mem.m, k)
+		e, ok := mem.m[k]
+		switch {
+		case !ok:
+		case e.expired(now):
+			mem.del(k)
+			expired = append(expired, k)
+		default:
+			// a hash that outlives some of its fields drops just those
+			if h, ok := e.val.(*hashValue); ok && h.stale(now) {
+				mem.purge(k, h, now)
+			}
+		}
+	}
+	onExpire := mem.onExpire
+	mem.unlock()
+
+	if onExpire != nil {
+		for _, k := range expired {
+			onExpire(k)
 		}
 	}
-	mem.mu.Unlock()
 }
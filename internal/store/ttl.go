@@ -4,8 +4,14 @@ import (
 	"time"
 )
 
+// expired reports whether e is gone by now: its TTL passed, or it's a
+// hash whose fields have all expired.
 func (e entry) expired(now time.Time) bool {
-	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+	if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+		return true
+	}
+	h, ok := e.val.(*hashValue)
+	return ok && h.expired(now)
 }
 
 func (mem *memory) TTL(k string) (int64, bool, bool) {
@@ -18,24 +24,135 @@ func (mem *memory) TTL(k string) (int64, bool, bool) {
 		return 0, true, false
 	}
 
-	remaining := e.expiresAt.Sub(mem.clock.Now())
-	secs := max(int64(remaining.Seconds()), 0)
-	return secs, true, true
+	// rounded to the nearest second, like redis
+	ms := max(e.expiresAt.Sub(mem.clock.Now()).Milliseconds(), 0)
+	return (ms + 500) / 1000, true, true
 }
 
-func (mem *memory) Persist(k string) bool {
+// PTTL is TTL in milliseconds.
+func (mem *memory) PTTL(k string) (int64, bool, bool) {
 	e, ok := mem.getEntry(k)
 	if !ok {
-		return false
+		return 0, false, false
 	}
 
 	if e.expiresAt.IsZero() {
+		return 0, true, false
+	}
+
+	return max(e.expiresAt.Sub(mem.clock.Now()).Milliseconds(), 0), true, true
+}
+
+// ExpireTime returns when k expires, the zero time if it doesn't, and
+// whether it exists.
+func (mem *memory) ExpireTime(k string) (time.Time, bool) {
+	e, ok := mem.getEntry(k)
+	if !ok {
+		return time.Time{}, false
+	}
+	return e.expiresAt, true
+}
+
+// ExpireAt sets an absolute expiry on an existing key. A time that has
+// already passed deletes the key right away.
+func (mem *memory) ExpireAt(k string, at time.Time) bool {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	e, ok := mem.m[k]
+	if !ok || e.expired(mem.clock.Now()) {
 		return false
 	}
 
+	if !at.After(mem.clock.Now()) {
+		mem.del(k)
+		return true
+	}
+
+	e.expiresAt = at
+	mem.put(k, e)
+	return true
+}
+
+// ExpireWith changes the expiry of an existing key under opt's conditions,
+// as one step. A time that has already passed deletes the key. Along with
+// what it did, it returns the expiry it went by, with a TTL resolved
+// against the store's clock, so the caller can journal it as an absolute
+// time.
+func (mem *memory) ExpireWith(k string, opt ExpireOptions) (ExpireResult, time.Time) {
 	mem.mu.Lock()
-	e.expiresAt = time.Time{}
-	mem.m[k] = e
-	mem.mu.Unlock()
+	defer mem.mu.Unlock()
+
+	return mem.expire(k, opt)
+}
+
+// allows reports whether c lets an expiry of cur, zero for none, change
+// to at. No expiry counts as expiring never, so GT can't apply to it and
+// LT always can.
+func (c ExpireCond) allows(cur, at time.Time) bool {
+	switch {
+	case c&ExpireNX != 0 && !cur.IsZero(),
+		c&ExpireXX != 0 && cur.IsZero(),
+		c&ExpireGT != 0 && (cur.IsZero() || !at.After(cur)),
+		c&ExpireLT != 0 && !cur.IsZero() && !at.Before(cur):
+		return false
+	}
 	return true
 }
+
+// expire is ExpireWith, also returning the expiry it set or, when it
+// deleted k, the time that had passed. Must be called with mu held.
+func (mem *memory) expire(k string, opt ExpireOptions) (ExpireResult, time.Time) {
+	now := mem.clock.Now()
+	e, ok := mem.m[k]
+	if !ok || e.expired(now) {
+		return ExpireSkipped, time.Time{}
+	}
+
+	if opt.Persist {
+		if e.expiresAt.IsZero() {
+			return ExpireSkipped, time.Time{}
+		}
+		e.expiresAt = time.Time{}
+		mem.put(k, e)
+		return ExpireSet, time.Time{}
+	}
+
+	at := opt.At
+	if at.IsZero() {
+		at = now.Add(opt.TTL)
+	}
+
+	if !opt.Cond.allows(e.expiresAt, at) {
+		return ExpireSkipped, time.Time{}
+	}
+
+	if !at.After(now) {
+		mem.del(k)
+		return ExpireDeleted, at
+	}
+	e.expiresAt = at
+	mem.put(k, e)
+	return ExpireSet, at
+}
+
+// GetEx returns k's value and, in the same step, changes its expiry like
+// ExpireWith. opt must set an expiry or Persist; a zero opt expires the key
+// right away. A key that isn't a string is left alone.
+func (mem *memory) GetEx(k string, opt ExpireOptions) (string, bool, ExpireResult, time.Time, error) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	now := mem.clock.Now()
+	e, ok := mem.m[k]
+	if !ok || e.expired(now) {
+		return "", false, ExpireSkipped, time.Time{}, nil
+	}
+	v, err := e.str()
+	if err != nil {
+		return "", false, ExpireSkipped, time.Time{}, err
+	}
+	e.meta.touch(now)
+	res, at := mem.expire(k, opt)
+	return v, true, res, at, nil
+}
@@ -52,7 +52,7 @@ func TestSetEx_TTL_DecreasesAndExpires(t *testing.T) {
 	}
 
 	fc.Advance(3 * time.Second) // total +6s
-	if _, ok := mem.Get("k"); ok {
+	if _, ok, _ := mem.Get("k"); ok {
 		t.Fatalf("expected Get after expiry to return not found")
 	}
 
@@ -68,14 +68,14 @@ func TestPersist_RemovesExpiry(t *testing.T) {
 
 	mem.SetEx("p", "v", 5*time.Second)
 
-	if n := mem.Persist("p"); !n {
-		t.Fatalf("Persist returned false; expected true for key with expiry")
+	if res, _ := mem.ExpireWith("p", store.ExpireOptions{Persist: true}); res != store.ExpireSet {
+		t.Fatalf("Persist returned %v; expected ExpireSet for key with expiry", res)
 	}
 
 	// Advance past original expiry; key should still exist
 	fc.Advance(10 * time.Second)
 
-	if v, ok := mem.Get("p"); !ok || v != "v" {
+	if v, ok, _ := mem.Get("p"); !ok || v != "v" {
 		t.Fatalf("after persist+advance: got (%q,%v), want (\"v\",true)", v, ok)
 	}
 
@@ -94,11 +94,11 @@ func TestPersist_OnExpiredKey(t *testing.T) {
 
 	fc.Advance(3 * time.Second)
 
-	if ok := mem.Persist("gone"); ok {
-		t.Fatalf("Persist on expired key: got true, want false")
+	if res, _ := mem.ExpireWith("gone", store.ExpireOptions{Persist: true}); res != store.ExpireSkipped {
+		t.Fatalf("Persist on expired key: got %v, want ExpireSkipped", res)
 	}
 
-	if _, exists := mem.Get("gone"); exists {
+	if _, exists, _ := mem.Get("gone"); exists {
 		t.Fatalf("expected expired key to be removed on access")
 	}
 
@@ -106,3 +106,172 @@ func TestPersist_OnExpiredKey(t *testing.T) {
 		t.Fatalf("TTL after expiry: got (secs=%d, exists=%v, hasExp=%v), want (0,false,false)", secs, exists, hasExp)
 	}
 }
+
+func TestExpireAt(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fc := newFakeClock(start)
+	mem := store.NewMemoryWithClock(fc)
+
+	if mem.ExpireAt("nope", start.Add(time.Second)) {
+		t.Fatalf("ExpireAt on missing key: got true, want false")
+	}
+
+	mem.Set("k", "v")
+	if !mem.ExpireAt("k", start.Add(4*time.Second)) {
+		t.Fatalf("ExpireAt: got false, want true")
+	}
+	if secs, exists, hasExp := mem.TTL("k"); !exists || !hasExp || secs != 4 {
+		t.Fatalf("TTL: got (secs=%d, exists=%v, hasExp=%v), want (4,true,true)", secs, exists, hasExp)
+	}
+
+	if !mem.ExpireAt("k", start) {
+		t.Fatalf("ExpireAt(now): got false, want true")
+	}
+	if _, ok, _ := mem.Get("k"); ok {
+		t.Fatalf("expected key to be deleted by an expiry that already passed")
+	}
+}
+
+func TestOnExpire_LazyExpiry(t *testing.T) {
+	clk := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(clk)
+
+	var expired []string
+	mem.OnExpire(func(k string) { expired = append(expired, k) })
+
+	mem.SetEx("k", "v", time.Second)
+	mem.Set("keep", "v")
+	clk.Advance(2 * time.Second)
+
+	if _, ok, _ := mem.Get("k"); ok {
+		t.Fatalf("expected k to be expired")
+	}
+	mem.Get("k")
+	mem.Get("keep")
+	if len(expired) != 1 || expired[0] != "k" {
+		t.Fatalf("OnExpire calls: got %q, want [k]", expired)
+	}
+}
+
+func TestOnExpire_Sweeper(t *testing.T) {
+	t.Setenv("EXPIRED_SWEEP_INTERVAL", "1")
+	mem := store.NewMemory()
+
+	expired := make(chan string, 1)
+	mem.OnExpire(func(k string) { expired <- k })
+	mem.SetEx("k", "v", time.Millisecond)
+
+	select {
+	case k := <-expired:
+		if k != "k" {
+			t.Fatalf("got %q, want k", k)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("sweeper never reported the expired key")
+	}
+}
+
+func TestTTL_RoundsToNearestSecond(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+	mem.SetEx("k", "v", 2499*time.Millisecond)
+
+	if secs, _, _ := mem.TTL("k"); secs != 2 {
+		t.Fatalf("TTL of 2.499s: got %d, want 2", secs)
+	}
+	if ms, _, _ := mem.PTTL("k"); ms != 2499 {
+		t.Fatalf("PTTL: got %d, want 2499", ms)
+	}
+	fc.Advance(-time.Millisecond)
+	if secs, _, _ := mem.TTL("k"); secs != 3 {
+		t.Fatalf("TTL of 2.5s: got %d, want 3", secs)
+	}
+}
+
+func TestExpireTime(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	mem := store.NewMemoryWithClock(newFakeClock(start))
+
+	if _, exists := mem.ExpireTime("nope"); exists {
+		t.Fatalf("missing key reported as existing")
+	}
+	mem.Set("k", "v")
+	if at, exists := mem.ExpireTime("k"); !exists || !at.IsZero() {
+		t.Fatalf("no expiry: got (%v, %v)", at, exists)
+	}
+	mem.SetEx("k", "v", time.Minute)
+	if at, _ := mem.ExpireTime("k"); !at.Equal(start.Add(time.Minute)) {
+		t.Fatalf("got %v, want %v", at, start.Add(time.Minute))
+	}
+}
+
+func TestExpireWith(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fc := newFakeClock(start)
+	mem := store.NewMemoryWithClock(fc)
+
+	if res, _ := mem.ExpireWith("nope", store.ExpireOptions{TTL: time.Second}); res != store.ExpireSkipped {
+		t.Fatalf("missing key: got %v", res)
+	}
+
+	mem.Set("k", "v")
+	steps := []struct {
+		opt  store.ExpireOptions
+		want store.ExpireResult
+		ttl  int64 // -1 for no expiry
+	}{
+		{store.ExpireOptions{Cond: store.ExpireXX, TTL: time.Minute}, store.ExpireSkipped, -1},
+		{store.ExpireOptions{Cond: store.ExpireGT, TTL: time.Minute}, store.ExpireSkipped, -1},
+		{store.ExpireOptions{Cond: store.ExpireNX, TTL: time.Minute}, store.ExpireSet, 60},
+		{store.ExpireOptions{Cond: store.ExpireNX, TTL: time.Hour}, store.ExpireSkipped, 60},
+		{store.ExpireOptions{Cond: store.ExpireGT, At: start.Add(30 * time.Second)}, store.ExpireSkipped, 60},
+		{store.ExpireOptions{Cond: store.ExpireGT, At: start.Add(90 * time.Second)}, store.ExpireSet, 90},
+		{store.ExpireOptions{Cond: store.ExpireLT, TTL: 2 * time.Minute}, store.ExpireSkipped, 90},
+		{store.ExpireOptions{Cond: store.ExpireXX | store.ExpireLT, TTL: 10 * time.Second}, store.ExpireSet, 10},
+		{store.ExpireOptions{Persist: true}, store.ExpireSet, -1},
+		{store.ExpireOptions{Persist: true}, store.ExpireSkipped, -1},
+		{store.ExpireOptions{Cond: store.ExpireLT, TTL: time.Minute}, store.ExpireSet, 60},
+	}
+	for i, s := range steps {
+		if res, _ := mem.ExpireWith("k", s.opt); res != s.want {
+			t.Fatalf("step %d: got %v, want %v", i, res, s.want)
+		}
+		secs, _, hasExp := mem.TTL("k")
+		if !hasExp {
+			secs = -1
+		}
+		if secs != s.ttl {
+			t.Fatalf("step %d: TTL got %d, want %d", i, secs, s.ttl)
+		}
+	}
+
+	if res, _ := mem.ExpireWith("k", store.ExpireOptions{TTL: -time.Second}); res != store.ExpireDeleted {
+		t.Fatalf("negative TTL: got %v, want ExpireDeleted", res)
+	}
+	if _, ok, _ := mem.Get("k"); ok {
+		t.Fatalf("key survived a past expiry")
+	}
+}
+
+func TestGetEx(t *testing.T) {
+	fc := newFakeClock(time.Unix(1_700_000_000, 0))
+	mem := store.NewMemoryWithClock(fc)
+
+	if _, ok, res, _, _ := mem.GetEx("nope", store.ExpireOptions{TTL: time.Second}); ok || res != store.ExpireSkipped {
+		t.Fatalf("missing key: got (%v, %v)", ok, res)
+	}
+
+	mem.Set("k", "v")
+	if v, ok, res, _, _ := mem.GetEx("k", store.ExpireOptions{TTL: 5 * time.Second}); v != "v" || !ok || res != store.ExpireSet {
+		t.Fatalf("GetEx: got (%q, %v, %v)", v, ok, res)
+	}
+	if secs, _, _ := mem.TTL("k"); secs != 5 {
+		t.Fatalf("TTL after GetEx: got %d, want 5", secs)
+	}
+	if v, ok, res, _, _ := mem.GetEx("k", store.ExpireOptions{At: fc.Now()}); v != "v" || !ok || res != store.ExpireDeleted {
+		t.Fatalf("GetEx with past expiry: got (%q, %v, %v)", v, ok, res)
+	}
+	if _, ok, _ := mem.Get("k"); ok {
+		t.Fatalf("key survived GetEx with a past expiry")
+	}
+}
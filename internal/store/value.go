@@ -0,0 +1,66 @@
+package store
+
+import "errors"
+
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// Kind is the type of value a key holds.
+type Kind uint8
+
+const (
+	KindNone Kind = iota // the key doesn't exist
+	KindString
+	KindList
+	KindSet
+	KindZSet
+	KindHash
+	KindStream
+)
+
+var kindNames = [...]string{"none", "string", "list", "set", "zset", "hash", "stream"}
+
+// String names k the way TYPE does.
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// ParseKind returns the Kind that TYPE calls name.
+func ParseKind(name string) (Kind, bool) {
+	for k, n := range kindNames {
+		if n == name && Kind(k) != KindNone {
+			return Kind(k), true
+		}
+	}
+	return KindNone, false
+}
+
+// value is what a key holds; each Kind has its own implementation.
+// Operations for one type fail with ErrWrongType on keys holding another,
+// except those that replace the value outright, like SET.
+type value interface {
+	kind() Kind
+	// encoding names the representation, as OBJECT ENCODING reports it
+	encoding() string
+	// clone returns a copy sharing nothing mutable with v, for COPY
+	clone() value
+}
+
+// stringValue is immutable, so copies of it can share the bytes.
+type stringValue string
+
+func (stringValue) kind() Kind         { return KindString }
+func (v stringValue) encoding() string { return stringEncoding(string(v)) }
+func (v stringValue) clone() value     { return v }
+
+// str returns the string e holds, or ErrWrongType if it holds another type.
+// The zero entry, which callers use for a missing key, holds "".
+func (e entry) str() (string, error) {
+	if e.val == nil {
+		return "", nil
+	}
+	v, ok := e.val.(stringValue)
+	if !ok {
+		return "", ErrWrongType
+	}
+	return string(v), nil
+}
@@ -0,0 +1,82 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/amir-aharon/goliath/internal/store"
+)
+
+func TestWrongType(t *testing.T) {
+	mem := store.NewMemory()
+	mem.Push("k", store.Left, false, "a")
+
+	checks := map[string]error{}
+	_, _, checks["Get"] = mem.Get("k")
+	_, _, checks["GetDel"] = mem.GetDel("k")
+	_, _, _, _, checks["GetEx"] = mem.GetEx("k", store.ExpireOptions{TTL: time.Minute})
+	_, _, _, _, checks["SetWith GET"] = mem.SetWith("k", "v", store.SetOptions{Get: true})
+	_, checks["IncrBy"] = mem.IncrBy("k", 1)
+	_, checks["IncrByFloat"] = mem.IncrByFloat("k", 1)
+	_, checks["Append"] = mem.Append("k", "v")
+	_, checks["SetRange"] = mem.SetRange("k", 0, "v")
+	for op, err := range checks {
+		if !errors.Is(err, store.ErrWrongType) {
+			t.Errorf("%s: got %v, want ErrWrongType", op, err)
+		}
+	}
+
+	// none of them touched the key
+	if typ := mem.Type("k"); typ != store.KindList {
+		t.Fatalf("Type: got %s, want list", typ)
+	}
+	if _, _, hasExp := mem.TTL("k"); hasExp {
+		t.Fatalf("GetEx set a TTL")
+	}
+	if _, ok := mem.MGet("k"); ok[0] {
+		t.Fatalf("MGet returned a value for a non-string")
+	}
+	if mem.MSetNX("k", "v") {
+		t.Fatalf("MSetNX wrote over an existing key")
+	}
+
+	// while writes that replace the value don't care what was there
+	if _, existed, written, _, err := mem.SetWith("k", "v", store.SetOptions{}); err != nil || !existed || !written {
+		t.Fatalf("SetWith: got %v, %v, %v", existed, written, err)
+	}
+	if v, ok, err := mem.Get("k"); err != nil || !ok || v != "v" {
+		t.Fatalf("Get after SET: got %q, %v, %v", v, ok, err)
+	}
+}
+
+func TestWrongType_KeyspaceOps(t *testing.T) {
+	mem := store.NewMemory()
+	mem.Push("a", store.Left, false, "x")
+
+	if !mem.Copy("a", "b", false) || mem.Type("b") != store.KindList {
+		t.Fatalf("Copy didn't carry the type")
+	}
+	if info, ok := mem.Object("a"); !ok || info.Encoding != "listpack" {
+		t.Fatalf("Object: got %+v, %v", info, ok)
+	}
+	if n := mem.Exists("a", "b"); n != 2 {
+		t.Fatalf("Exists: got %d, want 2", n)
+	}
+	if deleted := mem.DelKeys("a", "b"); len(deleted) != 2 {
+		t.Fatalf("DelKeys: got %v", deleted)
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for _, k := range []store.Kind{store.KindString, store.KindList, store.KindSet, store.KindZSet, store.KindHash, store.KindStream} {
+		if got, ok := store.ParseKind(k.String()); !ok || got != k {
+			t.Errorf("ParseKind(%q): got %v, %v", k, got, ok)
+		}
+	}
+	for _, name := range []string{"none", "String", "bitmap", ""} {
+		if _, ok := store.ParseKind(name); ok {
+			t.Errorf("ParseKind(%q) succeeded", name)
+		}
+	}
+}